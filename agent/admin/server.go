@@ -0,0 +1,366 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/goauthlink/authlink/pkg/logging"
+	"github.com/goauthlink/authlink/pkg/metrics"
+)
+
+const (
+	ContentTypeJSONPatch  = "application/json-patch+json"
+	ContentTypeMergePatch = "application/merge-patch+json"
+
+	maxBodySize = 64 << 20
+)
+
+const (
+	errTokenIsRequired       = "admin token is required"
+	errUnsupportedPatchType  = "content type must be " + ContentTypeJSONPatch + " or " + ContentTypeMergePatch
+	errRevisionMismatch      = "revision doesn't match If-Match header"
+	errRequestBodyIsTooLarge = "request body is too large"
+)
+
+// Store is the active policy and data of the agent
+type Store interface {
+	// Get returns the active policy and data, data is nil if it isn't set
+	Get() (policy, data []byte)
+	// Update activates policy and data returned by the update function at once,
+	// updates are serialized with other updates of the agent
+	Update(update func(policy, data []byte) (newPolicy, newData []byte, err error)) error
+}
+
+type Server struct {
+	srv    *http.Server
+	store  Store
+	token  []byte
+	cert   *tls.Certificate
+	logger *slog.Logger
+	meter  *metrics.Meter
+
+	listening atomic.Bool
+}
+
+type ServerOpt func(*Server)
+
+func WithLogger(logger *slog.Logger) ServerOpt {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+func WithCert(cert *tls.Certificate) ServerOpt {
+	return func(s *Server) {
+		s.cert = cert
+	}
+}
+
+// WithMeter creates metrics of requests by the meter instead of the global meter provider
+func WithMeter(meter *metrics.Meter) ServerOpt {
+	return func(s *Server) {
+		s.meter = meter
+	}
+}
+
+// NewServer creates the admin server, requests must be authenticated with the bearer token
+func NewServer(addr string, store Store, token string, opts ...ServerOpt) (*Server, error) {
+	if len(token) == 0 {
+		return nil, errors.New(errTokenIsRequired)
+	}
+
+	adminSrv := &Server{
+		srv: &http.Server{
+			Addr: addr,
+		},
+		store: store,
+		token: []byte(token),
+	}
+
+	for _, o := range opts {
+		o(adminSrv)
+	}
+
+	if adminSrv.logger == nil {
+		adminSrv.logger = logging.NewNullLogger()
+	}
+
+	router := http.NewServeMux()
+	router.HandleFunc("GET /v1/policy", adminSrv.getPolicy)
+	router.HandleFunc("PUT /v1/policy", adminSrv.putPolicy)
+	router.HandleFunc("GET /v1/data", adminSrv.getData)
+	router.HandleFunc("PATCH /v1/data", adminSrv.patchData)
+
+	// unauthenticated requests are counted as unmatched
+	metricsMiddleware, err := adminSrv.meter.NewHTTPMiddleware(adminSrv.authenticate(router), metrics.WithServerLabel("admin"))
+	if err != nil {
+		return nil, err
+	}
+
+	adminSrv.srv.Handler = metricsMiddleware
+
+	return adminSrv, nil
+}
+
+func (adminSrv *Server) Start(_ context.Context) error {
+	adminSrv.logger.Info(fmt.Sprintf("admin server is starting on %s", adminSrv.srv.Addr))
+
+	var listener net.Listener
+	var err error
+
+	if adminSrv.cert == nil {
+		listener, err = net.Listen("tcp", adminSrv.srv.Addr)
+	} else {
+		listener, err = tls.Listen("tcp", adminSrv.srv.Addr, &tls.Config{
+			Certificates: []tls.Certificate{*adminSrv.cert},
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("admin server listening: %w", err)
+	}
+	defer listener.Close()
+
+	adminSrv.listening.Store(true)
+	defer adminSrv.listening.Store(false)
+
+	if err := adminSrv.srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("admin server listening: %w", err)
+	}
+
+	return nil
+}
+
+// Listening reports whether the server accepts connections
+func (adminSrv *Server) Listening() bool {
+	return adminSrv.listening.Load()
+}
+
+func (adminSrv *Server) Shutdown(ctx context.Context) error {
+	ctxd, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	if err := adminSrv.srv.Shutdown(ctxd); err != nil {
+		return fmt.Errorf("shutdown admin server: %w", err)
+	}
+
+	adminSrv.logger.Info("admin server stopped")
+
+	return nil
+}
+
+func (adminSrv *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), adminSrv.token) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type policyResponse struct {
+	Revision string `json:"revision"`
+	Policy   string `json:"policy"`
+}
+
+type dataResponse struct {
+	Revision string          `json:"revision"`
+	Data     json.RawMessage `json:"data"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (adminSrv *Server) getPolicy(w http.ResponseWriter, r *http.Request) {
+	policy, _ := adminSrv.store.Get()
+	writePolicy(w, policy)
+}
+
+// putPolicy validates the policy with the active data and activates it
+func (adminSrv *Server) putPolicy(w http.ResponseWriter, r *http.Request) {
+	newPolicy, err := readBody(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = adminSrv.store.Update(func(policy, data []byte) ([]byte, []byte, error) {
+		if err := checkRevision(r, policy); err != nil {
+			return nil, nil, err
+		}
+		return newPolicy, data, nil
+	})
+	if err != nil {
+		adminSrv.writeUpdateError(w, "policy", err)
+		return
+	}
+
+	adminSrv.logger.Info(fmt.Sprintf("policy revision %s activated by admin api", Revision(newPolicy)))
+
+	writePolicy(w, newPolicy)
+}
+
+func (adminSrv *Server) getData(w http.ResponseWriter, r *http.Request) {
+	_, data := adminSrv.store.Get()
+	writeData(w, data)
+}
+
+// patchData applies RFC 6902 JSON Patch or RFC 7386 JSON Merge Patch to the active data
+func (adminSrv *Server) patchData(w http.ResponseWriter, r *http.Request) {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != ContentTypeJSONPatch && contentType != ContentTypeMergePatch {
+		writeError(w, http.StatusUnsupportedMediaType, errors.New(errUnsupportedPatchType))
+		return
+	}
+
+	patch, err := readBody(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var newData []byte
+	err = adminSrv.store.Update(func(policy, data []byte) ([]byte, []byte, error) {
+		if err := checkRevision(r, data); err != nil {
+			return nil, nil, err
+		}
+
+		newData, err = applyPatch(contentType, data, patch)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return policy, newData, nil
+	})
+	if err != nil {
+		adminSrv.writeUpdateError(w, "data", err)
+		return
+	}
+
+	adminSrv.logger.Info(fmt.Sprintf("data revision %s activated by admin api", Revision(newData)))
+
+	writeData(w, newData)
+}
+
+func applyPatch(contentType string, data, patch []byte) ([]byte, error) {
+	if data == nil {
+		data = []byte("{}")
+	}
+
+	if contentType == ContentTypeMergePatch {
+		newData, err := jsonpatch.MergePatch(data, patch)
+		if err != nil {
+			return nil, fmt.Errorf("apply merge patch: %w", err)
+		}
+		return newData, nil
+	}
+
+	decoded, err := jsonpatch.DecodePatch(patch)
+	if err != nil {
+		return nil, fmt.Errorf("decode json patch: %w", err)
+	}
+
+	newData, err := decoded.Apply(data)
+	if err != nil {
+		return nil, fmt.Errorf("apply json patch: %w", err)
+	}
+
+	return newData, nil
+}
+
+type revisionMismatchError struct{}
+
+func (revisionMismatchError) Error() string {
+	return errRevisionMismatch
+}
+
+// checkRevision supports optimistic concurrency with the revision in If-Match header
+func checkRevision(r *http.Request, current []byte) error {
+	ifMatch := r.Header.Get("If-Match")
+	if len(ifMatch) == 0 {
+		return nil
+	}
+
+	if strings.Trim(ifMatch, `"`) != Revision(current) {
+		return revisionMismatchError{}
+	}
+
+	return nil
+}
+
+func (adminSrv *Server) writeUpdateError(w http.ResponseWriter, document string, err error) {
+	if errors.Is(err, revisionMismatchError{}) {
+		writeError(w, http.StatusPreconditionFailed, err)
+		return
+	}
+
+	adminSrv.logger.Error(fmt.Sprintf("%s update by admin api rejected: %s", document, err.Error()))
+	writeError(w, http.StatusBadRequest, err)
+}
+
+// Revision identifies content of the policy or data
+func Revision(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, errors.New(errRequestBodyIsTooLarge)
+		}
+		return nil, fmt.Errorf("read request body: %w", err)
+	}
+
+	return body, nil
+}
+
+func writePolicy(w http.ResponseWriter, policy []byte) {
+	revision := Revision(policy)
+	w.Header().Set("ETag", `"`+revision+`"`)
+	writeJSON(w, http.StatusOK, policyResponse{Revision: revision, Policy: string(policy)})
+}
+
+func writeData(w http.ResponseWriter, data []byte) {
+	revision := Revision(data)
+	if data == nil {
+		data = []byte("null")
+	}
+	w.Header().Set("ETag", `"`+revision+`"`)
+	writeJSON(w, http.StatusOK, dataResponse{Revision: revision, Data: data})
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body) //nolint: errcheck
+}
@@ -0,0 +1,215 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goauthlink/authlink/sdk/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testToken  = "secret"
+	testData   = `{"users":["user1"]}`
+	testPolicy = `cn:
+  - header: "x-source"
+policies:
+  - uri: ["/endpoint"]
+    allow: ["{.users[*]}"]`
+)
+
+// testStore validates updates with the checker like the agent does
+type testStore struct {
+	mux     sync.Mutex
+	checker *policy.Checker
+	policy  []byte
+	data    []byte
+}
+
+func newTestStore(t *testing.T) *testStore {
+	s := &testStore{checker: policy.NewChecker()}
+	require.NoError(t, s.Update(func(_, _ []byte) ([]byte, []byte, error) {
+		return []byte(testPolicy), []byte(testData), nil
+	}))
+
+	return s
+}
+
+func (s *testStore) Get() ([]byte, []byte) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.policy, s.data
+}
+
+func (s *testStore) Update(update func(policy, data []byte) ([]byte, []byte, error)) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	newPolicy, newData, err := update(s.policy, s.data)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checker.SetPolicyWithData(newPolicy, newData); err != nil {
+		return err
+	}
+	s.policy, s.data = newPolicy, newData
+
+	return nil
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, *testStore) {
+	store := newTestStore(t)
+
+	adminSrv, err := NewServer("", store, testToken)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(adminSrv.srv.Handler)
+	t.Cleanup(srv.Close)
+
+	return srv, store
+}
+
+func doRequest(t *testing.T, method, url, contentType, body string, headers map[string]string) (*http.Response, map[string]interface{}) {
+	rq, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+
+	rq.Header.Set("Authorization", "Bearer "+testToken)
+	if len(contentType) > 0 {
+		rq.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range headers {
+		rq.Header.Set(k, v)
+	}
+
+	rsp, err := http.DefaultClient.Do(rq)
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+
+	content, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+
+	result := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(content, &result), string(content))
+
+	return rsp, result
+}
+
+func Test_Authentication(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	for _, authorization := range []string{"", "Bearer wrong", testToken} {
+		rq, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/policy", nil)
+		require.NoError(t, err)
+		if len(authorization) > 0 {
+			rq.Header.Set("Authorization", authorization)
+		}
+
+		rsp, err := http.DefaultClient.Do(rq)
+		require.NoError(t, err)
+		rsp.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, rsp.StatusCode, authorization)
+	}
+
+	_, err := NewServer("", newTestStore(t), "")
+	require.EqualError(t, err, errTokenIsRequired)
+}
+
+func Test_Policy(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	rsp, body := doRequest(t, http.MethodGet, srv.URL+"/v1/policy", "", "", nil)
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	assert.Equal(t, testPolicy, body["policy"])
+	assert.Equal(t, Revision([]byte(testPolicy)), body["revision"])
+	assert.Equal(t, `"`+Revision([]byte(testPolicy))+`"`, rsp.Header.Get("ETag"))
+
+	newPolicy := `cn:
+  - header: "x-source"
+policies:
+  - uri: ["/endpoint2"]
+    allow: ["{.users[*]}"]`
+
+	// invalid policy isn't activated
+	rsp, body = doRequest(t, http.MethodPut, srv.URL+"/v1/policy", "application/yaml", "policies: {", nil)
+	assert.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+	assert.NotEmpty(t, body["error"])
+
+	rsp, _ = doRequest(t, http.MethodPut, srv.URL+"/v1/policy", "application/yaml", newPolicy, map[string]string{
+		"If-Match": `"` + Revision([]byte("other")) + `"`,
+	})
+	assert.Equal(t, http.StatusPreconditionFailed, rsp.StatusCode)
+
+	policyData, _ := store.Get()
+	assert.Equal(t, testPolicy, string(policyData))
+
+	rsp, body = doRequest(t, http.MethodPut, srv.URL+"/v1/policy", "application/yaml", newPolicy, map[string]string{
+		"If-Match": `"` + Revision([]byte(testPolicy)) + `"`,
+	})
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	assert.Equal(t, Revision([]byte(newPolicy)), body["revision"])
+
+	policyData, _ = store.Get()
+	assert.Equal(t, newPolicy, string(policyData))
+}
+
+func Test_PatchData(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	rsp, body := doRequest(t, http.MethodGet, srv.URL+"/v1/data", "", "", nil)
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	assert.Equal(t, map[string]interface{}{"users": []interface{}{"user1"}}, body["data"])
+	assert.Equal(t, Revision([]byte(testData)), body["revision"])
+
+	// json patch
+	rsp, body = doRequest(t, http.MethodPatch, srv.URL+"/v1/data", ContentTypeJSONPatch,
+		`[{"op":"add","path":"/users/-","value":"user2"}]`, nil)
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	assert.Equal(t, map[string]interface{}{"users": []interface{}{"user1", "user2"}}, body["data"])
+
+	_, data := store.Get()
+	assert.JSONEq(t, `{"users":["user1","user2"]}`, string(data))
+
+	// merge patch
+	rsp, body = doRequest(t, http.MethodPatch, srv.URL+"/v1/data", ContentTypeMergePatch,
+		`{"admins":["admin"],"users":["user3"]}`, nil)
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	assert.Equal(t, map[string]interface{}{
+		"admins": []interface{}{"admin"},
+		"users":  []interface{}{"user3"},
+	}, body["data"])
+
+	// failed patches don't change the data
+	_, data = store.Get()
+
+	rsp, _ = doRequest(t, http.MethodPatch, srv.URL+"/v1/data", ContentTypeJSONPatch,
+		`[{"op":"remove","path":"/unknown"}]`, nil)
+	assert.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+
+	rsp, _ = doRequest(t, http.MethodPatch, srv.URL+"/v1/data", ContentTypeMergePatch, `{`, nil)
+	assert.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+
+	rsp, body = doRequest(t, http.MethodPatch, srv.URL+"/v1/data", "application/json", `{}`, nil)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rsp.StatusCode)
+	assert.Equal(t, errUnsupportedPatchType, body["error"])
+
+	rsp, _ = doRequest(t, http.MethodPatch, srv.URL+"/v1/data", ContentTypeMergePatch, `{"users":null}`, map[string]string{
+		"If-Match": Revision([]byte(testData)),
+	})
+	assert.Equal(t, http.StatusPreconditionFailed, rsp.StatusCode)
+
+	_, newData := store.Get()
+	assert.Equal(t, data, newData)
+}
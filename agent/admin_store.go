@@ -0,0 +1,29 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+// adminStore exposes the active policy and data to the admin api,
+// updates go through the same validation and activation as source reloads
+type adminStore struct {
+	agent *Agent
+}
+
+func (s adminStore) Get() ([]byte, []byte) {
+	return s.agent.content()
+}
+
+func (s adminStore) Update(update func(policy, data []byte) ([]byte, []byte, error)) error {
+	s.agent.updateMux.Lock()
+	defer s.agent.updateMux.Unlock()
+
+	policy, data := s.agent.content()
+	newPolicy, newData, err := update(policy, data)
+	if err != nil {
+		return err
+	}
+
+	// the revision of the source stays, changes of the admin api are reported by policy and data revisions
+	return s.agent.setPolicyWithData(s.agent.Revision(), newPolicy, newData)
+}
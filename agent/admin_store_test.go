@@ -0,0 +1,57 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/goauthlink/authlink/agent/admin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AdminStore(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	config := DefaultConfig()
+	config.LogLevel = slog.LevelError
+	config.PolicyFilePath = rootDir + "/policy.yaml"
+	config.DataFilePath = rootDir + "/data.json"
+
+	agent, err := Init(config)
+	require.NoError(t, err)
+
+	store := adminStore{agent: agent}
+
+	// stored bytes are returned, so revisions of the admin api and the status are the same
+	policy, data := store.Get()
+	assert.Equal(t, []byte(testPolicy), policy)
+	assert.Equal(t, []byte(testData), data)
+	assert.Equal(t, admin.Revision(data), agent.ReloadStatus().DataRevision)
+	assert.Equal(t, admin.Revision(policy), agent.ReloadStatus().PolicyRevision)
+
+	// invalid update is rejected like a failed reload
+	err = store.Update(func(policy, data []byte) ([]byte, []byte, error) {
+		return policy, []byte(`{"users":`), nil
+	})
+	require.ErrorContains(t, err, "invalid json format")
+	require.ErrorContains(t, agent.LastUpdateErr(), "invalid json format")
+
+	require.NoError(t, store.Update(func(policy, data []byte) ([]byte, []byte, error) {
+		return policy, []byte(`{"users":["user3"]}`), nil
+	}))
+	require.NoError(t, agent.LastUpdateErr())
+	assert.Equal(t, map[string]interface{}{"users": []interface{}{"user3"}}, agent.policy.Data())
+
+	// no data
+	config.DataFilePath = ""
+	agent, err = Init(config)
+	require.NoError(t, err)
+
+	_, data = adminStore{agent: agent}.Get()
+	assert.Nil(t, data)
+}
@@ -6,14 +6,51 @@ package agent
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/goauthlink/authlink/agent/admin"
+	"github.com/goauthlink/authlink/agent/bundle"
+	"github.com/goauthlink/authlink/agent/controlplane"
+	"github.com/goauthlink/authlink/agent/decision"
+	"github.com/goauthlink/authlink/agent/fetch"
+	"github.com/goauthlink/authlink/agent/git"
+	"github.com/goauthlink/authlink/agent/kube"
 	"github.com/goauthlink/authlink/agent/monitoring"
+	"github.com/goauthlink/authlink/agent/notify"
+	"github.com/goauthlink/authlink/pkg/metrics"
 	"github.com/goauthlink/authlink/sdk/policy"
+	api "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	reloadDebounce      = 100 * time.Millisecond
+	tracingFlushTimeout = 5 * time.Second
+	metricsFlushTimeout = 5 * time.Second
+
+	errDataSourceWithoutPolicySource = "data source must be used with policy source"
+	errDataIsStale                   = "data is stale, last updated %s ago, max staleness is %s"
+	errPolicyIsNotLoaded             = "policy isn't loaded"
+	errDataIsNotLoaded               = "data isn't loaded"
+	errServerIsNotListening          = "server %T isn't listening"
+
+	// GitWebhookPattern is the route of git push webhooks on the monitoring server
+	GitWebhookPattern = "POST /v1/git/webhook"
 )
 
 type Server interface {
@@ -21,40 +58,216 @@ type Server interface {
 	Shutdown(ctx context.Context) error
 }
 
+// ListeningServer is a Server reporting whether it accepts connections,
+// the agent isn't ready until all of them listen. Servers not implementing it are considered listening.
+type ListeningServer interface {
+	Server
+	Listening() bool
+}
+
 type Agent struct {
 	servers []Server
 	logger  *slog.Logger
 	policy  *Policy
 	config  Config
 	done    chan struct{}
+	// decisions logs decision events to the sinks of the config
+	decisions      *decision.Logger
+	tracerProvider trace.TracerProvider
+	// shutdownTracing flushes spans of the provider created by the agent
+	shutdownTracing func(ctx context.Context) error
+	// meterProvider records metrics of the agent, it's created by the agent unless WithMeterProvider is used
+	meterProvider api.MeterProvider
+	meter         *metrics.Meter
+	// metricsHandler serves metrics of the provider created by the agent at the scrape endpoint
+	metricsHandler http.Handler
+	// shutdownMetrics pushes the last metrics of the provider created by the agent
+	shutdownMetrics func(ctx context.Context) error
+
+	policySource PolicySource
+	dataSource   DataSource
+	// reloadCh coalesces change notifications of the sources
+	reloadCh chan struct{}
+
+	bundlePoller *bundle.Poller
+	gitSource    *git.Source
+	// stopKube stops informers of the kubernetes source
+	stopKube     context.CancelFunc
+	controlPlane *controlplane.Client
+	// stopControlPlane stops streaming snapshots from the control plane
+	stopControlPlane context.CancelFunc
+
+	// updateMux serializes updates from the sources and the bundle poller
+	updateMux   sync.Mutex
+	sourcesHash [sha256.Size]byte
+	// sourcesErr is the error of activating the content with sourcesHash
+	sourcesErr     error
+	statusMux      sync.RWMutex
+	lastUpdateErr  error
+	revision       string
+	policyRevision string
+	dataRevision   string
+	// policyContent and dataContent are the active policy and data as they were activated
+	policyContent       []byte
+	dataContent         []byte
+	updateAttempts      uint64
+	updateFailures      uint64
+	counterUpdateFailed metrics.Metric
+	counterUpdateTotal  metrics.Metric
+	// lastUpdate is the time of the last update attempt
+	lastUpdate time.Time
+	// dataUpdated is the time of the last successful update of policy and data
+	dataUpdated time.Time
+	created     time.Time
+	// webhook is notified about changed and failed updates
+	webhook *notify.Webhook
+}
+
+type Option func(*Agent)
+
+// WithConfig sets the agent config, DefaultConfig is used by default
+func WithConfig(config Config) Option {
+	return func(a *Agent) {
+		a.config = config
+	}
+}
+
+// WithPolicySource replaces the policy file, the bundle and the kubernetes source of the config
+func WithPolicySource(source PolicySource) Option {
+	return func(a *Agent) {
+		a.policySource = source
+	}
+}
+
+// WithDataSource replaces the data file of the config, it's used only with WithPolicySource
+func WithDataSource(source DataSource) Option {
+	return func(a *Agent) {
+		a.dataSource = source
+	}
+}
+
+// WithDecisionLogger replaces sinks of the config with the logger, so decisions can be written
+// to sinks of the program embedding the agent. The agent closes the logger on shutdown.
+func WithDecisionLogger(logger *decision.Logger) Option {
+	return func(a *Agent) {
+		a.decisions = logger
+	}
+}
+
+// WithAgentLogger sets the logger of the agent, by default it writes to stdout with the config log level
+func WithAgentLogger(logger *slog.Logger) Option {
+	return func(a *Agent) {
+		a.logger = logger
+	}
 }
 
+// Init creates the agent loading policy and data as set in the config
 func Init(config Config) (*Agent, error) {
+	return New(WithConfig(config))
+}
+
+// New creates the agent, so it can be embedded into another Go service
+func New(opts ...Option) (*Agent, error) {
 	agent := &Agent{
-		logger: slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		config:   DefaultConfig(),
+		done:     make(chan struct{}, 1),
+		reloadCh: make(chan struct{}, 1),
+		created:  time.Now(),
+	}
+
+	for _, o := range opts {
+		o(agent)
+	}
+
+	config := agent.config
+
+	if agent.logger == nil {
+		agent.logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
 			Level: config.LogLevel,
-		})),
-		config: config,
-		done:   make(chan struct{}, 1),
+		}))
+	}
+
+	if agent.dataSource != nil && agent.policySource == nil {
+		return nil, errors.New(errDataSourceWithoutPolicySource)
 	}
 
 	agent.logger.Info("start initing")
 
-	var checkLogger *CheckLogger
-	if config.LogCheckResults {
-		checkLogger = NewCheckLogger(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-			Level: slog.LevelInfo,
-		})))
+	if agent.meterProvider == nil {
+		if err := agent.initMetrics(); err != nil {
+			return nil, err
+		}
+	}
+	agent.meter = metrics.NewMeter(agent.meterProvider)
+
+	if agent.decisions == nil {
+		if err := agent.initDecisionLog(); err != nil {
+			return nil, err
+		}
 	}
 
-	agent.policy = NewPolicy(policy.NewChecker(), checkLogger)
+	agent.policy = NewPolicy(policy.NewChecker(), agent.decisions,
+		WithMetricsClients(config.MetricsClients),
+		WithMetricsMaxSeries(config.MetricsMaxSeries),
+		WithMeter(agent.meter),
+	)
+	agent.policy.dataStale = agent.DataStale
+	agent.policy.failClosed = config.DataStaleMode == StaleModeFailClosed
+	agent.policy.revision = agent.Revision
+
+	if agent.tracerProvider == nil && len(config.TracingEndpoint) > 0 {
+		if err := agent.initTracing(); err != nil {
+			return nil, err
+		}
+	}
+	if agent.tracerProvider != nil {
+		agent.policy.tracer = agent.tracerProvider.Tracer(tracerName)
+	}
 
-	if err := agent.updateFiles(); err != nil {
+	if err := agent.initReloadMetrics(); err != nil {
 		return nil, err
 	}
 
+	err := agent.meter.NewObservableGauge("data_age_seconds", "Age of the data since it was last confirmed current (seconds)", func() float64 {
+		return agent.DataAge().Seconds()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case agent.policySource != nil:
+	case len(config.BundleURL) > 0:
+		if err := agent.initBundle(); err != nil {
+			return nil, err
+		}
+	case len(config.KubeNamespace) > 0:
+		if err := agent.initKube(); err != nil {
+			return nil, err
+		}
+	case len(config.GitRepo) > 0:
+		if err := agent.initGit(); err != nil {
+			return nil, err
+		}
+	case len(config.ControlPlaneAddr) > 0:
+		if err := agent.initControlPlane(); err != nil {
+			return nil, err
+		}
+	default:
+		if err := agent.initFiles(); err != nil {
+			return nil, err
+		}
+	}
+
+	if agent.policySource != nil {
+		if err := agent.reloadSources(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
 	httpServerOptions := []ServerOpt{
 		WithLogger(agent.logger),
+		WithServerMeter(agent.meter),
 	}
 
 	if config.TLSCert != nil {
@@ -68,6 +281,17 @@ func Init(config Config) (*Agent, error) {
 
 	monitoringServerOpions := []monitoring.ServerOpt{
 		monitoring.WithLogger(agent.logger),
+		monitoring.WithHealthCheck(agent.LastUpdateErr),
+		monitoring.WithHealthDetails(func() interface{} { return agent.ReloadStatus() }),
+		monitoring.WithReadinessCheck(agent.readinessCheck),
+		monitoring.WithHandler(RuleStatsPattern, ruleStatsHandler(agent.policy)),
+		monitoring.WithMetricsHandler(config.MetricsPath, agent.metricsHandler),
+		monitoring.WithMeter(agent.meter),
+	}
+	// the monitoring server isn't authenticated, so unsigned webhooks aren't served
+	if agent.gitSource != nil && len(config.GitWebhookSecret) > 0 {
+		monitoringServerOpions = append(monitoringServerOpions,
+			monitoring.WithHandler(GitWebhookPattern, agent.gitSource.WebhookHandler(config.GitWebhookSecret)))
 	}
 	monitoringServer, err := monitoring.NewServer(config.MonitoringAddr, monitoringServerOpions...)
 	if err != nil {
@@ -79,6 +303,22 @@ func Init(config Config) (*Agent, error) {
 		monitoringServer,
 	}
 
+	if len(config.AdminAddr) > 0 {
+		adminServerOptions := []admin.ServerOpt{
+			admin.WithLogger(agent.logger),
+			admin.WithMeter(agent.meter),
+		}
+		if config.TLSCert != nil {
+			adminServerOptions = append(adminServerOptions, admin.WithCert(config.TLSCert))
+		}
+
+		adminServer, err := admin.NewServer(config.AdminAddr, adminStore{agent: agent}, config.AdminToken, adminServerOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("init admin server: %w", err)
+		}
+		agent.servers = append(agent.servers, adminServer)
+	}
+
 	agent.logger.Info("agent inited")
 
 	return agent, nil
@@ -94,10 +334,11 @@ func (a *Agent) Run(stop chan struct{}) error {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	errchan := make(chan error)
-	wg.Add(1)
+	// errchan is closed after the servers stopped, so the collector isn't waited by wg
+	errchan := make(chan error, len(a.servers))
+	errDone := make(chan struct{})
 	go func() {
-		defer wg.Done()
+		defer close(errDone)
 		for err := range errchan {
 			if err != nil {
 				a.logger.Error(err.Error())
@@ -116,25 +357,51 @@ func (a *Agent) Run(stop chan struct{}) error {
 		}(srv)
 	}
 
-	if a.config.UpdateFilesSeconds > 0 {
+	if a.policySource != nil {
+		subscribe := func(name string, subscribe func(ctx context.Context, notify func()) error) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := subscribe(ctx, a.notifyReload); err != nil {
+					a.logger.Error(fmt.Sprintf("subscribing to %s source failed: %s", name, err.Error()))
+				}
+			}()
+		}
+
+		subscribe("policy", a.policySource.Subscribe)
+		if a.dataSource != nil {
+			subscribe("data", a.dataSource.Subscribe)
+		}
+
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 
-			ticker := time.NewTicker(time.Second * time.Duration(a.config.UpdateFilesSeconds))
-
-			a.logger.Info("start updating files")
-			for {
-				select {
-				case <-ticker.C:
-					if err := a.updateFiles(); err != nil {
-						a.logger.Error(fmt.Sprintf("updating files failed: %s", err.Error()))
-					}
-				case <-ctx.Done():
-					a.logger.Info("stop updating files")
-					return
-				}
-			}
+			a.logger.Info("start reloading sources")
+			a.runReload(ctx)
+			a.logger.Info("stop reloading sources")
+		}()
+	}
+
+	if a.bundlePoller != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			a.logger.Info("start polling bundle")
+			a.bundlePoller.Run(ctx, a.applyBundle)
+			a.logger.Info("stop polling bundle")
+		}()
+	}
+
+	if a.gitSource != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			a.logger.Info("start syncing git repository")
+			a.gitSource.Run(ctx, a.applyRevision)
+			a.logger.Info("stop syncing git repository")
 		}()
 	}
 
@@ -144,47 +411,524 @@ func (a *Agent) Run(stop chan struct{}) error {
 	a.logger.Info("received exit signal")
 
 	a.shutdown(cancel, ctx)
-	close(errchan)
 	wg.Wait()
+	// the sources don't apply updates anymore, so scheduled reload events are complete
+	if a.webhook != nil {
+		a.webhook.Close()
+	}
+	close(errchan)
+	<-errDone
 	a.logger.Info("agent shutdown")
 	close(a.done)
 
 	return reserr
 }
 
-func (a *Agent) updateFiles() error {
-	policyData, err := os.ReadFile(a.config.PolicyFilePath)
+// initDecisionLog creates sinks of the decision log config, LogCheckResults adds the stdout sink
+func (a *Agent) initDecisionLog() error {
+	config := decision.Config{}
+	if a.config.DecisionLog != nil {
+		config = *a.config.DecisionLog
+		config.Sinks = slices.Clone(config.Sinks)
+	}
+
+	if a.config.LogCheckResults && !slices.ContainsFunc(config.Sinks, func(s decision.SinkConfig) bool {
+		return s.Type == decision.SinkStdout
+	}) {
+		config.Sinks = append(config.Sinks, decision.SinkConfig{Type: decision.SinkStdout})
+	}
+
+	if len(config.Sinks) == 0 {
+		return nil
+	}
+
+	decisions, err := decision.NewLogger(config, decision.WithLogger(a.logger), decision.WithMeter(a.meter))
 	if err != nil {
-		return fmt.Errorf("policy file updating failed: %w", err)
+		return fmt.Errorf("init decision log: %w", err)
 	}
-	if err := a.policy.SetPolicy(policyData); err != nil {
-		return fmt.Errorf("policy file updating failed: %w", err)
+	a.decisions = decisions
+
+	return nil
+}
+
+// initFiles sets the policy and data files or fetched data documents of the config as the sources
+func (a *Agent) initFiles() error {
+	opts := []FileSourceOpt{WithFileLogger(a.logger)}
+	if a.config.UpdateFilesSeconds > 0 {
+		opts = append(opts, WithFileUpdateInterval(time.Second*time.Duration(a.config.UpdateFilesSeconds)))
+	}
+	if a.config.WatchFiles {
+		opts = append(opts, WithFileWatch())
+	}
+
+	a.policySource = NewFileSource(a.config.PolicyFilePath, opts...)
+	if len(a.config.DataFilePath) > 0 {
+		a.dataSource = NewFileSource(a.config.DataFilePath, opts...)
+	}
+
+	if len(a.config.DataSources) > 0 {
+		dataSource, err := fetch.NewSource(a.config.DataSources, fetch.WithLogger(a.logger), fetch.WithMeter(a.meter))
+		if err != nil {
+			return fmt.Errorf("init data sources: %w", err)
+		}
+		a.dataSource = dataSource
 	}
-	a.logger.Info("policy file updated")
 
-	if len(a.config.DataFilePath) == 0 {
+	return nil
+}
+
+// notifyReload schedules reloading of the sources, notifications are merged until the reload starts
+func (a *Agent) notifyReload() {
+	select {
+	case a.reloadCh <- struct{}{}:
+	default:
+	}
+}
+
+// runReload reloads the sources after notifications until the context is done.
+// Notifications of a burst are merged, so related policy and data changes are applied at once.
+func (a *Agent) runReload(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.reloadCh:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reloadDebounce):
+		}
+
+		// notifications received during the debounce are covered by this reload
+		select {
+		case <-a.reloadCh:
+		default:
+		}
+
+		if err := a.reloadSources(ctx); err != nil {
+			a.logger.Error(fmt.Sprintf("reloading policy and data failed: %s", err.Error()))
+		}
+	}
+}
+
+// reloadSources loads policy and data from the sources and activates them at once.
+// On failure the previous policy and data stay active.
+func (a *Agent) reloadSources(ctx context.Context) error {
+	a.updateMux.Lock()
+	defer a.updateMux.Unlock()
+
+	updated, err := a.loadSources(ctx)
+	if err != nil {
+		return err
+	}
+
+	if updated {
+		a.logger.Info("policy and data updated")
+	} else {
+		a.logger.Debug("policy and data unchanged")
+	}
+
+	return nil
+}
+
+// loadSources activates content of the sources, it's skipped if the content is the same as the last time
+func (a *Agent) loadSources(ctx context.Context) (bool, error) {
+	policyData, err := a.policySource.Load(ctx)
+	if err != nil {
+		err = fmt.Errorf("policy loading failed: %w", err)
+		a.setUpdateResult(false, err, nil)
+		return false, err
+	}
+
+	var data []byte
+	if a.dataSource != nil {
+		data, err = a.dataSource.Load(ctx)
+		if err != nil {
+			err = fmt.Errorf("data loading failed: %w", err)
+			a.setUpdateResult(false, err, nil)
+			return false, err
+		}
+	}
+
+	hash := sha256.New()
+	hash.Write(policyData)
+	hash.Write([]byte{0})
+	hash.Write(data)
+	var sourcesHash [sha256.Size]byte
+	copy(sourcesHash[:], hash.Sum(nil))
+
+	if sourcesHash == a.sourcesHash {
+		// the same invalid content isn't parsed again and doesn't confirm the active data
+		if a.sourcesErr != nil {
+			return false, a.sourcesErr
+		}
+		a.setUpdateResult(false, nil, nil)
+		return false, nil
+	}
+	a.sourcesHash = sourcesHash
+
+	a.sourcesErr = a.setPolicyWithData(hex.EncodeToString(sourcesHash[:8]), policyData, data)
+	if a.sourcesErr != nil {
+		return false, a.sourcesErr
+	}
+
+	return true, nil
+}
+
+// initBundle applies the remote bundle, the cached one is used if the server isn't available
+func (a *Agent) initBundle() error {
+	poller, err := bundle.NewPoller(a.config.BundleURL, a.config.BundlePublicKey,
+		time.Second*time.Duration(a.config.BundlePollSeconds),
+		bundle.WithLogger(a.logger),
+		bundle.WithCacheDir(a.config.BundleCacheDir),
+		bundle.WithConfirmFunc(a.confirmData),
+	)
+	if err != nil {
+		return fmt.Errorf("init bundle poller: %w", err)
+	}
+	a.bundlePoller = poller
+
+	_, err = poller.Poll(context.Background(), a.applyBundle)
+	if err == nil {
 		return nil
 	}
 
-	data, err := os.ReadFile(a.config.DataFilePath)
+	a.logger.Error(fmt.Sprintf("polling bundle failed: %s", err.Error()))
+
+	if len(a.config.BundleCacheDir) == 0 {
+		return err
+	}
+
+	if cacheErr := poller.LoadCache(a.applyBundle); cacheErr != nil {
+		return fmt.Errorf("bundle isn't available: %w, %w", err, cacheErr)
+	}
+
+	return nil
+}
+
+// applyBundle activates policy and data of the bundle at once
+func (a *Agent) applyBundle(b *bundle.Bundle) error {
+	return a.applyRevision(b.Revision, b.Policy, b.Data)
+}
+
+// initGit applies the head of the tracked branch of the git repository
+func (a *Agent) initGit() error {
+	opts := []git.SourceOpt{
+		git.WithLogger(a.logger),
+		git.WithBranch(a.config.GitBranch),
+		git.WithPolicyPath(a.config.GitPolicyPath),
+		git.WithConfirmFunc(a.confirmData),
+	}
+	if len(a.config.GitDataPath) > 0 {
+		opts = append(opts, git.WithDataPath(a.config.GitDataPath))
+	}
+	if len(a.config.GitDir) > 0 {
+		opts = append(opts, git.WithDir(a.config.GitDir))
+	}
+
+	source, err := git.NewSource(a.config.GitRepo, time.Second*time.Duration(a.config.GitPollSeconds), opts...)
+	if err != nil {
+		return fmt.Errorf("init git source: %w", err)
+	}
+
+	if _, err := source.Sync(context.Background(), a.applyRevision); err != nil {
+		source.Close() //nolint: errcheck
+		return fmt.Errorf("git source: %w", err)
+	}
+	a.gitSource = source
+
+	return nil
+}
+
+// initControlPlane streams snapshots of the service from the control plane,
+// it waits until the first snapshot is applied
+func (a *Agent) initControlPlane() error {
+	opts := []controlplane.ClientOpt{
+		controlplane.WithLogger(a.logger),
+		controlplane.WithNodeID(a.config.ControlPlaneNodeID),
+	}
+	if a.config.ControlPlaneTLS != nil {
+		opts = append(opts, controlplane.WithDialOptions(
+			grpc.WithTransportCredentials(credentials.NewTLS(a.config.ControlPlaneTLS))))
+	}
+
+	client, err := controlplane.NewClient(a.config.ControlPlaneAddr, a.config.ControlPlaneService, opts...)
+	if err != nil {
+		return fmt.Errorf("init control plane client: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go client.Run(ctx, a.applyRevision)
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, a.config.ControlPlaneTimeout)
+	defer waitCancel()
+
+	if err := client.WaitApplied(waitCtx); err != nil {
+		cancel()
+		client.Close() //nolint: errcheck
+		return fmt.Errorf("control plane %s: %w", a.config.ControlPlaneAddr, err)
+	}
+
+	a.controlPlane = client
+	a.stopControlPlane = cancel
+
+	return nil
+}
+
+// applyRevision activates policy and data of the remote source revision at once
+func (a *Agent) applyRevision(revision string, policyData, data []byte) error {
+	a.updateMux.Lock()
+	defer a.updateMux.Unlock()
+
+	return a.setPolicyWithData(revision, policyData, data)
+}
+
+// initKube starts the kubernetes source, the agent serves the merged policy of the namespace
+func (a *Agent) initKube() error {
+	restConfig, err := clientcmd.BuildConfigFromFlags("", a.config.KubeConfigPath)
+	if err != nil {
+		return fmt.Errorf("kubernetes client config: %w", err)
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("kubernetes client: %w", err)
+	}
+
+	opts := []kube.SourceOpt{
+		kube.WithLogger(a.logger),
+		kube.WithNamespace(a.config.KubeNamespace),
+		kube.WithLabelSelector(a.config.KubeLabelSelector),
+		kube.WithClient(client),
+	}
+
+	if a.config.KubeAuthPolicies {
+		dynamicClient, err := dynamic.NewForConfig(restConfig)
+		if err != nil {
+			return fmt.Errorf("kubernetes dynamic client: %w", err)
+		}
+		opts = append(opts, kube.WithDynamicClient(dynamicClient))
+	}
+
+	if a.config.KubeReportStatus {
+		opts = append(opts, kube.WithStatusReports())
+	}
+
+	source, err := kube.NewSource(func(namespace string, policy, data []byte) error {
+		return a.applyPolicyWithData(policy, data)
+	}, opts...)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopKube = cancel
+
+	if err := source.Start(ctx); err != nil {
+		cancel()
+		return fmt.Errorf("kubernetes source: %w", err)
+	}
+
+	if a.policy.Policy() == nil {
+		cancel()
+		return fmt.Errorf("kubernetes source: namespace %s doesn't contain policy objects", a.config.KubeNamespace)
+	}
+
+	return nil
+}
+
+// applyPolicyWithData activates policy and data from the remote source at once
+func (a *Agent) applyPolicyWithData(policyData, data []byte) error {
+	a.updateMux.Lock()
+	defer a.updateMux.Unlock()
+
+	return a.setPolicyWithData("", policyData, data)
+}
+
+// setPolicyWithData activates policy and data of the revision and records the result, it's called under updateMux
+func (a *Agent) setPolicyWithData(revision string, policyData, data []byte) error {
+	// the diff is only sent to the webhook
+	var (
+		prevRules []policy.RuleStats
+		prevData  interface{}
+	)
+	if a.webhook != nil {
+		prevRules = a.policy.RuleStats()
+		prevData = a.policy.Data()
+	}
+
+	if err := a.policy.SetPolicyWithData(policyData, data); err != nil {
+		err = fmt.Errorf("policy and data updating failed: %w", err)
+		a.setUpdateResult(true, err, nil)
+		return err
+	}
+
+	policyRevision := contentRevision(policyData)
+
+	a.statusMux.Lock()
+	policyChanged := policyRevision != a.policyRevision
+	a.revision = revision
+	a.policyRevision = policyRevision
+	a.dataRevision = contentRevision(data)
+	a.policyContent = policyData
+	a.dataContent = data
+	a.statusMux.Unlock()
+
+	var diff *notify.Diff
+	if a.webhook != nil {
+		diff = notify.NewDiff(policyChanged, prevRules, a.policy.RuleStats(), prevData, a.policy.Data())
+	}
+	a.setUpdateResult(true, nil, diff)
+
+	return nil
+}
+
+// setUpdateResult records result of the policy and data update, unchanged content keeps the previous status.
+// Changed and failed updates are sent to the webhook with the diff of the successful one.
+func (a *Agent) setUpdateResult(updated bool, err error, diff *notify.Diff) {
+	a.counterUpdateTotal.Record(1, nil)
 	if err != nil {
-		return fmt.Errorf("data file updating failed: %s", err)
+		a.counterUpdateFailed.Record(1, nil)
 	}
 
-	if err := a.policy.SetData(data); err != nil {
-		return fmt.Errorf("loading data.json: %w", err)
+	a.statusMux.Lock()
+	a.updateAttempts++
+	a.lastUpdate = time.Now()
+	if err != nil {
+		a.updateFailures++
+	}
+	if updated || err != nil {
+		a.lastUpdateErr = err
 	}
+	if err == nil {
+		a.dataUpdated = a.lastUpdate
+	}
+	a.statusMux.Unlock()
+
+	if updated || err != nil {
+		a.notifyUpdate(err, diff)
+	}
+}
 
-	a.logger.Info("data file updated")
+// confirmData records that the remote source confirmed the applied policy and data are current
+func (a *Agent) confirmData() {
+	a.setUpdateResult(false, nil, nil)
+}
+
+// DataUpdated returns the time the data was last confirmed current, zero if it never was
+func (a *Agent) DataUpdated() time.Time {
+	if timer, ok := a.dataSource.(DataUpdateTimer); ok {
+		return timer.LastUpdated()
+	}
+
+	a.statusMux.RLock()
+	defer a.statusMux.RUnlock()
+
+	return a.dataUpdated
+}
+
+// DataAge returns the time since the data was last confirmed current,
+// the time since the agent was created if it never was
+func (a *Agent) DataAge() time.Duration {
+	updated := a.DataUpdated()
+	if updated.IsZero() {
+		updated = a.created
+	}
+
+	return time.Since(updated)
+}
+
+// DataStale reports whether the data is older than DataMaxStaleness of the config
+func (a *Agent) DataStale() bool {
+	return a.config.DataMaxStaleness > 0 && a.DataAge() > a.config.DataMaxStaleness
+}
+
+// readinessCheck fails until the policy and the data of the data source are loaded and all servers listen,
+// and while the data is stale in StaleModeUnready. All failed conditions are reported.
+func (a *Agent) readinessCheck() error {
+	var errs []string
+
+	if a.policy.Policy() == nil {
+		errs = append(errs, errPolicyIsNotLoaded)
+	}
+
+	if a.dataSource != nil && a.policy.Data() == nil {
+		errs = append(errs, errDataIsNotLoaded)
+	}
+
+	if a.config.DataStaleMode == StaleModeUnready && a.DataStale() {
+		errs = append(errs, fmt.Sprintf(errDataIsStale, a.DataAge().Truncate(time.Second), a.config.DataMaxStaleness))
+	}
+
+	for _, srv := range a.servers {
+		if listening, ok := srv.(ListeningServer); ok && !listening.Listening() {
+			errs = append(errs, fmt.Sprintf(errServerIsNotListening, srv))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
 
 	return nil
 }
 
+// Revision returns the active revision of the bundle, the git commit sha or the control plane snapshot.
+// For policy and data sources it's the digest of their content, it's empty for the kubernetes source.
+func (a *Agent) Revision() string {
+	a.statusMux.RLock()
+	defer a.statusMux.RUnlock()
+
+	return a.revision
+}
+
+// LastUpdateErr returns the error of the last policy and data update, nil if it succeeded
+func (a *Agent) LastUpdateErr() error {
+	a.statusMux.RLock()
+	defer a.statusMux.RUnlock()
+
+	return a.lastUpdateErr
+}
+
 func (agent *Agent) shutdown(cancel context.CancelFunc, ctx context.Context) {
 	cancel()
+	if agent.stopKube != nil {
+		agent.stopKube()
+	}
+	if agent.stopControlPlane != nil {
+		agent.stopControlPlane()
+		agent.controlPlane.Close() //nolint: errcheck
+	}
+	if agent.gitSource != nil {
+		if err := agent.gitSource.Close(); err != nil {
+			agent.logger.Error(fmt.Sprintf("removing git directory: %s", err.Error()))
+		}
+	}
 	for _, srv := range agent.servers {
 		srv.Shutdown(ctx) //nolint: errcheck
 	}
+	// the servers don't check anymore, so buffered decisions are complete
+	if agent.decisions != nil {
+		if err := agent.decisions.Close(); err != nil {
+			agent.logger.Error(fmt.Sprintf("closing decision log: %s", err.Error()))
+		}
+	}
+	if agent.shutdownTracing != nil {
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+		defer flushCancel()
+		if err := agent.shutdownTracing(flushCtx); err != nil {
+			agent.logger.Error(fmt.Sprintf("flushing spans: %s", err.Error()))
+		}
+	}
+	if agent.shutdownMetrics != nil {
+		flushCtx, flushCancel := context.WithTimeout(context.Background(), metricsFlushTimeout)
+		defer flushCancel()
+		if err := agent.shutdownMetrics(flushCtx); err != nil {
+			agent.logger.Error(fmt.Sprintf("pushing metrics: %s", err.Error()))
+		}
+	}
 }
 
 func (a *Agent) WaitUntilCompletion() {
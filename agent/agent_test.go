@@ -5,13 +5,18 @@
 package agent
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"log/slog"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/goauthlink/authlink/agent/fetch"
+	"github.com/goauthlink/authlink/pkg/logging"
+	"github.com/goauthlink/authlink/sdk/policy"
 	"github.com/goauthlink/authlink/test/testdata"
 	"github.com/goauthlink/authlink/test/util"
 	"github.com/stretchr/testify/assert"
@@ -75,6 +80,32 @@ func Test_InitNoData(t *testing.T) {
 	assert.Equal(t, nil, agent.policy.Data())
 }
 
+func Test_InitDataSources(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	hrSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"teams":["team1"]}`)) //nolint: errcheck
+	}))
+	defer hrSrv.Close()
+
+	config := DefaultConfig()
+	config.LogLevel = slog.LevelError
+	config.PolicyFilePath = rootDir + "/policy.yaml"
+	config.DataSources = []fetch.Document{
+		{Namespace: "hr", URL: hrSrv.URL},
+		{Namespace: "crm", File: rootDir + "/data.json"},
+	}
+
+	agent, err := Init(config)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"hr":  map[string]interface{}{"teams": []interface{}{"team1"}},
+		"crm": map[string]interface{}{"users": []interface{}{"user1", "user2"}},
+	}, agent.policy.Data())
+}
+
 func Test_TLSListening(t *testing.T) {
 	rootDir, cleanFs := createFiles(t)
 	defer cleanFs()
@@ -157,3 +188,82 @@ policies:
 
 	require.NoError(t, err)
 }
+
+func Test_UpdateFilesRollback(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	config := DefaultConfig()
+	config.LogLevel = slog.LevelError
+	config.PolicyFilePath = rootDir + "/policy.yaml"
+	config.DataFilePath = rootDir + "/data.json"
+
+	agent, err := Init(config)
+	require.NoError(t, err)
+	require.NoError(t, agent.LastUpdateErr())
+
+	newPolicy := `cn:
+  - header: "x-source2"
+policies:
+  - uri: ["/endpoint2"]
+    allow: ["client2"]`
+
+	// valid policy with invalid data isn't applied
+	require.NoError(t, util.ReWriteFileContent(rootDir+"/policy.yaml", []byte(newPolicy)))
+	require.NoError(t, util.ReWriteFileContent(rootDir+"/data.json", []byte(`{"users":`)))
+
+	require.ErrorContains(t, agent.reloadSources(context.Background()), "invalid json format")
+	require.ErrorContains(t, agent.LastUpdateErr(), "invalid json format")
+	assert.Equal(t, []byte(testPolicy), agent.policy.Policy())
+	assert.Equal(t, map[string]interface{}{"users": []interface{}{"user1", "user2"}}, agent.policy.Data())
+
+	// both are applied after the data is fixed
+	require.NoError(t, util.ReWriteFileContent(rootDir+"/data.json", []byte(`{"users":["user3"]}`)))
+
+	require.NoError(t, agent.reloadSources(context.Background()))
+	require.NoError(t, agent.LastUpdateErr())
+	assert.Equal(t, []byte(newPolicy), agent.policy.Policy())
+	assert.Equal(t, map[string]interface{}{"users": []interface{}{"user3"}}, agent.policy.Data())
+}
+
+func Test_ReadinessCheck(t *testing.T) {
+	config := DefaultConfig()
+	config.HttpAddr = "127.0.0.1:0"
+	config.MonitoringAddr = "127.0.0.1:0"
+
+	agent, err := New(
+		WithConfig(config),
+		WithAgentLogger(logging.NewNullLogger()),
+		WithPolicySource(NewMemorySource([]byte(testPolicy))),
+		WithDataSource(NewMemorySource([]byte(testData))),
+	)
+	require.NoError(t, err)
+
+	// servers aren't started
+	err = agent.readinessCheck()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server *agent.HttpServer isn't listening")
+	assert.Contains(t, err.Error(), "server *monitoring.Server isn't listening")
+
+	// policy and data aren't loaded
+	loaded := agent.policy
+	agent.policy = NewPolicy(policy.NewChecker(), nil)
+	err = agent.readinessCheck()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), errPolicyIsNotLoaded)
+	assert.Contains(t, err.Error(), errDataIsNotLoaded)
+	agent.policy = loaded
+
+	stop := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		agent.Run(stop) //nolint: errcheck
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return agent.readinessCheck() == nil }, 2*time.Second, 10*time.Millisecond)
+
+	stop <- struct{}{}
+	<-done
+	assert.ErrorContains(t, agent.readinessCheck(), "isn't listening")
+}
@@ -0,0 +1,306 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/goauthlink/authlink/pkg/logging"
+)
+
+const (
+	DefaultSignInterval = time.Minute
+
+	errLastRecordInvalid = "last record of audit log %s is invalid, the log must be verified and moved away: %s"
+	errLogClosed         = "audit log is closed"
+)
+
+// Record is a line of the audit log. Every record contains the hash of the previous line,
+// so changing, removing or reordering lines breaks the chain. Signature records sign the hash
+// of the previous line and with it all records before.
+type Record struct {
+	// Seq is the position of the record in the log starting from 1
+	Seq uint64 `json:"seq"`
+	// Prev is the hex encoded sha256 of the previous line, empty for the first record
+	Prev  string          `json:"prev"`
+	Entry json.RawMessage `json:"entry,omitempty"`
+	// Signature is the base64 encoded ed25519 signature of SignedMessage
+	Signature string `json:"signature,omitempty"`
+}
+
+// SignedMessage returns the message signed by the signature record at seq following the prev hash
+func SignedMessage(seq uint64, prev string) []byte {
+	return []byte(strconv.FormatUint(seq, 10) + ":" + prev)
+}
+
+// hashLine returns the hex encoded sha256 of the line without the line break
+func hashLine(line []byte) string {
+	sum := sha256.Sum256(bytes.TrimSuffix(line, []byte("\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Log appends entries to the hash chained file and signs the chain periodically
+type Log struct {
+	mux  sync.Mutex
+	file *os.File
+	key  ed25519.PrivateKey
+	seq  uint64
+	prev string
+	// unsigned is set when records were appended after the last signature
+	unsigned bool
+	closed   bool
+
+	logger       *slog.Logger
+	signInterval time.Duration
+	stop         chan struct{}
+	done         chan struct{}
+}
+
+type LogOpt func(*Log)
+
+func WithLogger(logger *slog.Logger) LogOpt {
+	return func(l *Log) {
+		l.logger = logger
+	}
+}
+
+// WithSignInterval sets the period of signing new records, DefaultSignInterval by default
+func WithSignInterval(interval time.Duration) LogOpt {
+	return func(l *Log) {
+		l.signInterval = interval
+	}
+}
+
+// Open opens the log for appending, the chain of the existing file is continued
+func Open(path string, key ed25519.PrivateKey, opts ...LogOpt) (*Log, error) {
+	l := &Log{
+		key:          key,
+		signInterval: DefaultSignInterval,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	for _, o := range opts {
+		o(l)
+	}
+
+	if l.logger == nil {
+		l.logger = logging.NewNullLogger()
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	line, err := lastLine(file)
+	if err != nil {
+		file.Close() //nolint: errcheck
+		return nil, err
+	}
+
+	if len(line) > 0 {
+		record := Record{}
+		if !bytes.HasSuffix(line, []byte("\n")) {
+			err = errors.New("the line isn't complete")
+		} else {
+			err = json.Unmarshal(line, &record)
+		}
+		if err != nil {
+			file.Close() //nolint: errcheck
+			return nil, fmt.Errorf(errLastRecordInvalid, path, err.Error())
+		}
+
+		l.seq = record.Seq
+		l.prev = hashLine(line)
+		l.unsigned = len(record.Signature) == 0
+	}
+
+	l.file = file
+
+	go l.run()
+
+	return l, nil
+}
+
+// Append writes entries as records of the chain and syncs the file
+func (l *Log) Append(entries ...json.RawMessage) error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.closed {
+		return errors.New(errLogClosed)
+	}
+
+	buf := bytes.Buffer{}
+	seq, prev := l.seq, l.prev
+	for _, entry := range entries {
+		line, err := encodeRecord(Record{Seq: seq + 1, Prev: prev, Entry: entry})
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		seq, prev = seq+1, hashLine(line)
+	}
+
+	if err := l.write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	l.seq, l.prev = seq, prev
+	l.unsigned = true
+
+	return nil
+}
+
+// Sign appends the signature record if records were appended after the last signature
+func (l *Log) Sign() error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	return l.sign()
+}
+
+func (l *Log) sign() error {
+	if !l.unsigned || l.closed {
+		return nil
+	}
+
+	seq := l.seq + 1
+	signature := ed25519.Sign(l.key, SignedMessage(seq, l.prev))
+	line, err := encodeRecord(Record{Seq: seq, Prev: l.prev, Signature: base64.StdEncoding.EncodeToString(signature)})
+	if err != nil {
+		return err
+	}
+
+	if err := l.write(line); err != nil {
+		return err
+	}
+
+	l.seq, l.prev = seq, hashLine(line)
+	l.unsigned = false
+
+	return nil
+}
+
+// write appends lines to the file, a partially written line would break the chain,
+// so the file is truncated back on failure
+func (l *Log) write(lines []byte) error {
+	info, err := l.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if _, err := l.file.Write(lines); err != nil {
+		l.file.Truncate(info.Size()) //nolint: errcheck
+		return err
+	}
+
+	return l.file.Sync()
+}
+
+// Close signs the appended records and closes the file
+func (l *Log) Close() error {
+	close(l.stop)
+	<-l.done
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	signErr := l.sign()
+	l.closed = true
+
+	return errors.Join(signErr, l.file.Close())
+}
+
+func (l *Log) run() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.signInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			if err := l.Sign(); err != nil {
+				l.logger.Error(fmt.Sprintf("signing audit log failed: %s", err.Error()))
+			}
+		}
+	}
+}
+
+func encodeRecord(record Record) ([]byte, error) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(line, '\n'), nil
+}
+
+// lastLine returns the last line of the file including the line break, the file is read backwards
+func lastLine(file *os.File) ([]byte, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	const chunkSize = 64 << 10
+
+	var line []byte
+	for end := info.Size(); end > 0; {
+		start := max(end-chunkSize, 0)
+		chunk := make([]byte, end-start)
+		if _, err := file.ReadAt(chunk, start); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		line = append(chunk, line...)
+
+		// the line break of the last line itself isn't the start of the line
+		if idx := bytes.LastIndexByte(line[:len(line)-1], '\n'); idx >= 0 {
+			return line[idx+1:], nil
+		}
+		end = start
+	}
+
+	return line, nil
+}
+
+// ParsePrivateKey parses PEM encoded PKCS #8 ed25519 private key
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key isn't PEM encoded")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key isn't ed25519 key")
+	}
+
+	return edKey, nil
+}
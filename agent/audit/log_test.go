@@ -0,0 +1,135 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readRecords(t *testing.T, path string) []Record {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	records := []Record{}
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		record := Record{}
+		require.NoError(t, json.Unmarshal(line, &record))
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func Test_LogAppend(t *testing.T) {
+	public, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, err := Open(path, key, WithSignInterval(time.Hour))
+	require.NoError(t, err)
+
+	require.NoError(t, log.Append(json.RawMessage(`{"id":"1"}`), json.RawMessage(`{"id":"2"}`)))
+	require.NoError(t, log.Sign())
+	// nothing to sign
+	require.NoError(t, log.Sign())
+	require.NoError(t, log.Append(json.RawMessage(`{"id":"3"}`)))
+	require.NoError(t, log.Close())
+	require.EqualError(t, log.Append(json.RawMessage(`{"id":"4"}`)), errLogClosed)
+
+	records := readRecords(t, path)
+	require.Len(t, records, 5)
+	assert.Equal(t, uint64(1), records[0].Seq)
+	assert.Empty(t, records[0].Prev)
+	assert.JSONEq(t, `{"id":"1"}`, string(records[0].Entry))
+	assert.NotEmpty(t, records[2].Signature)
+	assert.JSONEq(t, `{"id":"3"}`, string(records[3].Entry))
+	// the log is signed on close
+	assert.NotEmpty(t, records[4].Signature)
+
+	// the chain is continued after reopening
+	log, err = Open(path, key)
+	require.NoError(t, err)
+	require.NoError(t, log.Append(json.RawMessage(`{"id":"4"}`)))
+	require.NoError(t, log.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	report, err := Verify(file, public)
+	require.NoError(t, err)
+	assert.Equal(t, &Report{Records: 7, Signatures: 3}, report)
+}
+
+func Test_LogSignInterval(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, err := Open(path, key, WithSignInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer log.Close()
+
+	require.NoError(t, log.Append(json.RawMessage(`{"id":"1"}`)))
+
+	assert.Eventually(t, func() bool {
+		records := readRecords(t, path)
+		return len(records) == 2 && len(records[1].Signature) > 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func Test_OpenIncompleteLog(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	require.NoError(t, os.WriteFile(path, []byte(`{"seq":1,"prev":"","entry":{"id":"1"}}`+"\n"+`{"seq":2,"pr`), 0o600))
+
+	_, err = Open(path, key)
+	require.ErrorContains(t, err, "last record of audit log")
+}
+
+func Test_LastLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	long := bytes.Repeat([]byte("a"), 100<<10)
+	require.NoError(t, os.WriteFile(path, append(append([]byte("first\n"), long...), '\n'), 0o600))
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	line, err := lastLine(file)
+	require.NoError(t, err)
+	assert.Equal(t, append(long, '\n'), line)
+}
+
+func Test_ParsePrivateKey(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	parsed, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	_, err = ParsePrivateKey([]byte("key"))
+	require.Error(t, err)
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	errInvalidRecord    = "line %d: invalid record: %s"
+	errIncompleteRecord = "line %d: the last record isn't complete"
+	errRecordsMissing   = "line %d: record %d follows record %d, records are missing or out of order"
+	errRecordsReordered = "line %d: record %d follows record %d, records are repeated or out of order"
+	errChainBroken      = "line %d: hash of the previous record doesn't match, record %d or %d was modified"
+	errInvalidSignature = "line %d: invalid signature of records up to %d"
+	errEmptyRecord      = "line %d: record %d contains neither entry nor signature"
+)
+
+// Report is the result of the successful verification
+type Report struct {
+	Records    uint64
+	Signatures uint64
+	// Unsigned is the number of records after the last signature, they may be changed undetected
+	Unsigned uint64
+}
+
+// Verify checks that records of the log follow each other without gaps, every record contains
+// the hash of the previous one and signatures are valid. The first error is returned.
+func Verify(r io.Reader, key ed25519.PublicKey) (*Report, error) {
+	reader := bufio.NewReader(r)
+	report := &Report{}
+	prev := ""
+
+	for lineNum := 1; ; lineNum++ {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				return nil, fmt.Errorf(errIncompleteRecord, lineNum)
+			}
+			return report, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		record := Record{}
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf(errInvalidRecord, lineNum, err.Error())
+		}
+
+		expected := report.Records + 1
+		switch {
+		case record.Seq > expected:
+			return nil, fmt.Errorf(errRecordsMissing, lineNum, record.Seq, report.Records)
+		case record.Seq < expected:
+			return nil, fmt.Errorf(errRecordsReordered, lineNum, record.Seq, report.Records)
+		case record.Prev != prev:
+			return nil, fmt.Errorf(errChainBroken, lineNum, record.Seq-1, record.Seq)
+		}
+
+		switch {
+		case len(record.Signature) > 0 && len(record.Entry) > 0:
+			return nil, fmt.Errorf(errInvalidRecord, lineNum, "record contains both entry and signature")
+		case len(record.Signature) > 0:
+			signature, err := base64.StdEncoding.DecodeString(record.Signature)
+			if err != nil || !ed25519.Verify(key, SignedMessage(record.Seq, record.Prev), signature) {
+				return nil, fmt.Errorf(errInvalidSignature, lineNum, record.Seq-1)
+			}
+			report.Signatures++
+			report.Unsigned = 0
+		case len(record.Entry) > 0:
+			report.Unsigned++
+		default:
+			return nil, fmt.Errorf(errEmptyRecord, lineNum, record.Seq)
+		}
+
+		report.Records++
+		prev = hashLine(line)
+	}
+}
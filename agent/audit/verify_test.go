@@ -0,0 +1,154 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package audit
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createLog returns lines of the log with 3 entries, the signature and 2 unsigned entries
+func createLog(t *testing.T, key ed25519.PrivateKey) []string {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, err := Open(path, key, WithSignInterval(time.Hour))
+	require.NoError(t, err)
+
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, log.Append(json.RawMessage(fmt.Sprintf(`{"id":"%d"}`, i))))
+	}
+	require.NoError(t, log.Sign())
+	require.NoError(t, log.Append(json.RawMessage(`{"id":"4"}`), json.RawMessage(`{"id":"5"}`)))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, log.Close())
+
+	lines := strings.SplitAfter(string(content), "\n")
+
+	return lines[:len(lines)-1]
+}
+
+func Test_Verify(t *testing.T) {
+	public, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	otherPublic, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	lines := createLog(t, key)
+	require.Len(t, lines, 6)
+
+	report, err := Verify(strings.NewReader(strings.Join(lines, "")), public)
+	require.NoError(t, err)
+	assert.Equal(t, &Report{Records: 6, Signatures: 1, Unsigned: 2}, report)
+
+	report, err = Verify(strings.NewReader(""), public)
+	require.NoError(t, err)
+	assert.Equal(t, &Report{}, report)
+
+	modify := func(lines []string, i int, from, to string) []string {
+		lines = append([]string{}, lines...)
+		lines[i] = strings.Replace(lines[i], from, to, 1)
+		return lines
+	}
+
+	cases := map[string]struct {
+		lines []string
+		key   ed25519.PublicKey
+		err   string
+	}{
+		"modified entry": {
+			lines: modify(lines, 1, `"id":"2"`, `"id":"X"`),
+			err:   fmt.Sprintf(errChainBroken, 3, 2, 3),
+		},
+		"removed record": {
+			lines: append(append([]string{}, lines[:1]...), lines[2:]...),
+			err:   fmt.Sprintf(errRecordsMissing, 2, 3, 1),
+		},
+		"reordered records": {
+			lines: []string{lines[0], lines[2], lines[1], lines[3], lines[4], lines[5]},
+			err:   fmt.Sprintf(errRecordsMissing, 2, 3, 1),
+		},
+		"replayed record": {
+			lines: []string{lines[0], lines[1], lines[1]},
+			err:   fmt.Sprintf(errRecordsReordered, 3, 2, 2),
+		},
+		"modified and rehashed chain": {
+			lines: rehash(t, modify(lines, 1, `"id":"2"`, `"id":"X"`)),
+			err:   fmt.Sprintf(errInvalidSignature, 4, 3),
+		},
+		"other key": {
+			lines: lines,
+			key:   otherPublic,
+			err:   fmt.Sprintf(errInvalidSignature, 4, 3),
+		},
+		"incomplete record": {
+			lines: append(append([]string{}, lines[:5]...), strings.TrimSuffix(lines[5], "\n")),
+			err:   fmt.Sprintf(errIncompleteRecord, 6),
+		},
+		"invalid record": {
+			lines: []string{"not json\n"},
+			err:   "line 1: invalid record",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			verifyKey := public
+			if c.key != nil {
+				verifyKey = c.key
+			}
+			_, err := Verify(strings.NewReader(strings.Join(c.lines, "")), verifyKey)
+			require.ErrorContains(t, err, c.err)
+		})
+	}
+}
+
+// rehash recalculates prev hashes of the records as a forger without the signing key would do
+func rehash(t *testing.T, lines []string) []string {
+	result := []string{}
+	prev := ""
+	for _, line := range lines {
+		record := Record{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		record.Prev = prev
+
+		encoded, err := encodeRecord(record)
+		require.NoError(t, err)
+		result = append(result, string(encoded))
+		prev = hashLine(encoded)
+	}
+
+	return result
+}
+
+func Test_VerifyLongRecord(t *testing.T) {
+	public, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, err := Open(path, key)
+	require.NoError(t, err)
+	entry := fmt.Sprintf(`{"header":"%s"}`, bytes.Repeat([]byte("a"), 1<<20))
+	require.NoError(t, log.Append(json.RawMessage(entry)))
+	require.NoError(t, log.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	report, err := Verify(file, public)
+	require.NoError(t, err)
+	assert.Equal(t, &Report{Records: 2, Signatures: 1}, report)
+}
@@ -0,0 +1,141 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+const (
+	PolicyFileName   = "policy.yaml"
+	DataFileName     = "data.json"
+	ManifestFileName = ".manifest"
+)
+
+// maxFileSize limits size of a single unpacked file of the bundle
+const maxFileSize = 64 << 20
+
+const (
+	errBundleWithoutPolicy   = "bundle doesn't contain " + PolicyFileName
+	errBundleWithoutManifest = "bundle doesn't contain " + ManifestFileName
+	errBundleWithoutRevision = "bundle manifest doesn't contain revision"
+	errInvalidSignature      = "bundle signature is invalid"
+)
+
+// Bundle is a policy with data distributed as a tar.gz archive
+type Bundle struct {
+	Policy   []byte
+	Data     []byte
+	Revision string
+}
+
+type manifest struct {
+	Revision string `json:"revision"`
+}
+
+// Read unpacks tar.gz archive of the bundle, data is optional
+func Read(archive []byte) (*Bundle, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("read bundle gzip: %w", err)
+	}
+	defer gz.Close()
+
+	b := &Bundle{}
+	var mf *manifest
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read bundle tar: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if hdr.Size > maxFileSize {
+			return nil, fmt.Errorf("bundle file %s is too large", hdr.Name)
+		}
+
+		content, err := io.ReadAll(io.LimitReader(tr, maxFileSize))
+		if err != nil {
+			return nil, fmt.Errorf("read bundle file %s: %w", hdr.Name, err)
+		}
+
+		switch path.Clean(strings.TrimPrefix(hdr.Name, "/")) {
+		case PolicyFileName:
+			b.Policy = content
+		case DataFileName:
+			b.Data = content
+		case ManifestFileName:
+			mf = &manifest{}
+			if err := json.Unmarshal(content, mf); err != nil {
+				return nil, fmt.Errorf("parse bundle manifest: %w", err)
+			}
+		}
+	}
+
+	if b.Policy == nil {
+		return nil, errors.New(errBundleWithoutPolicy)
+	}
+	if mf == nil {
+		return nil, errors.New(errBundleWithoutManifest)
+	}
+	if len(mf.Revision) == 0 {
+		return nil, errors.New(errBundleWithoutRevision)
+	}
+	b.Revision = mf.Revision
+
+	return b, nil
+}
+
+// Verify checks detached base64 encoded ed25519 signature of the archive
+func Verify(archive, signature []byte, key ed25519.PublicKey) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("decode bundle signature: %w", err)
+	}
+
+	if !ed25519.Verify(key, archive, sig) {
+		return errors.New(errInvalidSignature)
+	}
+
+	return nil
+}
+
+// ParsePublicKey parses PEM encoded PKIX ed25519 public key
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("public key isn't PEM encoded")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("public key isn't ed25519 key")
+	}
+
+	return edKey, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `cn:
+  - header: "x-source"
+policies:
+  - uri: ["/endpoint"]
+    allow: ["client"]`
+
+func makeArchive(t *testing.T, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0o644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	return buf.Bytes()
+}
+
+func sign(key ed25519.PrivateKey, archive []byte) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, archive)))
+}
+
+func Test_Read(t *testing.T) {
+	archive := makeArchive(t, map[string]string{
+		PolicyFileName:   testPolicy,
+		DataFileName:     `{"users":["user1"]}`,
+		ManifestFileName: `{"revision":"rev1"}`,
+	})
+
+	b, err := Read(archive)
+	require.NoError(t, err)
+	assert.Equal(t, &Bundle{
+		Policy:   []byte(testPolicy),
+		Data:     []byte(`{"users":["user1"]}`),
+		Revision: "rev1",
+	}, b)
+
+	// data is optional
+	b, err = Read(makeArchive(t, map[string]string{
+		"./" + PolicyFileName: testPolicy,
+		ManifestFileName:      `{"revision":"rev2"}`,
+	}))
+	require.NoError(t, err)
+	assert.Nil(t, b.Data)
+	assert.Equal(t, "rev2", b.Revision)
+
+	_, err = Read(makeArchive(t, map[string]string{ManifestFileName: `{"revision":"rev1"}`}))
+	require.EqualError(t, err, errBundleWithoutPolicy)
+
+	_, err = Read(makeArchive(t, map[string]string{PolicyFileName: testPolicy}))
+	require.EqualError(t, err, errBundleWithoutManifest)
+
+	_, err = Read(makeArchive(t, map[string]string{PolicyFileName: testPolicy, ManifestFileName: `{}`}))
+	require.EqualError(t, err, errBundleWithoutRevision)
+}
+
+func Test_Verify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	archive := []byte("archive")
+
+	require.NoError(t, Verify(archive, sign(priv, archive), pub))
+	require.EqualError(t, Verify(archive, sign(otherPriv, archive), pub), errInvalidSignature)
+	require.EqualError(t, Verify([]byte("modified"), sign(priv, archive), pub), errInvalidSignature)
+}
+
+func Test_ParsePublicKey(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+
+	key, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.Equal(t, pub, key)
+
+	_, err = ParsePublicKey([]byte("key"))
+	require.Error(t, err)
+}
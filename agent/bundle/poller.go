@@ -0,0 +1,308 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/goauthlink/authlink/pkg/logging"
+)
+
+const (
+	// SignatureSuffix is appended to the bundle url to get its detached signature
+	SignatureSuffix = ".sig"
+
+	maxArchiveSize = 128 << 20
+
+	cacheArchiveFile   = "bundle.tar.gz"
+	cacheSignatureFile = "bundle.tar.gz.sig"
+	cacheETagFile      = "etag"
+)
+
+var errNotModified = errors.New("bundle not modified")
+
+// ApplyFunc activates the bundle, the bundle is cached only if it's applied
+type ApplyFunc func(b *Bundle) error
+
+type Poller struct {
+	url        string
+	key        ed25519.PublicKey
+	cacheDir   string
+	interval   time.Duration
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	client     *http.Client
+	logger     *slog.Logger
+	etag       string
+	confirm    func()
+}
+
+type PollerOpt func(*Poller)
+
+func WithLogger(logger *slog.Logger) PollerOpt {
+	return func(p *Poller) {
+		p.logger = logger
+	}
+}
+
+func WithHTTPClient(client *http.Client) PollerOpt {
+	return func(p *Poller) {
+		p.client = client
+	}
+}
+
+// WithCacheDir sets directory where the last applied bundle is persisted
+func WithCacheDir(dir string) PollerOpt {
+	return func(p *Poller) {
+		p.cacheDir = dir
+	}
+}
+
+// WithBackoff sets bounds of exponential backoff used after failed polls
+func WithBackoff(min, max time.Duration) PollerOpt {
+	return func(p *Poller) {
+		p.minBackoff = min
+		p.maxBackoff = max
+	}
+}
+
+// WithConfirmFunc sets the function called when the server confirms the applied bundle is current
+func WithConfirmFunc(confirm func()) PollerOpt {
+	return func(p *Poller) {
+		p.confirm = confirm
+	}
+}
+
+func NewPoller(url string, key ed25519.PublicKey, interval time.Duration, opts ...PollerOpt) (*Poller, error) {
+	if len(url) == 0 {
+		return nil, errors.New("bundle url is required")
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("bundle public key is required")
+	}
+	if interval <= 0 {
+		return nil, errors.New("bundle polling interval must be greater than 0")
+	}
+
+	p := &Poller{
+		url:        url,
+		key:        key,
+		interval:   interval,
+		minBackoff: time.Second,
+		maxBackoff: interval,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+
+	for _, o := range opts {
+		o(p)
+	}
+
+	if p.logger == nil {
+		p.logger = logging.NewNullLogger()
+	}
+
+	return p, nil
+}
+
+// Poll fetches the bundle once and applies it if it was changed.
+// It returns false if the server responded that the bundle isn't modified.
+func (p *Poller) Poll(ctx context.Context, apply ApplyFunc) (bool, error) {
+	archive, etag, err := p.fetch(ctx, p.url, p.etag)
+	if errors.Is(err, errNotModified) {
+		if p.confirm != nil {
+			p.confirm()
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("fetch bundle: %w", err)
+	}
+
+	signature, _, err := p.fetch(ctx, p.url+SignatureSuffix, "")
+	if err != nil {
+		return false, fmt.Errorf("fetch bundle signature: %w", err)
+	}
+
+	b, err := p.verifyAndRead(archive, signature)
+	if err != nil {
+		return false, err
+	}
+
+	if err := apply(b); err != nil {
+		return false, fmt.Errorf("apply bundle revision %s: %w", b.Revision, err)
+	}
+
+	p.etag = etag
+	if err := p.saveCache(archive, signature, etag); err != nil {
+		p.logger.Error(fmt.Sprintf("persist bundle: %s", err.Error()))
+	}
+
+	p.logger.Info(fmt.Sprintf("bundle revision %s applied", b.Revision))
+
+	return true, nil
+}
+
+// LoadCache applies the last persisted bundle, it's used when the server is unavailable on start
+func (p *Poller) LoadCache(apply ApplyFunc) error {
+	if len(p.cacheDir) == 0 {
+		return errors.New("bundle cache directory isn't configured")
+	}
+
+	archive, err := os.ReadFile(filepath.Join(p.cacheDir, cacheArchiveFile))
+	if err != nil {
+		return fmt.Errorf("read cached bundle: %w", err)
+	}
+	signature, err := os.ReadFile(filepath.Join(p.cacheDir, cacheSignatureFile))
+	if err != nil {
+		return fmt.Errorf("read cached bundle signature: %w", err)
+	}
+
+	b, err := p.verifyAndRead(archive, signature)
+	if err != nil {
+		return fmt.Errorf("cached bundle: %w", err)
+	}
+
+	if err := apply(b); err != nil {
+		return fmt.Errorf("apply cached bundle revision %s: %w", b.Revision, err)
+	}
+
+	if etag, err := os.ReadFile(filepath.Join(p.cacheDir, cacheETagFile)); err == nil {
+		p.etag = string(etag)
+	}
+
+	p.logger.Info(fmt.Sprintf("cached bundle revision %s applied", b.Revision))
+
+	return nil
+}
+
+// Run polls the bundle every interval until the context is done,
+// failed polls are retried with exponential backoff.
+func (p *Poller) Run(ctx context.Context, apply ApplyFunc) {
+	backoff := time.Duration(0)
+
+	for {
+		wait := p.interval
+		if backoff > 0 {
+			wait = backoff
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if _, err := p.Poll(ctx, apply); err != nil {
+			backoff = p.nextBackoff(backoff)
+			p.logger.Error(fmt.Sprintf("polling bundle failed, retry in %s: %s", backoff, err.Error()))
+			continue
+		}
+		backoff = 0
+	}
+}
+
+func (p *Poller) nextBackoff(current time.Duration) time.Duration {
+	if current <= 0 {
+		return p.minBackoff
+	}
+
+	next := current * 2
+	if next > p.maxBackoff {
+		return p.maxBackoff
+	}
+
+	return next
+}
+
+func (p *Poller) verifyAndRead(archive, signature []byte) (*Bundle, error) {
+	if err := Verify(archive, signature, p.key); err != nil {
+		return nil, err
+	}
+
+	return Read(archive)
+}
+
+func (p *Poller) fetch(ctx context.Context, url, etag string) ([]byte, string, error) {
+	rq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(etag) > 0 {
+		rq.Header.Set("If-None-Match", etag)
+	}
+
+	rsp, err := p.client.Do(rq)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rsp.Body.Close()
+
+	switch rsp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, "", errNotModified
+	default:
+		return nil, "", fmt.Errorf("unexpected response status %d", rsp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(rsp.Body, maxArchiveSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(body) > maxArchiveSize {
+		return nil, "", errors.New("bundle is too large")
+	}
+
+	return body, rsp.Header.Get("ETag"), nil
+}
+
+func (p *Poller) saveCache(archive, signature []byte, etag string) error {
+	if len(p.cacheDir) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(p.cacheDir, 0o755); err != nil {
+		return err
+	}
+
+	for name, content := range map[string][]byte{
+		cacheArchiveFile:   archive,
+		cacheSignatureFile: signature,
+		cacheETagFile:      []byte(etag),
+	} {
+		if err := writeFileAtomic(filepath.Join(p.cacheDir, name), content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeFileAtomic(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,219 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testBundleServer struct {
+	mux       sync.Mutex
+	archive   []byte
+	signature []byte
+	etag      string
+	requests  int
+	down      bool
+}
+
+func (s *testBundleServer) set(archive, signature []byte, etag string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.archive, s.signature, s.etag = archive, signature, etag
+}
+
+func (s *testBundleServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/bundle.tar.gz":
+		s.requests++
+		if r.Header.Get("If-None-Match") == s.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", s.etag)
+		w.Write(s.archive) //nolint: errcheck
+	case "/bundle.tar.gz" + SignatureSuffix:
+		w.Write(s.signature) //nolint: errcheck
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestBundle(t *testing.T, key ed25519.PrivateKey, revision string) ([]byte, []byte) {
+	archive := makeArchive(t, map[string]string{
+		PolicyFileName:   testPolicy,
+		DataFileName:     `{"users":["user1"]}`,
+		ManifestFileName: `{"revision":"` + revision + `"}`,
+	})
+
+	return archive, sign(key, archive)
+}
+
+func Test_PollETag(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	bundleSrv := &testBundleServer{}
+	archive, signature := newTestBundle(t, priv, "rev1")
+	bundleSrv.set(archive, signature, `"rev1"`)
+
+	srv := httptest.NewServer(bundleSrv)
+	defer srv.Close()
+
+	confirmed := 0
+	poller, err := NewPoller(srv.URL+"/bundle.tar.gz", pub, time.Minute, WithConfirmFunc(func() { confirmed++ }))
+	require.NoError(t, err)
+
+	applied := []string{}
+	apply := func(b *Bundle) error {
+		applied = append(applied, b.Revision)
+		return nil
+	}
+
+	updated, err := poller.Poll(context.Background(), apply)
+	require.NoError(t, err)
+	assert.True(t, updated)
+
+	// not modified confirms the applied bundle
+	updated, err = poller.Poll(context.Background(), apply)
+	require.NoError(t, err)
+	assert.False(t, updated)
+	assert.Equal(t, 1, confirmed)
+
+	archive, signature = newTestBundle(t, priv, "rev2")
+	bundleSrv.set(archive, signature, `"rev2"`)
+
+	updated, err = poller.Poll(context.Background(), apply)
+	require.NoError(t, err)
+	assert.True(t, updated)
+
+	assert.Equal(t, []string{"rev1", "rev2"}, applied)
+	assert.Equal(t, 3, bundleSrv.requests)
+	assert.Equal(t, 1, confirmed)
+}
+
+func Test_PollInvalidSignature(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	bundleSrv := &testBundleServer{}
+	archive, signature := newTestBundle(t, otherPriv, "rev1")
+	bundleSrv.set(archive, signature, `"rev1"`)
+
+	srv := httptest.NewServer(bundleSrv)
+	defer srv.Close()
+
+	poller, err := NewPoller(srv.URL+"/bundle.tar.gz", pub, time.Minute)
+	require.NoError(t, err)
+
+	_, err = poller.Poll(context.Background(), func(b *Bundle) error {
+		t.Fatal("bundle with invalid signature must not be applied")
+		return nil
+	})
+	require.EqualError(t, err, errInvalidSignature)
+}
+
+func Test_PollCache(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	bundleSrv := &testBundleServer{}
+	archive, signature := newTestBundle(t, priv, "rev1")
+	bundleSrv.set(archive, signature, `"rev1"`)
+
+	srv := httptest.NewServer(bundleSrv)
+	defer srv.Close()
+
+	cacheDir := t.TempDir()
+
+	poller, err := NewPoller(srv.URL+"/bundle.tar.gz", pub, time.Minute, WithCacheDir(cacheDir))
+	require.NoError(t, err)
+
+	// failed apply isn't cached
+	_, err = poller.Poll(context.Background(), func(b *Bundle) error { return assert.AnError })
+	require.ErrorIs(t, err, assert.AnError)
+	require.Error(t, poller.LoadCache(func(b *Bundle) error { return nil }))
+
+	_, err = poller.Poll(context.Background(), func(b *Bundle) error { return nil })
+	require.NoError(t, err)
+
+	// restart while the server is down
+	bundleSrv.mux.Lock()
+	bundleSrv.down = true
+	bundleSrv.mux.Unlock()
+
+	restarted, err := NewPoller(srv.URL+"/bundle.tar.gz", pub, time.Minute, WithCacheDir(cacheDir))
+	require.NoError(t, err)
+
+	_, err = restarted.Poll(context.Background(), func(b *Bundle) error { return nil })
+	require.Error(t, err)
+
+	var cached *Bundle
+	require.NoError(t, restarted.LoadCache(func(b *Bundle) error {
+		cached = b
+		return nil
+	}))
+	assert.Equal(t, "rev1", cached.Revision)
+	assert.Equal(t, `"rev1"`, restarted.etag)
+}
+
+func Test_PollRunBackoff(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	bundleSrv := &testBundleServer{down: true}
+	archive, signature := newTestBundle(t, priv, "rev1")
+	bundleSrv.set(archive, signature, `"rev1"`)
+
+	srv := httptest.NewServer(bundleSrv)
+	defer srv.Close()
+
+	poller, err := NewPoller(srv.URL+"/bundle.tar.gz", pub, 20*time.Millisecond, WithBackoff(10*time.Millisecond, 80*time.Millisecond))
+	require.NoError(t, err)
+
+	assert.Equal(t, 10*time.Millisecond, poller.nextBackoff(0))
+	assert.Equal(t, 40*time.Millisecond, poller.nextBackoff(20*time.Millisecond))
+	assert.Equal(t, 80*time.Millisecond, poller.nextBackoff(80*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	applied := make(chan string, 1)
+	go poller.Run(ctx, func(b *Bundle) error {
+		applied <- b.Revision
+		return nil
+	})
+
+	time.Sleep(100 * time.Millisecond)
+	bundleSrv.mux.Lock()
+	bundleSrv.down = false
+	bundleSrv.mux.Unlock()
+
+	select {
+	case rev := <-applied:
+		assert.Equal(t, "rev1", rev)
+	case <-time.After(2 * time.Second):
+		t.Fatal("bundle wasn't applied after the server recovery")
+	}
+}
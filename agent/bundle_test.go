@@ -0,0 +1,84 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goauthlink/authlink/agent/bundle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBundleServer(t *testing.T) (*httptest.Server, ed25519.PublicKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{
+		bundle.PolicyFileName:   testPolicy,
+		bundle.DataFileName:     testData,
+		bundle.ManifestFileName: `{"revision":"rev1"}`,
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	archive := buf.Bytes()
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, archive))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/bundle.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive) //nolint: errcheck
+	})
+	mux.HandleFunc("/bundle.tar.gz"+bundle.SignatureSuffix, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(signature)) //nolint: errcheck
+	})
+
+	return httptest.NewServer(mux), pub
+}
+
+func Test_InitBundle(t *testing.T) {
+	srv, pub := newTestBundleServer(t)
+
+	config := DefaultConfig()
+	config.LogLevel = slog.LevelError
+	config.PolicyFilePath = ""
+	config.BundleURL = srv.URL + "/bundle.tar.gz"
+	config.BundlePublicKey = pub
+	config.BundleCacheDir = t.TempDir()
+
+	agent, err := Init(config)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(testPolicy), agent.policy.Policy())
+	assert.Equal(t, map[string]interface{}{"users": []interface{}{"user1", "user2"}}, agent.policy.Data())
+	assert.Equal(t, "rev1", agent.Revision())
+
+	// the cached bundle is used while the server is down
+	srv.Close()
+
+	agent, err = Init(config)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(testPolicy), agent.policy.Policy())
+
+	// no server and no cache
+	config.BundleCacheDir = t.TempDir()
+	_, err = Init(config)
+	require.Error(t, err)
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/goauthlink/authlink/agent/audit"
+	"github.com/goauthlink/authlink/agent/bundle"
+	"github.com/spf13/cobra"
+)
+
+func newAuditCmd() *cobra.Command {
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Manage audit logs",
+	}
+
+	var publicKeyPath string
+	verifyCmd := &cobra.Command{
+		Use:   "verify [audit.log]...",
+		Short: "Verify hash chain and signatures of audit logs",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(command *cobra.Command, args []string) {
+			failed := false
+			for _, path := range args {
+				if err := verifyAuditLog(path, publicKeyPath, os.Stdout); err != nil {
+					fmt.Printf("%s: FAILED: %s\n", path, err.Error())
+					failed = true
+				}
+			}
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+	verifyCmd.Flags().StringVar(&publicKeyPath, "public-key", "", "set path of PEM encoded ed25519 public key of the audit log signing key")
+	verifyCmd.MarkFlagRequired("public-key") //nolint: errcheck
+
+	auditCmd.AddCommand(verifyCmd)
+
+	return auditCmd
+}
+
+// verifyAuditLog verifies the audit log and writes the report to out
+func verifyAuditLog(path, publicKeyPath string, out io.Writer) error {
+	keyData, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read public key: %w", err)
+	}
+	key, err := bundle.ParsePublicKey(keyData)
+	if err != nil {
+		return fmt.Errorf("failed to load public key: %w", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	report, err := audit.Verify(file, key)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%s: OK: %d records, %d signatures\n", path, report.Records, report.Signatures)
+	if report.Unsigned > 0 {
+		fmt.Fprintf(out, "%s: WARNING: the last %d records aren't signed yet\n", path, report.Unsigned)
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goauthlink/authlink/agent/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_VerifyAuditLog(t *testing.T) {
+	dir := t.TempDir()
+	public, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	keyPath := filepath.Join(dir, "audit.pub")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	path := filepath.Join(dir, "audit.log")
+	log, err := audit.Open(path, key)
+	require.NoError(t, err)
+	require.NoError(t, log.Append(json.RawMessage(`{"id":"1"}`)))
+	require.NoError(t, log.Sign())
+	require.NoError(t, log.Append(json.RawMessage(`{"id":"2"}`)))
+
+	out := &bytes.Buffer{}
+	require.NoError(t, verifyAuditLog(path, keyPath, out))
+	assert.Equal(t, path+": OK: 3 records, 1 signatures\n"+path+": WARNING: the last 1 records aren't signed yet\n", out.String())
+
+	require.NoError(t, log.Close())
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(string(content), `"id":"1"`, `"id":"3"`, 1)), 0o600))
+
+	require.ErrorContains(t, verifyAuditLog(path, keyPath, out), "hash of the previous record doesn't match")
+	require.ErrorContains(t, verifyAuditLog(path, path, out), "failed to load public key")
+}
@@ -19,6 +19,7 @@ func NewRootCommand(runExtensions ...RunExtension) *cobra.Command {
 
 	rootCmd.AddCommand(newRunCmd(runExtensions...))
 	rootCmd.AddCommand(newVersionCmd())
+	rootCmd.AddCommand(newAuditCmd())
 
 	return rootCmd
 }
@@ -6,14 +6,24 @@ package app
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/goauthlink/authlink/agent"
+	"github.com/goauthlink/authlink/agent/bundle"
+	"github.com/goauthlink/authlink/agent/decision"
+	"github.com/goauthlink/authlink/agent/fetch"
+	"github.com/goauthlink/authlink/agent/git"
+	"github.com/goauthlink/authlink/agent/kube"
+	"github.com/goauthlink/authlink/agent/monitoring"
 	"github.com/goauthlink/authlink/pkg/cmd"
 	"github.com/goauthlink/authlink/pkg/logging"
+	"github.com/goauthlink/authlink/pkg/metrics"
 	"github.com/spf13/cobra"
 )
 
@@ -23,14 +33,52 @@ type RunExtension interface {
 }
 
 type runCmdParams struct {
-	logLevel           string
-	logCheckResults    bool
-	httpAddr           string
-	observeAddr        string
-	updateFilesSeconds int
-	tlsDisable         bool
-	tlsPrivateKeyPath  string
-	tlsCertPath        string
+	logLevel            string
+	logCheckResults     bool
+	httpAddr            string
+	observeAddr         string
+	updateFilesSeconds  int
+	watchFiles          bool
+	tlsDisable          bool
+	tlsPrivateKeyPath   string
+	tlsCertPath         string
+	bundleURL           string
+	bundlePublicKey     string
+	bundleCacheDir      string
+	bundlePollSeconds   int
+	kubeNamespace       string
+	kubeLabelSelector   string
+	kubeAuthPolicies    bool
+	kubeConfigPath      string
+	kubeReportStatus    bool
+	adminAddr           string
+	adminTokenFile      string
+	dataSourcesFile     string
+	dataMaxStaleness    time.Duration
+	dataStaleMode       string
+	gitRepo             string
+	gitBranch           string
+	gitPolicyPath       string
+	gitDataPath         string
+	gitDir              string
+	gitPollSeconds      int
+	gitWebhookSecret    string
+	controlPlaneAddr    string
+	controlPlaneService string
+	controlPlaneNodeID  string
+	controlPlaneCAFile  string
+	controlPlaneTimeout time.Duration
+	decisionLogConfig   string
+	tracingEndpoint     string
+	tracingInsecure     bool
+	tracingSampleRatio  float64
+	metricsClients      []string
+	metricsMaxSeries    int
+	metricsPath         string
+	metricsOTLPEndpoint string
+	metricsOTLPInsecure bool
+	metricsOTLPInterval time.Duration
+	reloadWebhookURL    string
 }
 
 func exitErr(msg string) {
@@ -79,12 +127,50 @@ func newRunCmd(extensions ...RunExtension) *cobra.Command {
 	runCmd.Flags().StringVar(&cmdParams.logLevel, "log-level", "info", "set log level")
 
 	runCmd.Flags().StringVar(&cmdParams.httpAddr, "http-addr", ":8181", "set listening address of the http server (e.g., [ip]:<port>)")
-	runCmd.Flags().StringVar(&cmdParams.observeAddr, "monitoring-addr", ":9191", "set listening address for the /health and /metrics (e.g., [ip]:<port>)")
-	runCmd.Flags().BoolVar(&cmdParams.logCheckResults, "log-check-results", false, "log info about check requests results (default false)")
+	runCmd.Flags().StringVar(&cmdParams.observeAddr, "monitoring-addr", ":9191", "set listening address for the /health, /ready and metrics (e.g., [ip]:<port>)")
+	runCmd.Flags().BoolVar(&cmdParams.logCheckResults, "log-check-results", false, "log decisions to stdout as json lines (default false)")
+	runCmd.Flags().StringVar(&cmdParams.decisionLogConfig, "decision-log-config", "", "set path of yaml file with sinks of decision logs (default empty - decisions aren't logged)")
+	runCmd.Flags().StringVar(&cmdParams.tracingEndpoint, "tracing-endpoint", "", "set host:port of the OTLP gRPC collector receiving spans of checks (default empty - checks aren't traced)")
+	runCmd.Flags().BoolVar(&cmdParams.tracingInsecure, "tracing-insecure", false, "disable TLS of the tracing collector connection (default false)")
+	runCmd.Flags().Float64Var(&cmdParams.tracingSampleRatio, "tracing-sample-ratio", 1, "set share of traced checks without a remote parent span from 0 to 1")
+	runCmd.Flags().StringSliceVar(&cmdParams.metricsClients, "metrics-clients", nil, "set client names labelled in decision metrics, other clients are counted as \"other\"")
+	runCmd.Flags().IntVar(&cmdParams.metricsMaxSeries, "metrics-max-series", agent.DefaultMetricsMaxSeries, "set max number of distinct label sets of decision metrics, further ones are counted as \"other\"")
+	runCmd.Flags().StringVar(&cmdParams.metricsPath, "metrics-path", monitoring.DefaultMetricsPath, "set path of the prometheus scrape endpoint of the monitoring server")
+	runCmd.Flags().StringVar(&cmdParams.metricsOTLPEndpoint, "metrics-otlp-endpoint", "", "set host:port of the OTLP gRPC collector metrics are pushed to (default empty - metrics aren't pushed)")
+	runCmd.Flags().BoolVar(&cmdParams.metricsOTLPInsecure, "metrics-otlp-insecure", false, "disable TLS of the metrics collector connection (default false)")
+	runCmd.Flags().DurationVar(&cmdParams.metricsOTLPInterval, "metrics-otlp-interval", metrics.DefaultOTLPInterval, "set period of pushing metrics to the OTLP collector")
+	runCmd.Flags().StringVar(&cmdParams.reloadWebhookURL, "reload-webhook-url", "", "set url receiving changed and failed policy and data updates as json (default empty - not sent)")
 	runCmd.Flags().IntVar(&cmdParams.updateFilesSeconds, "update-files-seconds", 0, "set policy/data file updating period (seconds) (default 0 - do not update)")
+	runCmd.Flags().BoolVar(&cmdParams.watchFiles, "watch-files", false, "reload policy/data files on change using file system notifications (default false)")
 	runCmd.Flags().BoolVar(&cmdParams.tlsDisable, "tls-disable", false, "disables TLS completely")
 	runCmd.Flags().StringVar(&cmdParams.tlsPrivateKeyPath, "tls-private-key", "", "set path of TLS private key file")
 	runCmd.Flags().StringVar(&cmdParams.tlsCertPath, "tls-cert", "", "set path of TLS certificate file")
+	runCmd.Flags().StringVar(&cmdParams.bundleURL, "bundle-url", "", "set url of a signed policy bundle (tar.gz) to poll instead of policy/data files")
+	runCmd.Flags().StringVar(&cmdParams.bundlePublicKey, "bundle-public-key", "", "set path of PEM encoded ed25519 public key verifying bundle signatures")
+	runCmd.Flags().StringVar(&cmdParams.bundleCacheDir, "bundle-cache-dir", "", "set directory persisting the last applied bundle (default empty - do not persist)")
+	runCmd.Flags().IntVar(&cmdParams.bundlePollSeconds, "bundle-poll-seconds", 60, "set bundle polling period (seconds)")
+	runCmd.Flags().StringVar(&cmdParams.kubeNamespace, "kube-namespace", "", "set kubernetes namespace to load merged policy objects from instead of policy/data files")
+	runCmd.Flags().StringVar(&cmdParams.kubeLabelSelector, "kube-label-selector", kube.DefaultLabelSelector, "set label selector of policy ConfigMaps and Secrets")
+	runCmd.Flags().BoolVar(&cmdParams.kubeAuthPolicies, "kube-auth-policies", false, "load AuthPolicy custom resources as well (default false)")
+	runCmd.Flags().StringVar(&cmdParams.kubeConfigPath, "kubeconfig", "", "set path of kubeconfig file (default empty - in-cluster config)")
+	runCmd.Flags().BoolVar(&cmdParams.kubeReportStatus, "kube-report-status", false, "write statuses to the policy objects, enable it for one replica only (default false)")
+	runCmd.Flags().StringVar(&cmdParams.adminAddr, "admin-addr", "", "set listening address of the admin api (e.g., [ip]:<port>) (default empty - disabled)")
+	runCmd.Flags().StringVar(&cmdParams.adminTokenFile, "admin-token-file", "", "set path of file with bearer token of the admin api")
+	runCmd.Flags().StringVar(&cmdParams.dataSourcesFile, "data-sources", "", "set path of yaml file with data documents fetched periodically instead of the data file")
+	runCmd.Flags().DurationVar(&cmdParams.dataMaxStaleness, "data-max-staleness", 0, "set age of the data after which --data-stale-mode applies (e.g., 10m) (default 0 - no limit)")
+	runCmd.Flags().StringVar(&cmdParams.dataStaleMode, "data-stale-mode", string(agent.StaleModeServe), "set behaviour with stale data: serve, fail-closed (jsonpath allow entries and conditions using data don't match) or unready (/ready responds 503)")
+	runCmd.Flags().StringVar(&cmdParams.gitRepo, "git-repo", "", "set git repository (local path, file:// or ssh url) to track instead of policy/data files")
+	runCmd.Flags().StringVar(&cmdParams.gitBranch, "git-branch", git.DefaultBranch, "set tracked branch of the git repository")
+	runCmd.Flags().StringVar(&cmdParams.gitPolicyPath, "git-policy-path", git.DefaultPolicyPath, "set path of the policy file in the git repository")
+	runCmd.Flags().StringVar(&cmdParams.gitDataPath, "git-data-path", "", "set path of the data file in the git repository (default empty - no data)")
+	runCmd.Flags().StringVar(&cmdParams.gitDir, "git-dir", "", "set directory of the local clone (default empty - temporary directory)")
+	runCmd.Flags().IntVar(&cmdParams.gitPollSeconds, "git-poll-seconds", 60, "set git repository polling period (seconds)")
+	runCmd.Flags().StringVar(&cmdParams.gitWebhookSecret, "git-webhook-secret-file", "", "set path of file with secret verifying signatures of push webhooks, enables the webhook (default empty - no webhook)")
+	runCmd.Flags().StringVar(&cmdParams.controlPlaneAddr, "control-plane-addr", "", "set grpc address of authlink-server streaming policy and data instead of policy/data files")
+	runCmd.Flags().StringVar(&cmdParams.controlPlaneService, "control-plane-service", "", "set name of the service whose snapshots are streamed from the control plane")
+	runCmd.Flags().StringVar(&cmdParams.controlPlaneNodeID, "control-plane-node-id", "", "set id of the agent reported to the control plane (default empty - hostname)")
+	runCmd.Flags().StringVar(&cmdParams.controlPlaneCAFile, "control-plane-ca-file", "", "set path of PEM encoded CA certificate enabling TLS of the control plane connection (default empty - plaintext)")
+	runCmd.Flags().DurationVar(&cmdParams.controlPlaneTimeout, "control-plane-timeout", 30*time.Second, "set timeout of waiting for the first snapshot of the control plane on start")
 	runCmd.SetUsageTemplate(`Usage:
   {{.UseLine}} [policy-file.yaml] [data-file.json (optional)]
 
@@ -98,14 +184,38 @@ Flags:
 	return runCmd
 }
 
-const usageArgs = "arguments must by: [policy-file.yaml] [data-file.json (optional)]"
+const (
+	usageArgs          = "arguments must by: [policy-file.yaml] [data-file.json (optional)]"
+	errBundleWithFiles = "policy/data files must not be set with --bundle-url"
+	errKubeWithFiles   = "policy/data files must not be set with --kube-namespace"
+	errGitWithFiles    = "policy/data files must not be set with --git-repo"
+	errCPWithFiles     = "policy/data files must not be set with --control-plane-addr"
+	errInvalidCAFile   = "control plane CA file doesn't contain PEM encoded certificates"
+)
 
 func prepareConfig(args []string, params runCmdParams) (*agent.Config, error) {
-	if len(args) == 0 || len(args) > 2 {
+	if len(params.bundleURL) > 0 && len(args) > 0 {
+		return nil, errors.New(errBundleWithFiles)
+	}
+	if len(params.kubeNamespace) > 0 && len(args) > 0 {
+		return nil, errors.New(errKubeWithFiles)
+	}
+	if len(params.gitRepo) > 0 && len(args) > 0 {
+		return nil, errors.New(errGitWithFiles)
+	}
+	if len(params.controlPlaneAddr) > 0 && len(args) > 0 {
+		return nil, errors.New(errCPWithFiles)
+	}
+	remoteSource := len(params.bundleURL) > 0 || len(params.kubeNamespace) > 0 || len(params.gitRepo) > 0 ||
+		len(params.controlPlaneAddr) > 0
+	if !remoteSource && (len(args) == 0 || len(args) > 2) {
 		return nil, errors.New(usageArgs)
 	}
 
 	config := agent.DefaultConfig()
+	if remoteSource {
+		config.PolicyFilePath = ""
+	}
 
 	// load files
 	for _, file := range args {
@@ -131,6 +241,106 @@ func prepareConfig(args []string, params runCmdParams) (*agent.Config, error) {
 	config.MonitoringAddr = params.observeAddr
 	config.LogCheckResults = params.logCheckResults
 	config.UpdateFilesSeconds = params.updateFilesSeconds
+	config.WatchFiles = params.watchFiles
+	config.BundleURL = params.bundleURL
+	config.BundleCacheDir = params.bundleCacheDir
+	config.BundlePollSeconds = params.bundlePollSeconds
+	config.KubeNamespace = params.kubeNamespace
+	config.KubeLabelSelector = params.kubeLabelSelector
+	config.KubeAuthPolicies = params.kubeAuthPolicies
+	config.KubeConfigPath = params.kubeConfigPath
+	config.KubeReportStatus = params.kubeReportStatus
+	config.AdminAddr = params.adminAddr
+	config.GitRepo = params.gitRepo
+	config.GitBranch = params.gitBranch
+	config.GitPolicyPath = params.gitPolicyPath
+	config.GitDataPath = params.gitDataPath
+	config.GitDir = params.gitDir
+	config.GitPollSeconds = params.gitPollSeconds
+	config.ControlPlaneAddr = params.controlPlaneAddr
+	config.ControlPlaneService = params.controlPlaneService
+	config.ControlPlaneNodeID = params.controlPlaneNodeID
+	if params.controlPlaneTimeout > 0 {
+		config.ControlPlaneTimeout = params.controlPlaneTimeout
+	}
+	config.DataMaxStaleness = params.dataMaxStaleness
+	config.TracingEndpoint = params.tracingEndpoint
+	config.TracingInsecure = params.tracingInsecure
+	config.TracingSampleRatio = params.tracingSampleRatio
+	config.MetricsClients = params.metricsClients
+	config.MetricsMaxSeries = params.metricsMaxSeries
+	config.MetricsPath = params.metricsPath
+	config.MetricsOTLPEndpoint = params.metricsOTLPEndpoint
+	config.MetricsOTLPInsecure = params.metricsOTLPInsecure
+	config.MetricsOTLPInterval = params.metricsOTLPInterval
+	config.ReloadWebhookURL = params.reloadWebhookURL
+	if len(params.dataStaleMode) > 0 {
+		config.DataStaleMode = agent.StaleMode(params.dataStaleMode)
+	}
+
+	if len(params.adminTokenFile) > 0 {
+		token, err := os.ReadFile(params.adminTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read admin token: %w", err)
+		}
+		config.AdminToken = strings.TrimSpace(string(token))
+	}
+
+	if len(params.gitWebhookSecret) > 0 {
+		secret, err := os.ReadFile(params.gitWebhookSecret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read git webhook secret: %w", err)
+		}
+		config.GitWebhookSecret = strings.TrimSpace(string(secret))
+	}
+
+	if len(params.controlPlaneCAFile) > 0 {
+		caData, err := os.ReadFile(params.controlPlaneCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read control plane CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, errors.New(errInvalidCAFile)
+		}
+		config.ControlPlaneTLS = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	if len(params.dataSourcesFile) > 0 {
+		content, err := os.ReadFile(params.dataSourcesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read data sources: %w", err)
+		}
+		docs, err := fetch.ParseDocuments(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load data sources: %w", err)
+		}
+		config.DataSources = docs
+	}
+
+	if len(params.decisionLogConfig) > 0 {
+		content, err := os.ReadFile(params.decisionLogConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read decision log config: %w", err)
+		}
+		decisionLog, err := decision.ParseConfig(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load decision log config: %w", err)
+		}
+		config.DecisionLog = decisionLog
+	}
+
+	if len(params.bundlePublicKey) > 0 {
+		keyData, err := os.ReadFile(params.bundlePublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle public key: %w", err)
+		}
+		key, err := bundle.ParsePublicKey(keyData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load bundle public key: %w", err)
+		}
+		config.BundlePublicKey = key
+	}
 
 	if !params.tlsDisable {
 		cert, err := tls.LoadX509KeyPair(params.tlsCertPath, params.tlsPrivateKeyPath)
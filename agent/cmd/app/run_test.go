@@ -7,7 +7,13 @@ package app
 import (
 	"log/slog"
 	"testing"
+	"time"
 
+	"github.com/goauthlink/authlink/agent"
+	"github.com/goauthlink/authlink/agent/decision"
+	"github.com/goauthlink/authlink/agent/fetch"
+	"github.com/goauthlink/authlink/agent/monitoring"
+	"github.com/goauthlink/authlink/pkg/metrics"
 	"github.com/goauthlink/authlink/test/testdata"
 	"github.com/goauthlink/authlink/test/util"
 	"github.com/stretchr/testify/assert"
@@ -16,9 +22,13 @@ import (
 
 func createTestCmdParams() runCmdParams {
 	return runCmdParams{
-		logLevel:   "info",
-		httpAddr:   ":8181",
-		tlsDisable: true,
+		logLevel:            "info",
+		httpAddr:            ":8181",
+		tlsDisable:          true,
+		tracingSampleRatio:  1,
+		metricsMaxSeries:    agent.DefaultMetricsMaxSeries,
+		metricsPath:         monitoring.DefaultMetricsPath,
+		metricsOTLPInterval: metrics.DefaultOTLPInterval,
 	}
 }
 
@@ -37,7 +47,16 @@ func createFiles(t *testing.T) (string, func()) {
 		"data.json":   []byte(testCommonData),
 		"data.txt":    []byte("text"),
 		"key.pem":     testdata.TLSServerKey,
-		"cert.pem":    testdata.TLSServerCert,
+		"token":       []byte("secret\n"),
+		"sources.yaml": []byte(`sources:
+  - namespace: hr
+    url: http://localhost/hr.json
+    interval: 30s`),
+		"cert.pem": testdata.TLSServerCert,
+		"ca.pem":   testdata.TLSCACert,
+		"decisions.yaml": []byte(`sinks:
+  - type: file
+    path: /var/log/authlink/decisions.log`),
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -52,6 +71,7 @@ func Test_AgentOtherParams(t *testing.T) {
 
 	params := createTestCmdParams()
 	params.updateFilesSeconds = 60
+	params.watchFiles = true
 	params.observeAddr = ":8181"
 	params.logCheckResults = true
 
@@ -59,6 +79,7 @@ func Test_AgentOtherParams(t *testing.T) {
 	require.NoError(t, err)
 
 	assert.Equal(t, params.updateFilesSeconds, config.UpdateFilesSeconds)
+	assert.True(t, config.WatchFiles)
 	assert.Equal(t, params.observeAddr, config.MonitoringAddr)
 	assert.Equal(t, params.logCheckResults, true)
 }
@@ -132,3 +153,243 @@ func Test_AgentArgsPolicyAndData(t *testing.T) {
 	_, err = prepareConfig([]string{}, createTestCmdParams())
 	require.ErrorContains(t, err, usageArgs)
 }
+
+func Test_AgentBundleParams(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	params := createTestCmdParams()
+	params.bundleURL = "http://localhost/bundle.tar.gz"
+	params.bundlePollSeconds = 30
+
+	_, err := prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.EqualError(t, err, errBundleWithFiles)
+
+	_, err = prepareConfig([]string{}, params)
+	require.ErrorContains(t, err, "bundle public key is required")
+
+	params.bundlePublicKey = rootDir + "/cert.pem"
+	_, err = prepareConfig([]string{}, params)
+	require.ErrorContains(t, err, "failed to load bundle public key")
+}
+
+func Test_AgentKubeParams(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	params := createTestCmdParams()
+	params.kubeNamespace = "authz"
+	params.kubeAuthPolicies = true
+
+	_, err := prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.EqualError(t, err, errKubeWithFiles)
+
+	config, err := prepareConfig([]string{}, params)
+	require.NoError(t, err)
+	assert.Equal(t, "authz", config.KubeNamespace)
+	assert.True(t, config.KubeAuthPolicies)
+	assert.Empty(t, config.PolicyFilePath)
+}
+
+func Test_AgentAdminParams(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	params := createTestCmdParams()
+	params.adminAddr = ":8282"
+
+	_, err := prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.ErrorContains(t, err, "admin token is required")
+
+	params.adminTokenFile = rootDir + "/token"
+	config, err := prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.NoError(t, err)
+	assert.Equal(t, ":8282", config.AdminAddr)
+	assert.Equal(t, "secret", config.AdminToken)
+}
+
+func Test_AgentDataSourcesParams(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	params := createTestCmdParams()
+	params.dataSourcesFile = rootDir + "/sources.yaml"
+
+	config, err := prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.NoError(t, err)
+	assert.Equal(t, []fetch.Document{{Namespace: "hr", URL: "http://localhost/hr.json", Interval: 30 * time.Second}}, config.DataSources)
+
+	_, err = prepareConfig([]string{rootDir + "/policy.yaml", rootDir + "/data.json"}, params)
+	require.ErrorContains(t, err, "data sources and data file must not be set together")
+
+	params.dataSourcesFile = rootDir + "/policy.yaml"
+	_, err = prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.ErrorContains(t, err, "failed to load data sources")
+}
+
+func Test_AgentDecisionLogParams(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	params := createTestCmdParams()
+	params.decisionLogConfig = rootDir + "/decisions.yaml"
+
+	config, err := prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.NoError(t, err)
+	require.NotNil(t, config.DecisionLog)
+	assert.Equal(t, []decision.SinkConfig{{Type: decision.SinkFile, Path: "/var/log/authlink/decisions.log"}}, config.DecisionLog.Sinks)
+
+	params.decisionLogConfig = rootDir + "/sources.yaml"
+	_, err = prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.ErrorContains(t, err, "failed to load decision log config")
+}
+
+func Test_AgentTracingParams(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	params := createTestCmdParams()
+	params.tracingEndpoint = "otel-collector:4317"
+	params.tracingInsecure = true
+	params.tracingSampleRatio = 0.25
+
+	config, err := prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.NoError(t, err)
+	assert.Equal(t, "otel-collector:4317", config.TracingEndpoint)
+	assert.True(t, config.TracingInsecure)
+	assert.Equal(t, 0.25, config.TracingSampleRatio)
+
+	params.tracingSampleRatio = 2
+	_, err = prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.ErrorContains(t, err, "tracing sample ratio must be between 0 and 1")
+}
+
+func Test_AgentMetricsParams(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	params := createTestCmdParams()
+	params.metricsClients = []string{"orders", "payments"}
+	params.metricsMaxSeries = 100
+
+	config, err := prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orders", "payments"}, config.MetricsClients)
+	assert.Equal(t, 100, config.MetricsMaxSeries)
+
+	params.metricsMaxSeries = 0
+	_, err = prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.ErrorContains(t, err, "metrics max series must be greater than 0")
+}
+
+func Test_AgentMetricsExportParams(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	params := createTestCmdParams()
+	params.metricsPath = "/stats/prometheus"
+	params.metricsOTLPEndpoint = "collector:4317"
+	params.metricsOTLPInsecure = true
+	params.metricsOTLPInterval = 10 * time.Second
+
+	config, err := prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.NoError(t, err)
+	assert.Equal(t, "/stats/prometheus", config.MetricsPath)
+	assert.Equal(t, "collector:4317", config.MetricsOTLPEndpoint)
+	assert.True(t, config.MetricsOTLPInsecure)
+	assert.Equal(t, 10*time.Second, config.MetricsOTLPInterval)
+
+	params.metricsPath = "metrics"
+	_, err = prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.ErrorContains(t, err, "metrics path must start with /")
+
+	params.metricsPath = "/metrics"
+	params.metricsOTLPInterval = 0
+	_, err = prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.ErrorContains(t, err, "metrics otlp push interval must be greater than 0")
+}
+
+func Test_AgentReloadWebhookParams(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	params := createTestCmdParams()
+	params.reloadWebhookURL = "https://hooks.internal/authlink"
+
+	config, err := prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.NoError(t, err)
+	assert.Equal(t, "https://hooks.internal/authlink", config.ReloadWebhookURL)
+
+	params.reloadWebhookURL = "hooks.internal"
+	_, err = prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.ErrorContains(t, err, "reload webhook url must be")
+}
+
+func Test_AgentDataStalenessParams(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	params := createTestCmdParams()
+	params.dataMaxStaleness = 10 * time.Minute
+	params.dataStaleMode = "fail-closed"
+
+	config, err := prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, config.DataMaxStaleness)
+	assert.Equal(t, agent.StaleModeFailClosed, config.DataStaleMode)
+
+	params.dataStaleMode = "ignore"
+	_, err = prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.ErrorContains(t, err, "data stale mode must be")
+}
+
+func Test_AgentGitParams(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	params := createTestCmdParams()
+	params.gitRepo = "file:///srv/policies.git"
+	params.gitBranch = "prod"
+	params.gitPolicyPath = "authz/policy.yaml"
+	params.gitPollSeconds = 30
+	params.gitWebhookSecret = rootDir + "/token"
+
+	_, err := prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.ErrorContains(t, err, errGitWithFiles)
+
+	config, err := prepareConfig([]string{}, params)
+	require.NoError(t, err)
+	assert.Equal(t, "file:///srv/policies.git", config.GitRepo)
+	assert.Equal(t, "prod", config.GitBranch)
+	assert.Equal(t, "authz/policy.yaml", config.GitPolicyPath)
+	assert.Equal(t, 30, config.GitPollSeconds)
+	assert.Equal(t, "secret", config.GitWebhookSecret)
+	assert.Empty(t, config.PolicyFilePath)
+}
+
+func Test_AgentControlPlaneParams(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	params := createTestCmdParams()
+	params.controlPlaneAddr = "authlink-server:8383"
+	params.controlPlaneService = "orders"
+	params.controlPlaneNodeID = "agent-1"
+	params.controlPlaneCAFile = rootDir + "/ca.pem"
+
+	_, err := prepareConfig([]string{rootDir + "/policy.yaml"}, params)
+	require.ErrorContains(t, err, errCPWithFiles)
+
+	config, err := prepareConfig([]string{}, params)
+	require.NoError(t, err)
+	assert.Equal(t, "authlink-server:8383", config.ControlPlaneAddr)
+	assert.Equal(t, "orders", config.ControlPlaneService)
+	assert.Equal(t, "agent-1", config.ControlPlaneNodeID)
+	assert.Equal(t, 30*time.Second, config.ControlPlaneTimeout)
+	assert.NotNil(t, config.ControlPlaneTLS)
+	assert.Empty(t, config.PolicyFilePath)
+
+	params.controlPlaneCAFile = rootDir + "/data.txt"
+	_, err = prepareConfig([]string{}, params)
+	require.ErrorContains(t, err, errInvalidCAFile)
+}
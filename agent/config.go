@@ -5,9 +5,20 @@
 package agent
 
 import (
+	"crypto/ed25519"
 	"crypto/tls"
 	"errors"
 	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/goauthlink/authlink/agent/decision"
+	"github.com/goauthlink/authlink/agent/fetch"
+	"github.com/goauthlink/authlink/agent/git"
+	"github.com/goauthlink/authlink/agent/kube"
+	"github.com/goauthlink/authlink/agent/monitoring"
+	"github.com/goauthlink/authlink/pkg/metrics"
 )
 
 type Config struct {
@@ -18,18 +29,101 @@ type Config struct {
 	PolicyFilePath     string
 	DataFilePath       string
 	UpdateFilesSeconds int
+	WatchFiles         bool
 	TLSCert            *tls.Certificate
+	BundleURL          string
+	BundlePublicKey    ed25519.PublicKey
+	BundleCacheDir     string
+	BundlePollSeconds  int
+	KubeNamespace      string
+	KubeLabelSelector  string
+	KubeAuthPolicies   bool
+	KubeConfigPath     string
+	KubeReportStatus   bool
+	AdminAddr          string
+	AdminToken         string
+	// DataSources are fetched periodically and mounted under their namespaces instead of the data file
+	DataSources []fetch.Document
+	// DataMaxStaleness is the age of the data after which DataStaleMode applies, zero disables the limit
+	DataMaxStaleness time.Duration
+	DataStaleMode    StaleMode
+	GitRepo          string
+	GitBranch        string
+	GitPolicyPath    string
+	GitDataPath      string
+	GitDir           string
+	GitPollSeconds   int
+	// GitWebhookSecret verifies signatures of push webhooks, the webhook isn't served if it's empty
+	GitWebhookSecret string
+	// ControlPlaneAddr is the grpc address of authlink-server streaming snapshots of ControlPlaneService
+	ControlPlaneAddr    string
+	ControlPlaneService string
+	// ControlPlaneNodeID identifies the agent on the control plane, the hostname is used if it's empty
+	ControlPlaneNodeID string
+	// ControlPlaneTLS enables TLS of the control plane connection, plaintext is used if it's nil
+	ControlPlaneTLS *tls.Config
+	// ControlPlaneTimeout limits waiting for the first snapshot on start
+	ControlPlaneTimeout time.Duration
+	// DecisionLog defines sinks of decision events, LogCheckResults adds the stdout sink to them
+	DecisionLog *decision.Config
+	// TracingEndpoint is the host:port of the OTLP gRPC collector receiving spans of checks,
+	// checks aren't traced if it's empty
+	TracingEndpoint string
+	// TracingInsecure disables TLS of the collector connection
+	TracingInsecure bool
+	// TracingSampleRatio is the share of traced checks without a remote parent span,
+	// checks with a remote parent follow its sampling decision
+	TracingSampleRatio float64
+	// MetricsClients are client names labelled in decision metrics, other clients are counted as other
+	MetricsClients []string
+	// MetricsMaxSeries caps distinct label sets of decision metrics, labels of further ones are replaced with other
+	MetricsMaxSeries int
+	// MetricsPath is the path of the prometheus scrape endpoint of the monitoring server
+	MetricsPath string
+	// MetricsOTLPEndpoint is the host:port of the OTLP gRPC collector metrics are pushed to,
+	// metrics aren't pushed if it's empty
+	MetricsOTLPEndpoint string
+	// MetricsOTLPInsecure disables TLS of the collector connection
+	MetricsOTLPInsecure bool
+	// MetricsOTLPInterval is the period of pushes
+	MetricsOTLPInterval time.Duration
+	// ReloadWebhookURL receives changed and failed policy and data updates as json, nothing is sent if it's empty
+	ReloadWebhookURL string
 }
 
+// StaleMode defines behaviour of the agent when the data is older than DataMaxStaleness
+type StaleMode string
+
+const (
+	// StaleModeServe keeps serving decisions with the stale data, they are marked in the decision log
+	StaleModeServe StaleMode = "serve"
+	// StaleModeFailClosed makes jsonpath allow entries never match and conditions referencing data false
+	StaleModeFailClosed StaleMode = "fail-closed"
+	// StaleModeUnready makes the readiness endpoint respond 503
+	StaleModeUnready StaleMode = "unready"
+)
+
 func DefaultConfig() Config {
 	return Config{
-		HttpAddr:           ":8181",
-		MonitoringAddr:     ":9191",
-		LogLevel:           slog.LevelInfo,
-		LogCheckResults:    false,
-		UpdateFilesSeconds: 0,
-		PolicyFilePath:     "policy.yaml",
-		DataFilePath:       "",
+		HttpAddr:            ":8181",
+		MonitoringAddr:      ":9191",
+		LogLevel:            slog.LevelInfo,
+		LogCheckResults:     false,
+		UpdateFilesSeconds:  0,
+		WatchFiles:          false,
+		PolicyFilePath:      "policy.yaml",
+		DataFilePath:        "",
+		BundlePollSeconds:   60,
+		KubeLabelSelector:   kube.DefaultLabelSelector,
+		DataStaleMode:       StaleModeServe,
+		GitBranch:           git.DefaultBranch,
+		GitPolicyPath:       git.DefaultPolicyPath,
+		GitPollSeconds:      60,
+		ControlPlaneTimeout: 30 * time.Second,
+		TracingSampleRatio:  1,
+		MetricsMaxSeries:    DefaultMetricsMaxSeries,
+		MetricsPath:         monitoring.DefaultMetricsPath,
+		MetricsOTLPInterval: metrics.DefaultOTLPInterval,
 	}
 }
 
@@ -37,6 +131,24 @@ const (
 	errUpdatePolicyFileSeconds     = "update policy file period must not be less than 0 seconds"
 	errTLSPrivateKeyPathIsRequired = "TLS private key is required when TLS is enabled"
 	errTLSCertPathIsRequired       = "TLS certificate is required when TLS is enabled"
+	errBundlePublicKeyIsRequired   = "bundle public key is required when bundle url is set"
+	errBundlePollSeconds           = "bundle polling period must be greater than 0 seconds"
+	errBundleWithKube              = "bundle url and kubernetes namespace must not be set together"
+	errAdminTokenIsRequired        = "admin token is required when admin api is enabled"
+	errDataSourcesWithDataFile     = "data sources and data file must not be set together"
+	errDataSourcesWithRemotePolicy = "data sources must not be used with bundle url, kubernetes namespace, git repository or control plane"
+	errDataMaxStaleness            = "data max staleness must not be negative"
+	errInvalidStaleMode            = "data stale mode must be serve, fail-closed or unready"
+	errGitWithRemoteSource         = "git repository must not be set with bundle url or kubernetes namespace"
+	errGitPollSeconds              = "git polling period must be greater than 0 seconds"
+	errControlPlaneWithRemote      = "control plane address must not be set with bundle url, kubernetes namespace or git repository"
+	errControlPlaneService         = "control plane service is required when control plane address is set"
+	errControlPlaneTimeout         = "control plane timeout must be greater than 0"
+	errTracingSampleRatio          = "tracing sample ratio must be between 0 and 1"
+	errMetricsMaxSeries            = "metrics max series must be greater than 0"
+	errMetricsPath                 = "metrics path must start with /"
+	errMetricsOTLPInterval         = "metrics otlp push interval must be greater than 0"
+	errReloadWebhookURL            = "reload webhook url must be an absolute http or https url"
 )
 
 func (c *Config) Validate() error {
@@ -44,5 +156,94 @@ func (c *Config) Validate() error {
 		return errors.New(errUpdatePolicyFileSeconds)
 	}
 
+	if c.DataMaxStaleness < 0 {
+		return errors.New(errDataMaxStaleness)
+	}
+
+	switch c.DataStaleMode {
+	case "", StaleModeServe, StaleModeFailClosed, StaleModeUnready:
+	default:
+		return errors.New(errInvalidStaleMode)
+	}
+
+	if len(c.AdminAddr) > 0 && len(c.AdminToken) == 0 {
+		return errors.New(errAdminTokenIsRequired)
+	}
+
+	if len(c.BundleURL) > 0 && len(c.KubeNamespace) > 0 {
+		return errors.New(errBundleWithKube)
+	}
+
+	if len(c.DataSources) > 0 {
+		if len(c.DataFilePath) > 0 {
+			return errors.New(errDataSourcesWithDataFile)
+		}
+		if len(c.BundleURL) > 0 || len(c.KubeNamespace) > 0 || len(c.GitRepo) > 0 || len(c.ControlPlaneAddr) > 0 {
+			return errors.New(errDataSourcesWithRemotePolicy)
+		}
+		if err := fetch.ValidateDocuments(c.DataSources); err != nil {
+			return err
+		}
+	}
+
+	if len(c.GitRepo) > 0 {
+		if len(c.BundleURL) > 0 || len(c.KubeNamespace) > 0 {
+			return errors.New(errGitWithRemoteSource)
+		}
+		if c.GitPollSeconds <= 0 {
+			return errors.New(errGitPollSeconds)
+		}
+	}
+
+	if len(c.ControlPlaneAddr) > 0 {
+		if len(c.BundleURL) > 0 || len(c.KubeNamespace) > 0 || len(c.GitRepo) > 0 {
+			return errors.New(errControlPlaneWithRemote)
+		}
+		if len(c.ControlPlaneService) == 0 {
+			return errors.New(errControlPlaneService)
+		}
+		if c.ControlPlaneTimeout <= 0 {
+			return errors.New(errControlPlaneTimeout)
+		}
+	}
+
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		return errors.New(errTracingSampleRatio)
+	}
+
+	if c.MetricsMaxSeries <= 0 {
+		return errors.New(errMetricsMaxSeries)
+	}
+
+	if !strings.HasPrefix(c.MetricsPath, "/") {
+		return errors.New(errMetricsPath)
+	}
+
+	if c.MetricsOTLPInterval <= 0 {
+		return errors.New(errMetricsOTLPInterval)
+	}
+
+	if len(c.ReloadWebhookURL) > 0 {
+		u, err := url.Parse(c.ReloadWebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			return errors.New(errReloadWebhookURL)
+		}
+	}
+
+	if c.DecisionLog != nil {
+		if err := c.DecisionLog.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if len(c.BundleURL) > 0 {
+		if len(c.BundlePublicKey) == 0 {
+			return errors.New(errBundlePublicKeyIsRequired)
+		}
+		if c.BundlePollSeconds <= 0 {
+			return errors.New(errBundlePollSeconds)
+		}
+	}
+
 	return nil
 }
@@ -6,7 +6,10 @@ package agent
 
 import (
 	"testing"
+	"time"
 
+	"github.com/goauthlink/authlink/agent/decision"
+	"github.com/goauthlink/authlink/agent/fetch"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -18,3 +21,109 @@ func TestArguments(t *testing.T) {
 	err := cfg.Validate()
 	assert.ErrorContains(t, err, errUpdatePolicyFileSeconds)
 }
+
+func TestBundleArguments(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.BundleURL = "http://localhost/bundle.tar.gz"
+
+	assert.ErrorContains(t, cfg.Validate(), errBundlePublicKeyIsRequired)
+
+	cfg.BundlePublicKey = make([]byte, 32)
+	cfg.BundlePollSeconds = 0
+
+	assert.ErrorContains(t, cfg.Validate(), errBundlePollSeconds)
+}
+
+func TestKubeArguments(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.BundleURL = "http://localhost/bundle.tar.gz"
+	cfg.BundlePublicKey = make([]byte, 32)
+	cfg.KubeNamespace = "authz"
+
+	assert.ErrorContains(t, cfg.Validate(), errBundleWithKube)
+}
+
+func TestAdminArguments(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AdminAddr = ":8282"
+
+	assert.ErrorContains(t, cfg.Validate(), errAdminTokenIsRequired)
+
+	cfg.AdminToken = "token"
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestDataSourcesArguments(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DataSources = []fetch.Document{{Namespace: "hr", URL: "http://localhost/hr.json"}}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.DataFilePath = "data.json"
+	assert.ErrorContains(t, cfg.Validate(), errDataSourcesWithDataFile)
+
+	cfg.DataFilePath = ""
+	cfg.KubeNamespace = "authz"
+	assert.ErrorContains(t, cfg.Validate(), errDataSourcesWithRemotePolicy)
+}
+
+func TestDataStalenessArguments(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DataMaxStaleness = -time.Second
+	assert.ErrorContains(t, cfg.Validate(), errDataMaxStaleness)
+
+	cfg.DataMaxStaleness = time.Minute
+	cfg.DataStaleMode = "unknown"
+	assert.ErrorContains(t, cfg.Validate(), errInvalidStaleMode)
+
+	cfg.DataStaleMode = StaleModeFailClosed
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestGitArguments(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.GitRepo = "file:///srv/policies.git"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.GitPollSeconds = 0
+	assert.ErrorContains(t, cfg.Validate(), errGitPollSeconds)
+
+	cfg.KubeNamespace = "authz"
+	assert.ErrorContains(t, cfg.Validate(), errGitWithRemoteSource)
+}
+
+func TestControlPlaneArguments(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ControlPlaneAddr = "authlink-server:8383"
+	assert.ErrorContains(t, cfg.Validate(), errControlPlaneService)
+
+	cfg.ControlPlaneService = "orders"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.ControlPlaneTimeout = 0
+	assert.ErrorContains(t, cfg.Validate(), errControlPlaneTimeout)
+
+	cfg.ControlPlaneTimeout = time.Second
+	cfg.GitRepo = "file:///srv/policies.git"
+	assert.ErrorContains(t, cfg.Validate(), errControlPlaneWithRemote)
+}
+
+func TestDecisionLogArguments(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DecisionLog = &decision.Config{}
+	assert.Error(t, cfg.Validate())
+
+	cfg.DecisionLog.Sinks = []decision.SinkConfig{{Type: decision.SinkFile, Path: "decisions.log"}}
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestReloadWebhookArguments(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ReloadWebhookURL = "https://hooks.internal/authlink"
+
+	assert.NoError(t, cfg.Validate())
+
+	for _, invalid := range []string{"hooks.internal/authlink", "ftp://hooks.internal", "http://"} {
+		cfg.ReloadWebhookURL = invalid
+		assert.ErrorContains(t, cfg.Validate(), errReloadWebhookURL, invalid)
+	}
+}
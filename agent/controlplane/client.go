@@ -0,0 +1,277 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/goauthlink/authlink/pkg/logging"
+	"github.com/goauthlink/authlink/pkg/snapshot"
+	rpc_status "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const DefaultRetryInterval = 5 * time.Second
+
+const (
+	errAddrIsRequired     = "control plane address is required"
+	errServiceIsRequired  = "service is required"
+	errUnexpectedResource = "unexpected response with %d resources of type %s"
+	errUnexpectedService  = "snapshot of service %s is received instead of %s"
+	errNotApplied         = "no snapshot was applied: %w"
+)
+
+// ApplyFunc activates policy and data of the snapshot revision, data is nil if the service has no data
+type ApplyFunc func(revision string, policy, data []byte) error
+
+// Client receives snapshots of the service from the control plane and applies them.
+// Applied versions are acknowledged, rejected ones are reported with the error
+// so the control plane knows the state of every agent.
+type Client struct {
+	conn          *grpc.ClientConn
+	addr          string
+	service       string
+	nodeID        string
+	dialOpts      []grpc.DialOption
+	retryInterval time.Duration
+	logger        *slog.Logger
+
+	mux      sync.Mutex
+	version  string
+	revision string
+	lastErr  error
+	applied  chan struct{}
+}
+
+type ClientOpt func(*Client)
+
+func WithLogger(logger *slog.Logger) ClientOpt {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithNodeID sets the id of the agent reported to the control plane, the hostname by default
+func WithNodeID(id string) ClientOpt {
+	return func(c *Client) {
+		c.nodeID = id
+	}
+}
+
+// WithDialOptions sets options of the grpc connection, plaintext is used by default
+func WithDialOptions(opts ...grpc.DialOption) ClientOpt {
+	return func(c *Client) {
+		c.dialOpts = opts
+	}
+}
+
+// WithRetryInterval sets the delay of reconnecting after the stream failure, DefaultRetryInterval by default
+func WithRetryInterval(interval time.Duration) ClientOpt {
+	return func(c *Client) {
+		c.retryInterval = interval
+	}
+}
+
+func NewClient(addr, service string, opts ...ClientOpt) (*Client, error) {
+	if len(addr) == 0 {
+		return nil, errors.New(errAddrIsRequired)
+	}
+	if len(service) == 0 {
+		return nil, errors.New(errServiceIsRequired)
+	}
+
+	c := &Client{
+		addr:          addr,
+		service:       service,
+		retryInterval: DefaultRetryInterval,
+		dialOpts:      []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+		applied:       make(chan struct{}),
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	if c.logger == nil {
+		c.logger = logging.NewNullLogger()
+	}
+
+	if len(c.nodeID) == 0 {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("node id: %w", err)
+		}
+		c.nodeID = hostname
+	}
+
+	conn, err := grpc.NewClient(addr, c.dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("control plane connection: %w", err)
+	}
+	c.conn = conn
+
+	return c, nil
+}
+
+// Close closes the connection to the control plane
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Version returns the applied version of the service snapshot, empty if nothing was applied
+func (c *Client) Version() string {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.version
+}
+
+// Revision returns the applied revision of the service snapshot, empty if nothing was applied
+func (c *Client) Revision() string {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.revision
+}
+
+// WaitApplied waits until a snapshot is applied, the error of the last attempt is returned
+// if the context is done before
+func (c *Client) WaitApplied(ctx context.Context) error {
+	select {
+	case <-c.applied:
+		return nil
+	case <-ctx.Done():
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.lastErr != nil {
+		return fmt.Errorf(errNotApplied, c.lastErr)
+	}
+
+	return fmt.Errorf(errNotApplied, ctx.Err())
+}
+
+// Run streams snapshots until the context is done, the stream is reestablished after failures
+func (c *Client) Run(ctx context.Context, apply ApplyFunc) {
+	for {
+		err := c.stream(ctx, apply)
+		if ctx.Err() != nil {
+			return
+		}
+
+		c.setErr(err)
+		c.logger.Error(fmt.Sprintf("control plane stream failed, retrying in %s: %s", c.retryInterval, err.Error()))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.retryInterval):
+		}
+	}
+}
+
+func (c *Client) stream(ctx context.Context, apply ApplyFunc) error {
+	stream, err := discoveryv3.NewAggregatedDiscoveryServiceClient(c.conn).StreamAggregatedResources(ctx)
+	if err != nil {
+		return err
+	}
+
+	node := &corev3.Node{Id: c.nodeID, Cluster: c.service}
+	if err := stream.Send(&discoveryv3.DiscoveryRequest{
+		VersionInfo: c.Version(),
+		Node:        node,
+		TypeUrl:     snapshot.TypeURL,
+	}); err != nil {
+		return err
+	}
+
+	c.logger.Info(fmt.Sprintf("control plane %s stream of service %s established", c.addr, c.service))
+
+	for {
+		rsp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		rq := &discoveryv3.DiscoveryRequest{
+			Node:          node,
+			TypeUrl:       snapshot.TypeURL,
+			ResponseNonce: rsp.GetNonce(),
+		}
+
+		s, err := c.decode(rsp)
+		if err == nil {
+			err = apply(s.Revision, s.Policy, s.Data)
+		}
+
+		if err != nil {
+			c.setErr(err)
+			c.logger.Error(fmt.Sprintf("control plane snapshot version %s rejected: %s", rsp.GetVersionInfo(), err.Error()))
+
+			rq.VersionInfo = c.Version()
+			rq.ErrorDetail = &rpc_status.Status{
+				Code:    int32(codes.InvalidArgument),
+				Message: err.Error(),
+			}
+		} else {
+			c.setApplied(s)
+			c.logger.Info(fmt.Sprintf("control plane snapshot version %s (revision %s) applied", s.Version, s.Revision))
+
+			rq.VersionInfo = s.Version
+		}
+
+		if err := stream.Send(rq); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) decode(rsp *discoveryv3.DiscoveryResponse) (snapshot.Snapshot, error) {
+	if rsp.GetTypeUrl() != snapshot.TypeURL || len(rsp.GetResources()) != 1 {
+		return snapshot.Snapshot{}, fmt.Errorf(errUnexpectedResource, len(rsp.GetResources()), rsp.GetTypeUrl())
+	}
+
+	s, err := snapshot.Decode(rsp.GetResources()[0])
+	if err != nil {
+		return snapshot.Snapshot{}, err
+	}
+
+	if s.Service != c.service {
+		return snapshot.Snapshot{}, fmt.Errorf(errUnexpectedService, s.Service, c.service)
+	}
+
+	return s, nil
+}
+
+func (c *Client) setApplied(s snapshot.Snapshot) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if len(c.version) == 0 {
+		close(c.applied)
+	}
+	c.version = s.Version
+	c.revision = s.Revision
+	c.lastErr = nil
+}
+
+func (c *Client) setErr(err error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.lastErr = err
+}
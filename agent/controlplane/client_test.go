@@ -0,0 +1,153 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/goauthlink/authlink/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+const testPolicy = `cn:
+  - header: "x-source"
+policies:
+  - uri: ["/endpoint"]
+    allow: ["client"]`
+
+func startControlPlane(t *testing.T, cp *server.ControlPlane) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	discoveryv3.RegisterAggregatedDiscoveryServiceServer(srv, cp)
+	go srv.Serve(listener) //nolint: errcheck
+	t.Cleanup(srv.Stop)
+
+	return listener.Addr().String()
+}
+
+type testApplier struct {
+	mux       sync.Mutex
+	revisions []string
+	data      []byte
+	reject    bool
+}
+
+func (a *testApplier) apply(revision string, _, data []byte) error {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	if a.reject {
+		return errors.New("invalid data")
+	}
+	a.revisions = append(a.revisions, revision)
+	a.data = data
+
+	return nil
+}
+
+func (a *testApplier) setReject(reject bool) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	a.reject = reject
+}
+
+func Test_ClientRun(t *testing.T) {
+	cp := server.NewControlPlane()
+	s1, err := cp.SetPolicy("orders", []byte(testPolicy), nil)
+	require.NoError(t, err)
+
+	client, err := NewClient(startControlPlane(t, cp), "orders", WithNodeID("agent-1"))
+	require.NoError(t, err)
+	defer client.Close()
+
+	applier := &testApplier{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		client.Run(ctx, applier.apply)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	require.NoError(t, client.WaitApplied(waitCtx))
+	assert.Equal(t, s1.Version, client.Version())
+	assert.Equal(t, s1.Revision, client.Revision())
+
+	// the control plane knows the applied version
+	assert.Eventually(t, func() bool {
+		agents := cp.Agents()
+		return len(agents) == 1 && agents[0].ID == "agent-1" && agents[0].Revision == s1.Revision
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// rejected version is reported
+	applier.setReject(true)
+	s2, err := cp.SetPolicy("orders", []byte(testPolicy), []byte(`{"users":["user1"]}`))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		agents := cp.Agents()
+		return len(agents) == 1 && agents[0].ErrorVersion == s2.Version
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "invalid data", cp.Agents()[0].Error)
+	assert.Equal(t, s1.Version, client.Version())
+
+	// the next version is applied
+	applier.setReject(false)
+	s3, err := cp.SetPolicy("orders", []byte(testPolicy), []byte(`{"users":["user2"]}`))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return client.Version() == s3.Version }, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		agents := cp.Agents()
+		return len(agents) == 1 && agents[0].Version == s3.Version && len(agents[0].Error) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	applier.mux.Lock()
+	defer applier.mux.Unlock()
+	assert.Equal(t, []string{s1.Revision, s3.Revision}, applier.revisions)
+	assert.Equal(t, []byte(`{"users":["user2"]}`), applier.data)
+}
+
+func Test_ClientWaitApplied(t *testing.T) {
+	cp := server.NewControlPlane()
+	_, err := cp.SetPolicy("orders", []byte(testPolicy), nil)
+	require.NoError(t, err)
+
+	client, err := NewClient(startControlPlane(t, cp), "orders", WithRetryInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.Run(ctx, func(string, []byte, []byte) error {
+		return errors.New("invalid policy")
+	})
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer waitCancel()
+	require.ErrorContains(t, client.WaitApplied(waitCtx), "invalid policy")
+
+	_, err = NewClient("", "orders")
+	require.EqualError(t, err, errAddrIsRequired)
+
+	_, err = NewClient("127.0.0.1:1", "")
+	require.EqualError(t, err, errServiceIsRequired)
+}
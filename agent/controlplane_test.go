@@ -0,0 +1,71 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/goauthlink/authlink/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func Test_InitControlPlane(t *testing.T) {
+	cp := server.NewControlPlane()
+	s1, err := cp.SetPolicy("orders", []byte(testPolicy), []byte(testData))
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	discoveryv3.RegisterAggregatedDiscoveryServiceServer(srv, cp)
+	go srv.Serve(listener) //nolint: errcheck
+	defer srv.Stop()
+
+	config := DefaultConfig()
+	config.LogLevel = slog.LevelError
+	config.HttpAddr = "127.0.0.1:0"
+	config.MonitoringAddr = "127.0.0.1:0"
+	config.PolicyFilePath = ""
+	config.ControlPlaneAddr = listener.Addr().String()
+	config.ControlPlaneService = "orders"
+	config.ControlPlaneNodeID = "agent-1"
+
+	agent, err := Init(config)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(testPolicy), agent.policy.Policy())
+	assert.Equal(t, s1.Revision, agent.Revision())
+
+	stop := make(chan struct{}, 1)
+	go func() {
+		agent.Run(stop) //nolint: errcheck
+	}()
+	defer func() {
+		stop <- struct{}{}
+		agent.WaitUntilCompletion()
+	}()
+
+	// a new snapshot is applied and acknowledged
+	s2, err := cp.SetPolicy("orders", []byte(testPolicy), []byte(`{"users":["user3"]}`))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return agent.Revision() == s2.Revision }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, map[string]interface{}{"users": []interface{}{"user3"}}, agent.policy.Data())
+	assert.Eventually(t, func() bool {
+		agents := cp.Agents()
+		return len(agents) == 1 && agents[0].ID == "agent-1" && agents[0].Version == s2.Version
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the service without a snapshot
+	config.ControlPlaneService = "payments"
+	config.ControlPlaneTimeout = 100 * time.Millisecond
+	_, err = Init(config)
+	require.ErrorContains(t, err, "no snapshot was applied")
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package decision
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/goauthlink/authlink/agent/audit"
+)
+
+// AuditSink appends events to the tamper-evident audit log: every record contains the hash
+// of the previous one and the log is signed periodically, see audit.Verify
+type AuditSink struct {
+	log *audit.Log
+}
+
+// NewAuditSink opens the audit log signed with the key, zero signInterval means audit.DefaultSignInterval
+func NewAuditSink(path string, key ed25519.PrivateKey, signInterval time.Duration, logger *slog.Logger) (*AuditSink, error) {
+	opts := []audit.LogOpt{audit.WithLogger(logger)}
+	if signInterval > 0 {
+		opts = append(opts, audit.WithSignInterval(signInterval))
+	}
+
+	log, err := audit.Open(path, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditSink{log: log}, nil
+}
+
+func newAuditSink(config SinkConfig, logger *slog.Logger) (*AuditSink, error) {
+	keyData, err := os.ReadFile(config.SigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("read signing key: %w", err)
+	}
+
+	key, err := audit.ParsePrivateKey(keyData)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAuditSink(config.Path, key, config.SignInterval, logger)
+}
+
+func (s *AuditSink) Write(events []Event) error {
+	entries := make([]json.RawMessage, 0, len(events))
+	for _, event := range events {
+		entry, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+
+	return s.log.Append(entries...)
+}
+
+// Close signs the last events and closes the log
+func (s *AuditSink) Close() error {
+	return s.log.Close()
+}
@@ -0,0 +1,243 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package decision
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/goauthlink/authlink/sdk/policy"
+	"gopkg.in/yaml.v3"
+)
+
+// SinkType is the destination of decision events
+type SinkType string
+
+const (
+	// SinkStdout writes events to stdout as json lines
+	SinkStdout SinkType = "stdout"
+	// SinkFile writes events as json lines to a file rotated by size
+	SinkFile SinkType = "file"
+	// SinkHTTP posts batches of events as a json array
+	SinkHTTP SinkType = "http"
+	// SinkSyslog sends every event as a json message to syslog
+	SinkSyslog SinkType = "syslog"
+	// SinkAudit appends events to the hash chained audit log signed periodically
+	SinkAudit SinkType = "audit"
+)
+
+const (
+	DefaultBufferSize    = 10000
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = time.Second
+	DefaultHTTPTimeout   = 10 * time.Second
+	DefaultMaxSizeMB     = 100
+	DefaultMaxBackups    = 5
+	DefaultSyslogTag     = "authlink"
+)
+
+// DefaultRedactHeaders are headers carrying credentials, they are redacted unless redact headers are set
+var DefaultRedactHeaders = []string{"authorization", "proxy-authorization", "cookie", "x-api-key"}
+
+const (
+	errNoSinks           = "decision log config doesn't contain sinks"
+	errUnknownSinkType   = "unknown type %q of sink %s, it must be stdout, file, http, syslog or audit"
+	errDuplicateSinkName = "sink name %s is used by several sinks"
+	errInvalidSampleRate = "sample rate of sink %s must be between 0 and 1"
+	errNegativeSinkParam = "buffer size, batch size, flush interval, timeout and sign interval of sink %s must not be negative"
+	errFilePathRequired  = "path is required for file sink %s"
+	errAuditKeyRequired  = "path and signing key are required for audit sink %s"
+	errAuditSampleRate   = "audit sink %s writes every decision, sample rate isn't supported"
+	errURLRequired       = "url is required for http sink %s"
+	errNegativeRotation  = "maxSizeMB and maxBackups of sink %s must not be negative"
+)
+
+// Config defines sinks of decision events and redaction of sensitive values
+type Config struct {
+	// Redact is applied to events before they are passed to sinks
+	Redact *RedactConfig `yaml:"redact"`
+	// Audit selects decisions of policies without the audit field: always, deny-only, sample:N or never.
+	// If it isn't set, decisions are selected by sample rates of the sinks.
+	Audit string       `yaml:"audit"`
+	Sinks []SinkConfig `yaml:"sinks"`
+}
+
+// RedactConfig lists names of headers and jwt claims whose values are replaced with Redacted
+type RedactConfig struct {
+	// Headers are DefaultRedactHeaders if they aren't set, an empty list disables header redaction
+	Headers []string `yaml:"headers"`
+	Claims  []string `yaml:"claims"`
+}
+
+type SinkConfig struct {
+	// Name identifies the sink in metrics, the type by default
+	Name string   `yaml:"name"`
+	Type SinkType `yaml:"type"`
+	// SampleRate is the share of events written by the sink from 0 to 1, 1 by default
+	SampleRate *float64 `yaml:"sampleRate"`
+	// BufferSize is the number of events waiting for the sink, newer events are dropped
+	// when the buffer is full, except for the audit sink which blocks checks. DefaultBufferSize by default.
+	BufferSize int `yaml:"bufferSize"`
+	// BatchSize is the max number of events written at once, DefaultBatchSize by default
+	BatchSize int `yaml:"batchSize"`
+	// FlushInterval is the max delay of buffered events, DefaultFlushInterval by default
+	FlushInterval time.Duration `yaml:"flushInterval"`
+
+	// Path of the file sink or the audit sink
+	Path string `yaml:"path"`
+	// MaxSizeMB is the size of the file after which it's rotated, DefaultMaxSizeMB by default
+	MaxSizeMB int `yaml:"maxSizeMB"`
+	// MaxBackups is the number of kept rotated files, DefaultMaxBackups by default
+	MaxBackups int `yaml:"maxBackups"`
+
+	// URL of the http sink
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	// Timeout of a batch upload, DefaultHTTPTimeout by default
+	Timeout time.Duration `yaml:"timeout"`
+
+	// Network and Address of the syslog server, the local syslog is used if they are empty
+	Network string `yaml:"network"`
+	Address string `yaml:"address"`
+	// Tag of syslog messages, DefaultSyslogTag by default
+	Tag string `yaml:"tag"`
+
+	// SigningKey is the path of PEM encoded ed25519 private key signing the audit log
+	SigningKey string `yaml:"signingKey"`
+	// SignInterval is the period of signing new records of the audit log, audit.DefaultSignInterval by default
+	SignInterval time.Duration `yaml:"signInterval"`
+}
+
+// ParseConfig parses the decision log config file:
+//
+//	audit: never
+//	redact:
+//	  headers: [authorization, cookie]
+//	  claims: [email]
+//	sinks:
+//	  - type: stdout
+//	    sampleRate: 0.1
+//	  - type: file
+//	    path: /var/log/authlink/decisions.log
+//	    maxSizeMB: 100
+//	    maxBackups: 5
+//	  - type: http
+//	    url: https://collector.internal/decisions
+//	    batchSize: 500
+//	    flushInterval: 5s
+//	  - type: syslog
+//	    network: udp
+//	    address: localhost:514
+//	  - type: audit
+//	    path: /var/log/authlink/audit.log
+//	    signingKey: /etc/authlink/audit.key
+//	    signInterval: 1m
+func ParseConfig(content []byte) (*Config, error) {
+	config := &Config{}
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("invalid decision log config format: %w", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Validate checks the audit mode and that sinks have unique names and the parameters required by their types
+func (c *Config) Validate() error {
+	if len(c.Sinks) == 0 {
+		return errors.New(errNoSinks)
+	}
+
+	if _, err := policy.ParseAudit(c.Audit); err != nil {
+		return err
+	}
+
+	names := map[string]struct{}{}
+	for _, sink := range c.Sinks {
+		name := sink.name()
+		if _, ok := names[name]; ok {
+			return fmt.Errorf(errDuplicateSinkName, name)
+		}
+		names[name] = struct{}{}
+
+		if sink.SampleRate != nil && (*sink.SampleRate < 0 || *sink.SampleRate > 1) {
+			return fmt.Errorf(errInvalidSampleRate, name)
+		}
+
+		if sink.BufferSize < 0 || sink.BatchSize < 0 || sink.FlushInterval < 0 || sink.Timeout < 0 || sink.SignInterval < 0 {
+			return fmt.Errorf(errNegativeSinkParam, name)
+		}
+
+		switch sink.Type {
+		case SinkStdout, SinkSyslog:
+		case SinkFile:
+			if len(sink.Path) == 0 {
+				return fmt.Errorf(errFilePathRequired, name)
+			}
+			if sink.MaxSizeMB < 0 || sink.MaxBackups < 0 {
+				return fmt.Errorf(errNegativeRotation, name)
+			}
+		case SinkHTTP:
+			if len(sink.URL) == 0 {
+				return fmt.Errorf(errURLRequired, name)
+			}
+		case SinkAudit:
+			if len(sink.Path) == 0 || len(sink.SigningKey) == 0 {
+				return fmt.Errorf(errAuditKeyRequired, name)
+			}
+			if sink.SampleRate != nil {
+				return fmt.Errorf(errAuditSampleRate, name)
+			}
+		default:
+			return fmt.Errorf(errUnknownSinkType, sink.Type, name)
+		}
+	}
+
+	return nil
+}
+
+func (s SinkConfig) name() string {
+	if len(s.Name) > 0 {
+		return s.Name
+	}
+
+	return string(s.Type)
+}
+
+func (s SinkConfig) sampleRate() float64 {
+	if s.SampleRate == nil {
+		return 1
+	}
+
+	return *s.SampleRate
+}
+
+func (s SinkConfig) bufferSize() int {
+	if s.BufferSize == 0 {
+		return DefaultBufferSize
+	}
+
+	return s.BufferSize
+}
+
+func (s SinkConfig) batchSize() int {
+	if s.BatchSize == 0 {
+		return DefaultBatchSize
+	}
+
+	return s.BatchSize
+}
+
+func (s SinkConfig) flushInterval() time.Duration {
+	if s.FlushInterval == 0 {
+		return DefaultFlushInterval
+	}
+
+	return s.FlushInterval
+}
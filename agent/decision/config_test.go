@@ -0,0 +1,106 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package decision
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseConfig(t *testing.T) {
+	config, err := ParseConfig([]byte(`
+audit: deny-only
+redact:
+  headers: [authorization, x-token]
+  claims: [email]
+sinks:
+  - type: stdout
+    sampleRate: 0.5
+  - name: audit
+    type: file
+    path: /var/log/authlink/decisions.log
+    maxSizeMB: 10
+  - type: http
+    url: https://collector.internal/decisions
+    headers:
+      authorization: Bearer secret
+    batchSize: 500
+    flushInterval: 5s`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "deny-only", config.Audit)
+	assert.Equal(t, &RedactConfig{Headers: []string{"authorization", "x-token"}, Claims: []string{"email"}}, config.Redact)
+	require.Len(t, config.Sinks, 3)
+	assert.Equal(t, 0.5, config.Sinks[0].sampleRate())
+	assert.Equal(t, DefaultBufferSize, config.Sinks[0].bufferSize())
+	assert.Equal(t, "audit", config.Sinks[1].name())
+	assert.Equal(t, 1.0, config.Sinks[1].sampleRate())
+	assert.Equal(t, "http", config.Sinks[2].name())
+	assert.Equal(t, 500, config.Sinks[2].batchSize())
+	assert.Equal(t, 5*time.Second, config.Sinks[2].flushInterval())
+	assert.Equal(t, map[string]string{"authorization": "Bearer secret"}, config.Sinks[2].Headers)
+
+	_, err = ParseConfig([]byte(`sinks: []`))
+	require.EqualError(t, err, errNoSinks)
+
+	_, err = ParseConfig([]byte(`sinks: {`))
+	require.Error(t, err)
+}
+
+func Test_ValidateConfig(t *testing.T) {
+	rate := 1.5
+	half := 0.5
+
+	cases := []struct {
+		sinks []SinkConfig
+		err   string
+	}{
+		{
+			sinks: []SinkConfig{{Type: "kafka"}},
+			err:   fmt.Sprintf(errUnknownSinkType, "kafka", "kafka"),
+		},
+		{
+			sinks: []SinkConfig{{Type: SinkStdout}, {Type: SinkStdout}},
+			err:   fmt.Sprintf(errDuplicateSinkName, "stdout"),
+		},
+		{
+			sinks: []SinkConfig{{Type: SinkStdout, SampleRate: &rate}},
+			err:   fmt.Sprintf(errInvalidSampleRate, "stdout"),
+		},
+		{
+			sinks: []SinkConfig{{Type: SinkStdout, BufferSize: -1}},
+			err:   fmt.Sprintf(errNegativeSinkParam, "stdout"),
+		},
+		{
+			sinks: []SinkConfig{{Type: SinkFile}},
+			err:   fmt.Sprintf(errFilePathRequired, "file"),
+		},
+		{
+			sinks: []SinkConfig{{Type: SinkFile, Path: "decisions.log", MaxBackups: -1}},
+			err:   fmt.Sprintf(errNegativeRotation, "file"),
+		},
+		{
+			sinks: []SinkConfig{{Name: "collector", Type: SinkHTTP}},
+			err:   fmt.Sprintf(errURLRequired, "collector"),
+		},
+		{
+			sinks: []SinkConfig{{Type: SinkAudit, Path: "audit.log"}},
+			err:   fmt.Sprintf(errAuditKeyRequired, "audit"),
+		},
+		{
+			sinks: []SinkConfig{{Type: SinkAudit, Path: "audit.log", SigningKey: "audit.key", SampleRate: &half}},
+			err:   fmt.Sprintf(errAuditSampleRate, "audit"),
+		},
+	}
+
+	for _, c := range cases {
+		config := Config{Sinks: c.sinks}
+		assert.EqualError(t, config.Validate(), c.err)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package decision
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/goauthlink/authlink/sdk/policy"
+)
+
+// Redacted replaces values of redacted headers and claims
+const Redacted = "[REDACTED]"
+
+// Event is a structured record of a policy decision
+type Event struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Input     Input     `json:"input"`
+	Identity  Identity  `json:"identity"`
+	// Rule is the matched endpoint of the policy, default if no policy matched
+	Rule   string `json:"rule"`
+	Result Result `json:"result"`
+	// Revision of the active policy and data
+	Revision string `json:"revision,omitempty"`
+	// LatencyMs is the duration of the check
+	LatencyMs float64 `json:"latencyMs"`
+	// StaleData marks decisions which used the data older than the allowed staleness
+	StaleData bool `json:"staleData,omitempty"`
+
+	// audit of the matched policy selects whether the event is logged
+	audit policy.Audit
+}
+
+type Input struct {
+	URI     string            `json:"uri"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type Identity struct {
+	ClientName string `json:"clientName,omitempty"`
+	// Identities are client names resolved from named sources
+	Identities map[string]string `json:"identities,omitempty"`
+	// Claims are jwt claims of the identities by source name
+	Claims map[string]map[string]interface{} `json:"claims,omitempty"`
+}
+
+type Result struct {
+	Allow bool   `json:"allow"`
+	Error string `json:"error,omitempty"`
+}
+
+// NewEvent creates the event of the check result
+func NewEvent(in policy.CheckInput, result *policy.CheckResult, latency time.Duration) Event {
+	event := Event{
+		ID:        newID(),
+		Timestamp: time.Now().UTC(),
+		Input: Input{
+			URI:     in.Uri,
+			Method:  in.Method,
+			Headers: in.Headers,
+		},
+		Identity: Identity{
+			ClientName: result.ClientName,
+			Identities: result.Identities,
+			Claims:     result.Claims,
+		},
+		Rule: result.Endpoint,
+		Result: Result{
+			Allow: result.Allow,
+		},
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+		audit:     result.Audit,
+	}
+
+	if result.Err != nil {
+		event.Result.Error = result.Err.Error()
+	}
+
+	return event
+}
+
+func newID() string {
+	id := make([]byte, 16)
+	rand.Read(id) //nolint: errcheck
+
+	return hex.EncodeToString(id)
+}
+
+// redactor replaces values of the listed headers and claims, names are case insensitive
+type redactor struct {
+	headers map[string]struct{}
+	claims  map[string]struct{}
+}
+
+func newRedactor(config *RedactConfig) *redactor {
+	if config == nil {
+		config = &RedactConfig{}
+	}
+
+	headers := config.Headers
+	if headers == nil {
+		headers = DefaultRedactHeaders
+	}
+
+	r := &redactor{
+		headers: map[string]struct{}{},
+		claims:  map[string]struct{}{},
+	}
+	for _, header := range headers {
+		r.headers[strings.ToLower(header)] = struct{}{}
+	}
+	for _, claim := range config.Claims {
+		r.claims[strings.ToLower(claim)] = struct{}{}
+	}
+
+	return r
+}
+
+// redact returns the event with redacted values, maps of the input event aren't changed
+func (r *redactor) redact(event Event) Event {
+	if len(r.headers) > 0 && len(event.Input.Headers) > 0 {
+		headers := maps.Clone(event.Input.Headers)
+		for name := range headers {
+			if _, ok := r.headers[strings.ToLower(name)]; ok {
+				headers[name] = Redacted
+			}
+		}
+		event.Input.Headers = headers
+	}
+
+	if len(r.claims) > 0 && len(event.Identity.Claims) > 0 {
+		claims := make(map[string]map[string]interface{}, len(event.Identity.Claims))
+		for source, sourceClaims := range event.Identity.Claims {
+			redacted := maps.Clone(sourceClaims)
+			for name := range redacted {
+				if _, ok := r.claims[strings.ToLower(name)]; ok {
+					redacted[name] = Redacted
+				}
+			}
+			claims[source] = redacted
+		}
+		event.Identity.Claims = claims
+	}
+
+	return event
+}
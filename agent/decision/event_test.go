@@ -0,0 +1,86 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package decision
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/goauthlink/authlink/sdk/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewEvent(t *testing.T) {
+	in := policy.CheckInput{
+		Uri:     "/orders/1",
+		Method:  "GET",
+		Headers: map[string]string{"x-source": "web-bff"},
+	}
+	result := &policy.CheckResult{
+		Allow:      false,
+		ClientName: "web-bff",
+		Identities: map[string]string{"service": "web-bff"},
+		Endpoint:   "/orders/[0-9]+",
+		Err:        errors.New("jsonpath error"),
+	}
+
+	event := NewEvent(in, result, 1500*time.Microsecond)
+	assert.Len(t, event.ID, 32)
+	assert.False(t, event.Timestamp.IsZero())
+	assert.Equal(t, Input{URI: "/orders/1", Method: "GET", Headers: in.Headers}, event.Input)
+	assert.Equal(t, Identity{ClientName: "web-bff", Identities: result.Identities}, event.Identity)
+	assert.Equal(t, "/orders/[0-9]+", event.Rule)
+	assert.Equal(t, Result{Allow: false, Error: "jsonpath error"}, event.Result)
+	assert.Equal(t, 1.5, event.LatencyMs)
+
+	assert.NotEqual(t, event.ID, NewEvent(in, result, 0).ID)
+}
+
+func Test_Redact(t *testing.T) {
+	event := Event{
+		Input: Input{Headers: map[string]string{
+			"Authorization": "Bearer token",
+			"Cookie":        "session=1",
+			"X-Token":       "token",
+			"x-source":      "web-bff",
+		}},
+		Identity: Identity{Claims: map[string]map[string]interface{}{
+			"user": {"sub": "jhon", "Email": "jhon@example.com"},
+		}},
+	}
+
+	// credentials headers are redacted by default
+	redacted := newRedactor(nil).redact(event)
+	assert.Equal(t, map[string]string{
+		"Authorization": Redacted,
+		"Cookie":        Redacted,
+		"X-Token":       "token",
+		"x-source":      "web-bff",
+	}, redacted.Input.Headers)
+	assert.Equal(t, event.Identity.Claims, redacted.Identity.Claims)
+
+	redacted = newRedactor(&RedactConfig{Headers: []string{"x-token"}, Claims: []string{"email"}}).redact(event)
+	assert.Equal(t, map[string]string{
+		"Authorization": "Bearer token",
+		"Cookie":        "session=1",
+		"X-Token":       Redacted,
+		"x-source":      "web-bff",
+	}, redacted.Input.Headers)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"user": {"sub": "jhon", "Email": Redacted},
+	}, redacted.Identity.Claims)
+
+	// the source event isn't changed
+	assert.Equal(t, "Bearer token", event.Input.Headers["Authorization"])
+	assert.Equal(t, "jhon@example.com", event.Identity.Claims["user"]["Email"])
+
+	// claims only config keeps the default headers redaction
+	redacted = newRedactor(&RedactConfig{Claims: []string{"email"}}).redact(event)
+	assert.Equal(t, Redacted, redacted.Input.Headers["Authorization"])
+
+	redacted = newRedactor(&RedactConfig{Headers: []string{}}).redact(event)
+	assert.Equal(t, "Bearer token", redacted.Input.Headers["Authorization"])
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package decision
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPSink posts every batch of events as a json array to the url
+type HTTPSink struct {
+	url     string
+	headers map[string]string
+	timeout time.Duration
+	client  *http.Client
+}
+
+// NewHTTPSink creates the sink sending the headers with every batch, e.g. authorization of the collector.
+// Zero timeout means DefaultHTTPTimeout.
+func NewHTTPSink(url string, headers map[string]string, timeout time.Duration) *HTTPSink {
+	if timeout == 0 {
+		timeout = DefaultHTTPTimeout
+	}
+
+	return &HTTPSink{
+		url:     url,
+		headers: headers,
+		timeout: timeout,
+		client:  http.DefaultClient,
+	}
+}
+
+func (s *HTTPSink) Write(events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	rq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	rq.Header.Set("Content-Type", "application/json")
+	for name, value := range s.headers {
+		rq.Header.Set(name, value)
+	}
+
+	rsp, err := s.client.Do(rq)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	io.Copy(io.Discard, rsp.Body) //nolint: errcheck
+
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %d", rsp.StatusCode)
+	}
+
+	return nil
+}
+
+func (s *HTTPSink) Close() error {
+	return nil
+}
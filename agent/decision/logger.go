@@ -0,0 +1,322 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package decision
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goauthlink/authlink/pkg/logging"
+	"github.com/goauthlink/authlink/pkg/metrics"
+	"github.com/goauthlink/authlink/sdk/policy"
+)
+
+// Sink writes batches of decision events to their destination
+type Sink interface {
+	Write(events []Event) error
+	Close() error
+}
+
+// SinkStats are counters of a sink since the logger was created
+type SinkStats struct {
+	Name string
+	// Written is the number of events written by the sink
+	Written uint64
+	// Dropped is the number of events dropped because the buffer was full
+	Dropped uint64
+	// Failed is the number of events lost on write errors
+	Failed uint64
+}
+
+// pipeline buffers sampled events of a sink and writes them in batches on its own goroutine
+type pipeline struct {
+	name       string
+	sink       Sink
+	sampleRate float64
+	// blocking pipelines wait for buffer space instead of dropping events
+	blocking      bool
+	batchSize     int
+	flushInterval time.Duration
+	events        chan Event
+	done          chan struct{}
+
+	written atomic.Uint64
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+}
+
+// Logger passes redacted decision events to the sinks. Logging doesn't block checks:
+// every sink has a bounded buffer and events are dropped when it's full. The audit sink
+// is the exception, it must not lose events, so checks wait for its buffer.
+type Logger struct {
+	redactor  *redactor
+	audit     policy.Audit
+	pipelines []*pipeline
+	logger    *slog.Logger
+	meter     *metrics.Meter
+	// closeMux guards sending to buffers against closing them
+	closeMux sync.RWMutex
+	closed   bool
+
+	counterDropped metrics.Metric
+	counterFailed  metrics.Metric
+}
+
+type LoggerOpt func(*Logger)
+
+func WithLogger(logger *slog.Logger) LoggerOpt {
+	return func(l *Logger) {
+		l.logger = logger
+	}
+}
+
+// WithMeter creates metrics of the sinks by the meter instead of the global meter provider
+func WithMeter(meter *metrics.Meter) LoggerOpt {
+	return func(l *Logger) {
+		l.meter = meter
+	}
+}
+
+// WithSink adds a sink created by the program embedding the agent
+func WithSink(config SinkConfig, sink Sink) LoggerOpt {
+	return func(l *Logger) {
+		l.pipelines = append(l.pipelines, newPipeline(config, sink))
+	}
+}
+
+// NewLogger creates sinks of the config and starts writing to them
+func NewLogger(config Config, opts ...LoggerOpt) (*Logger, error) {
+	l := &Logger{
+		redactor: newRedactor(config.Redact),
+	}
+
+	for _, o := range opts {
+		o(l)
+	}
+
+	if l.logger == nil {
+		l.logger = logging.NewNullLogger()
+	}
+
+	if len(config.Sinks) > 0 {
+		if err := config.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	audit, err := policy.ParseAudit(config.Audit)
+	if err != nil {
+		return nil, err
+	}
+	l.audit = audit
+
+	counterDropped, err := l.meter.NewCounter("decision_log_dropped", "A counter of decision events dropped by full sink buffers")
+	if err != nil {
+		return nil, err
+	}
+	l.counterDropped = counterDropped
+
+	counterFailed, err := l.meter.NewCounter("decision_log_failed", "A counter of decision events lost on sink write errors")
+	if err != nil {
+		return nil, err
+	}
+	l.counterFailed = counterFailed
+
+	for _, sinkConfig := range config.Sinks {
+		sink, err := newSink(sinkConfig, l.logger)
+		if err != nil {
+			l.closeSinks()
+			return nil, fmt.Errorf("init decision log sink %s: %w", sinkConfig.name(), err)
+		}
+		l.pipelines = append(l.pipelines, newPipeline(sinkConfig, sink))
+	}
+
+	for _, p := range l.pipelines {
+		go l.run(p)
+	}
+
+	return l, nil
+}
+
+func newSink(config SinkConfig, logger *slog.Logger) (Sink, error) {
+	switch config.Type {
+	case SinkStdout:
+		return NewStdoutSink(), nil
+	case SinkFile:
+		return NewFileSink(config.Path, config.MaxSizeMB, config.MaxBackups)
+	case SinkHTTP:
+		return NewHTTPSink(config.URL, config.Headers, config.Timeout), nil
+	case SinkSyslog:
+		return NewSyslogSink(config.Network, config.Address, config.Tag)
+	case SinkAudit:
+		return newAuditSink(config, logger)
+	}
+
+	return nil, fmt.Errorf(errUnknownSinkType, config.Type, config.name())
+}
+
+func newPipeline(config SinkConfig, sink Sink) *pipeline {
+	return &pipeline{
+		name:          config.name(),
+		sink:          sink,
+		sampleRate:    config.sampleRate(),
+		blocking:      config.Type == SinkAudit,
+		batchSize:     config.batchSize(),
+		flushInterval: config.flushInterval(),
+		events:        make(chan Event, config.bufferSize()),
+		done:          make(chan struct{}),
+	}
+}
+
+// Log redacts the event and passes it to the sinks if it's selected by the audit mode of the policy,
+// or by the audit of the config if the policy doesn't set it. Events selected by always, deny-only
+// and sample:N are written by every sink, otherwise sinks sample events with their own rates.
+func (l *Logger) Log(event Event) {
+	selected, sampled := l.selected(event)
+	if !selected {
+		return
+	}
+
+	event = l.redactor.redact(event)
+
+	l.closeMux.RLock()
+	defer l.closeMux.RUnlock()
+
+	if l.closed {
+		return
+	}
+
+	for _, p := range l.pipelines {
+		if sampled && p.sampleRate < 1 && rand.Float64() >= p.sampleRate {
+			continue
+		}
+
+		if p.blocking {
+			p.events <- event
+			continue
+		}
+
+		select {
+		case p.events <- event:
+		default:
+			p.dropped.Add(1)
+			l.counterDropped.Record(1, map[string]string{"sink": p.name})
+		}
+	}
+}
+
+// selected reports whether the event is logged and whether sample rates of the sinks apply to it
+func (l *Logger) selected(event Event) (bool, bool) {
+	audit := event.audit
+	if len(audit.Mode) == 0 {
+		audit = l.audit
+	}
+
+	switch audit.Mode {
+	case policy.AuditAlways:
+		return true, false
+	case policy.AuditDenyOnly:
+		return !event.Result.Allow, false
+	case policy.AuditSample:
+		return rand.Float64() < audit.Rate, false
+	case policy.AuditNever:
+		return false, false
+	}
+
+	return true, true
+}
+
+// Stats returns counters of the sinks in the order of the config
+func (l *Logger) Stats() []SinkStats {
+	stats := make([]SinkStats, 0, len(l.pipelines))
+	for _, p := range l.pipelines {
+		stats = append(stats, SinkStats{
+			Name:    p.name,
+			Written: p.written.Load(),
+			Dropped: p.dropped.Load(),
+			Failed:  p.failed.Load(),
+		})
+	}
+
+	return stats
+}
+
+// Close writes buffered events and closes the sinks, events logged after closing are dropped
+func (l *Logger) Close() error {
+	l.closeMux.Lock()
+	if l.closed {
+		l.closeMux.Unlock()
+		return nil
+	}
+	l.closed = true
+	for _, p := range l.pipelines {
+		close(p.events)
+	}
+	l.closeMux.Unlock()
+
+	for _, p := range l.pipelines {
+		<-p.done
+	}
+
+	return l.closeSinks()
+}
+
+func (l *Logger) closeSinks() error {
+	var errs []error
+	for _, p := range l.pipelines {
+		if err := p.sink.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close decision log sink %s: %w", p.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// run writes batches of the pipeline when the batch is full or the flush interval passed
+func (l *Logger) run(p *pipeline) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, p.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := p.sink.Write(batch); err != nil {
+			p.failed.Add(uint64(len(batch)))
+			l.counterFailed.Record(float64(len(batch)), map[string]string{"sink": p.name})
+			l.logger.Error(fmt.Sprintf("writing %d decision events to sink %s failed: %s", len(batch), p.name, err.Error()))
+		} else {
+			p.written.Add(uint64(len(batch)))
+		}
+
+		batch = make([]Event, 0, p.batchSize)
+	}
+
+	for {
+		select {
+		case event, ok := <-p.events:
+			if !ok {
+				flush()
+				return
+			}
+
+			batch = append(batch, event)
+			if len(batch) >= p.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package decision
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goauthlink/authlink/sdk/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSink struct {
+	mux     sync.Mutex
+	batches [][]Event
+	block   chan struct{}
+	err     error
+}
+
+func (s *testSink) Write(events []Event) error {
+	if s.block != nil {
+		<-s.block
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.batches = append(s.batches, events)
+
+	return s.err
+}
+
+func (s *testSink) Close() error {
+	return nil
+}
+
+func (s *testSink) events() []Event {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	var events []Event
+	for _, batch := range s.batches {
+		events = append(events, batch...)
+	}
+
+	return events
+}
+
+func Test_LoggerBatches(t *testing.T) {
+	sink := &testSink{}
+	logger, err := NewLogger(Config{}, WithSink(SinkConfig{Name: "test", BatchSize: 2, FlushInterval: time.Hour}, sink))
+	require.NoError(t, err)
+
+	for _, id := range []string{"1", "2", "3"} {
+		logger.Log(Event{ID: id, Input: Input{Headers: map[string]string{"authorization": "token"}}})
+	}
+
+	// the full batch is written right away
+	assert.Eventually(t, func() bool { return len(sink.events()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, Redacted, sink.events()[0].Input.Headers["authorization"])
+
+	// the rest is flushed on close
+	require.NoError(t, logger.Close())
+	events := sink.events()
+	require.Len(t, events, 3)
+	assert.Equal(t, "3", events[2].ID)
+	assert.Equal(t, []SinkStats{{Name: "test", Written: 3}}, logger.Stats())
+
+	// events after closing are dropped
+	logger.Log(Event{ID: "4"})
+	assert.Len(t, sink.events(), 3)
+}
+
+func Test_LoggerFlushInterval(t *testing.T) {
+	sink := &testSink{}
+	logger, err := NewLogger(Config{}, WithSink(SinkConfig{Name: "test", FlushInterval: 10 * time.Millisecond}, sink))
+	require.NoError(t, err)
+	defer logger.Close()
+
+	logger.Log(Event{ID: "1"})
+	assert.Eventually(t, func() bool { return len(sink.events()) == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func Test_LoggerDrops(t *testing.T) {
+	blocked := &testSink{block: make(chan struct{})}
+	failed := &testSink{err: errors.New("collector is unavailable")}
+	zero := 0.0
+
+	logger, err := NewLogger(Config{},
+		WithSink(SinkConfig{Name: "blocked", BufferSize: 2, BatchSize: 1}, blocked),
+		WithSink(SinkConfig{Name: "failed", BatchSize: 1}, failed),
+		WithSink(SinkConfig{Name: "sampled", SampleRate: &zero}, &testSink{}),
+	)
+	require.NoError(t, err)
+
+	// the first event is taken by the blocked write, two are buffered, the rest are dropped
+	logger.Log(Event{ID: "1"})
+	assert.Eventually(t, func() bool { return len(blocked.events()) == 0 && len(logger.pipelines[0].events) == 0 }, time.Second, 5*time.Millisecond)
+	for i := 0; i < 5; i++ {
+		logger.Log(Event{})
+	}
+
+	close(blocked.block)
+	require.NoError(t, logger.Close())
+
+	assert.Equal(t, []SinkStats{
+		{Name: "blocked", Written: 3, Dropped: 3},
+		{Name: "failed", Failed: 6},
+		{Name: "sampled"},
+	}, logger.Stats())
+}
+
+func Test_LoggerAuditSinkBlocks(t *testing.T) {
+	sink := &testSink{block: make(chan struct{})}
+	logger, err := NewLogger(Config{}, WithSink(SinkConfig{Type: SinkAudit, BufferSize: 1, BatchSize: 1}, sink))
+	require.NoError(t, err)
+
+	// the first event is taken by the blocked write, the second is buffered
+	logger.Log(Event{ID: "1"})
+	assert.Eventually(t, func() bool { return len(logger.pipelines[0].events) == 0 }, time.Second, 5*time.Millisecond)
+	logger.Log(Event{ID: "2"})
+
+	// the third waits for the buffer instead of being dropped
+	logged := make(chan struct{})
+	go func() {
+		logger.Log(Event{ID: "3"})
+		close(logged)
+	}()
+
+	select {
+	case <-logged:
+		t.Fatal("event is logged while the audit buffer is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(sink.block)
+	<-logged
+	require.NoError(t, logger.Close())
+
+	assert.Len(t, sink.events(), 3)
+	assert.Equal(t, []SinkStats{{Name: "audit", Written: 3}}, logger.Stats())
+}
+
+func Test_NewLoggerInvalidSink(t *testing.T) {
+	_, err := NewLogger(Config{Sinks: []SinkConfig{{Type: SinkFile, Path: t.TempDir() + "/unknown/decisions.log"}}})
+	require.ErrorContains(t, err, "init decision log sink file")
+}
+
+func Test_LoggerAudit(t *testing.T) {
+	zero := 0.0
+	sink := &testSink{}
+	logger, err := NewLogger(Config{Audit: "never"}, WithSink(SinkConfig{Name: "test", SampleRate: &zero}, sink))
+	require.NoError(t, err)
+
+	events := []Event{
+		{ID: "always", audit: policy.Audit{Mode: policy.AuditAlways}},
+		{ID: "denied", audit: policy.Audit{Mode: policy.AuditDenyOnly}},
+		{ID: "allowed", Result: Result{Allow: true}, audit: policy.Audit{Mode: policy.AuditDenyOnly}},
+		{ID: "sampled", audit: policy.Audit{Mode: policy.AuditSample, Rate: 1}},
+		{ID: "not-sampled", audit: policy.Audit{Mode: policy.AuditSample}},
+		{ID: "never", audit: policy.Audit{Mode: policy.AuditNever}},
+		// the audit of the config applies to policies without audit
+		{ID: "unset"},
+	}
+	for _, event := range events {
+		logger.Log(event)
+	}
+	require.NoError(t, logger.Close())
+
+	ids := []string{}
+	for _, event := range sink.events() {
+		ids = append(ids, event.ID)
+	}
+	// selected events bypass the sample rate of the sink
+	assert.Equal(t, []string{"always", "denied", "sampled"}, ids)
+
+	_, err = NewLogger(Config{Audit: "sometimes", Sinks: []SinkConfig{{Type: SinkStdout}}})
+	require.ErrorContains(t, err, "invalid audit `sometimes`")
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package decision
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// WriterSink writes events as json lines to the writer
+type WriterSink struct {
+	mux    sync.Mutex
+	writer io.Writer
+}
+
+func NewWriterSink(writer io.Writer) *WriterSink {
+	return &WriterSink{writer: writer}
+}
+
+// NewStdoutSink writes events as json lines to stdout
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink(os.Stdout)
+}
+
+func (s *WriterSink) Write(events []Event) error {
+	lines, err := encodeLines(events)
+	if err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	_, err = s.writer.Write(lines)
+
+	return err
+}
+
+func (s *WriterSink) Close() error {
+	return nil
+}
+
+// FileSink writes events as json lines to the file, the file is rotated when it exceeds the max size:
+// path is renamed to path.1, path.1 to path.2 and so on, files above max backups are removed
+type FileSink struct {
+	mux        sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// NewFileSink opens the file for appending, zero maxSizeMB and maxBackups mean the defaults
+func NewFileSink(path string, maxSizeMB, maxBackups int) (*FileSink, error) {
+	if maxSizeMB == 0 {
+		maxSizeMB = DefaultMaxSizeMB
+	}
+	if maxBackups == 0 {
+		maxBackups = DefaultMaxBackups
+	}
+
+	s := &FileSink{
+		path:       path,
+		maxSize:    int64(maxSizeMB) << 20,
+		maxBackups: maxBackups,
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileSink) Write(events []Event) error {
+	lines, err := encodeLines(events)
+	if err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.size > 0 && s.size+int64(len(lines)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(lines)
+	s.size += int64(n)
+
+	return err
+}
+
+func (s *FileSink) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.file.Close()
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("open decision log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close() //nolint: errcheck
+		return fmt.Errorf("stat decision log file: %w", err)
+	}
+
+	s.file = file
+	s.size = info.Size()
+
+	return nil
+}
+
+// rotate shifts the backups and reopens the file, it's called under mux
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("close decision log file: %w", err)
+	}
+
+	os.Remove(s.backupPath(s.maxBackups)) //nolint: errcheck
+	for i := s.maxBackups - 1; i > 0; i-- {
+		os.Rename(s.backupPath(i), s.backupPath(i+1)) //nolint: errcheck
+	}
+	if err := os.Rename(s.path, s.backupPath(1)); err != nil {
+		return fmt.Errorf("rotate decision log file: %w", err)
+	}
+
+	return s.open()
+}
+
+func (s *FileSink) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", s.path, i)
+}
+
+func encodeLines(events []Event) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
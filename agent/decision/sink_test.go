@@ -0,0 +1,158 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package decision
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goauthlink/authlink/agent/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readLines(t *testing.T, path string) []Event {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var events []Event
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		event := Event{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func Test_WriterSink(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sink := NewWriterSink(buf)
+
+	require.NoError(t, sink.Write([]Event{{ID: "1", Rule: "default"}, {ID: "2"}}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"id":"1"`)
+	assert.Contains(t, lines[0], `"rule":"default"`)
+}
+
+func Test_FileSinkRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.log")
+
+	sink, err := NewFileSink(path, 1, 2)
+	require.NoError(t, err)
+	// rotate after every batch
+	sink.maxSize = 10
+
+	for _, id := range []string{"1", "2", "3", "4"} {
+		require.NoError(t, sink.Write([]Event{{ID: id}}))
+	}
+	require.NoError(t, sink.Close())
+
+	assert.Equal(t, "4", readLines(t, path)[0].ID)
+	assert.Equal(t, "3", readLines(t, path+".1")[0].ID)
+	assert.Equal(t, "2", readLines(t, path+".2")[0].ID)
+	assert.NoFileExists(t, path+".3")
+
+	// the existing file is appended
+	sink, err = NewFileSink(path, 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, sink.Write([]Event{{ID: "5"}}))
+	require.NoError(t, sink.Close())
+
+	events := readLines(t, path)
+	require.Len(t, events, 2)
+	assert.Equal(t, "5", events[1].ID)
+}
+
+func Test_HTTPSink(t *testing.T) {
+	var received []Event
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink := NewHTTPSink(srv.URL, map[string]string{"Authorization": "Bearer secret"}, time.Second)
+	require.NoError(t, sink.Write([]Event{{ID: "1"}, {ID: "2"}}))
+	require.Len(t, received, 2)
+	assert.Equal(t, "2", received[1].ID)
+
+	status = http.StatusServiceUnavailable
+	require.EqualError(t, sink.Write([]Event{{ID: "3"}}), "unexpected response status 503")
+}
+
+func Test_SyslogSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("udp", conn.LocalAddr().String(), "")
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Write([]Event{{ID: "1"}}))
+
+	buf := make([]byte, 4096)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+
+	message := string(buf[:n])
+	assert.Contains(t, message, DefaultSyslogTag)
+	assert.Contains(t, message, `{"id":"1"`)
+}
+
+func Test_AuditSink(t *testing.T) {
+	dir := t.TempDir()
+	public, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "audit.key"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	logger, err := NewLogger(Config{Sinks: []SinkConfig{{
+		Type:       SinkAudit,
+		Path:       filepath.Join(dir, "audit.log"),
+		SigningKey: filepath.Join(dir, "audit.key"),
+	}}})
+	require.NoError(t, err)
+
+	logger.Log(Event{ID: "1"})
+	logger.Log(Event{ID: "2"})
+	require.NoError(t, logger.Close())
+
+	file, err := os.Open(filepath.Join(dir, "audit.log"))
+	require.NoError(t, err)
+	defer file.Close()
+
+	report, err := audit.Verify(file, public)
+	require.NoError(t, err)
+	assert.Equal(t, &audit.Report{Records: 3, Signatures: 1}, report)
+
+	_, err = NewLogger(Config{Sinks: []SinkConfig{{
+		Type:       SinkAudit,
+		Path:       filepath.Join(dir, "audit.log"),
+		SigningKey: filepath.Join(dir, "audit.log"),
+	}}})
+	require.ErrorContains(t, err, "init decision log sink audit: private key isn't PEM encoded")
+}
@@ -0,0 +1,50 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+//go:build !windows && !plan9
+
+package decision
+
+import (
+	"encoding/json"
+	"log/syslog"
+)
+
+// SyslogSink sends every event as a json message with info severity
+type SyslogSink struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogSink connects to the syslog server, the local syslog is used if the network and address are empty.
+// Empty tag means DefaultSyslogTag.
+func NewSyslogSink(network, address, tag string) (*SyslogSink, error) {
+	if len(tag) == 0 {
+		tag = DefaultSyslogTag
+	}
+
+	writer, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SyslogSink{writer: writer}, nil
+}
+
+func (s *SyslogSink) Write(events []Event) error {
+	for _, event := range events {
+		message, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if err := s.writer.Info(string(message)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SyslogSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,23 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+//go:build windows || plan9
+
+package decision
+
+import "errors"
+
+type SyslogSink struct{}
+
+func NewSyslogSink(_, _, _ string) (*SyslogSink, error) {
+	return nil, errors.New("syslog isn't supported on this platform")
+}
+
+func (s *SyslogSink) Write(_ []Event) error {
+	return nil
+}
+
+func (s *SyslogSink) Close() error {
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/goauthlink/authlink/agent/decision"
+	"github.com/goauthlink/authlink/sdk/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DecisionLog(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	config := DefaultConfig()
+	config.LogLevel = slog.LevelError
+	config.PolicyFilePath = rootDir + "/policy.yaml"
+	config.DecisionLog = &decision.Config{
+		Sinks: []decision.SinkConfig{{Type: decision.SinkFile, Path: rootDir + "/decisions.log"}},
+	}
+
+	agent, err := Init(config)
+	require.NoError(t, err)
+	require.Len(t, agent.Revision(), 16)
+
+	_, err = agent.policy.Check(context.Background(), policy.CheckInput{
+		Uri:     "/endpoint",
+		Method:  "GET",
+		Headers: map[string]string{"x-source": "client", "authorization": "Bearer token"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, agent.decisions.Close())
+
+	content, err := os.ReadFile(rootDir + "/decisions.log")
+	require.NoError(t, err)
+
+	event := decision.Event{}
+	require.NoError(t, json.Unmarshal(content, &event))
+	assert.Equal(t, "client", event.Identity.ClientName)
+	assert.Equal(t, "/endpoint", event.Rule)
+	assert.True(t, event.Result.Allow)
+	assert.Equal(t, agent.Revision(), event.Revision)
+	assert.Equal(t, decision.Redacted, event.Input.Headers["authorization"])
+	assert.Equal(t, "client", event.Input.Headers["x-source"])
+
+	// check results logging adds the stdout sink
+	config.LogCheckResults = true
+	agent, err = Init(config)
+	require.NoError(t, err)
+	defer agent.decisions.Close()
+
+	stats := agent.decisions.Stats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, "stdout", stats[1].Name)
+}
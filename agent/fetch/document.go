@@ -0,0 +1,125 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultInterval = time.Minute
+	DefaultTimeout  = 10 * time.Second
+)
+
+const (
+	errNoDocuments               = "data sources file doesn't contain sources"
+	errInvalidNamespace          = "invalid namespace %q, it must be a jsonpath field name"
+	errDuplicateNamespace        = "namespace %s is used by several sources"
+	errURLOrFileIsRequired       = "source %s must have either url or file"
+	errNegativeDurations         = "interval, timeout and maxStaleness of source %s must not be negative"
+	errStalenessNotAboveInterval = "maxStaleness of source %s must be greater than its interval"
+)
+
+var namespaceRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Document is a data document fetched periodically and mounted under the namespace,
+// e.g. the document with namespace hr is queried as {.hr.teams[*]}
+type Document struct {
+	Namespace string `yaml:"namespace"`
+	URL       string `yaml:"url"`
+	File      string `yaml:"file"`
+	// Interval between fetches, DefaultInterval by default
+	Interval time.Duration `yaml:"interval"`
+	// Timeout of a single fetch, DefaultTimeout by default
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxStaleness is the age of the last good value after which the document is stale,
+	// zero means the document never gets stale
+	MaxStaleness time.Duration `yaml:"maxStaleness"`
+}
+
+type documentsFile struct {
+	Sources []Document `yaml:"sources"`
+}
+
+// ParseDocuments parses the data sources file:
+//
+//	sources:
+//	  - namespace: hr
+//	    url: https://hr.internal/teams.json
+//	    interval: 30s
+//	    timeout: 5s
+//	    maxStaleness: 10m
+//	  - namespace: crm
+//	    file: /data/crm.json
+func ParseDocuments(content []byte) ([]Document, error) {
+	file := documentsFile{}
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("invalid data sources format: %w", err)
+	}
+
+	if len(file.Sources) == 0 {
+		return nil, errors.New(errNoDocuments)
+	}
+
+	if err := ValidateDocuments(file.Sources); err != nil {
+		return nil, err
+	}
+
+	return file.Sources, nil
+}
+
+// ValidateDocuments checks that namespaces are unique and every document has a single location
+func ValidateDocuments(docs []Document) error {
+	namespaces := map[string]struct{}{}
+	for _, doc := range docs {
+		if !namespaceRegexp.MatchString(doc.Namespace) {
+			return fmt.Errorf(errInvalidNamespace, doc.Namespace)
+		}
+		if _, ok := namespaces[doc.Namespace]; ok {
+			return fmt.Errorf(errDuplicateNamespace, doc.Namespace)
+		}
+		namespaces[doc.Namespace] = struct{}{}
+
+		if (len(doc.URL) == 0) == (len(doc.File) == 0) {
+			return fmt.Errorf(errURLOrFileIsRequired, doc.Namespace)
+		}
+
+		if doc.Interval < 0 || doc.Timeout < 0 || doc.MaxStaleness < 0 {
+			return fmt.Errorf(errNegativeDurations, doc.Namespace)
+		}
+
+		if doc.MaxStaleness > 0 && doc.MaxStaleness <= doc.interval() {
+			return fmt.Errorf(errStalenessNotAboveInterval, doc.Namespace)
+		}
+	}
+
+	return nil
+}
+
+func (doc Document) interval() time.Duration {
+	if doc.Interval > 0 {
+		return doc.Interval
+	}
+	return DefaultInterval
+}
+
+func (doc Document) timeout() time.Duration {
+	if doc.Timeout > 0 {
+		return doc.Timeout
+	}
+	return DefaultTimeout
+}
+
+func (doc Document) location() string {
+	if len(doc.URL) > 0 {
+		return doc.URL
+	}
+	return doc.File
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseDocuments(t *testing.T) {
+	docs, err := ParseDocuments([]byte(`
+sources:
+  - namespace: hr
+    url: https://hr.internal/teams.json
+    interval: 30s
+    timeout: 5s
+    maxStaleness: 10m
+  - namespace: crm
+    file: /data/crm.json`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Document{
+		{Namespace: "hr", URL: "https://hr.internal/teams.json", Interval: 30 * time.Second, Timeout: 5 * time.Second, MaxStaleness: 10 * time.Minute},
+		{Namespace: "crm", File: "/data/crm.json"},
+	}, docs)
+
+	assert.Equal(t, DefaultInterval, docs[1].interval())
+	assert.Equal(t, DefaultTimeout, docs[1].timeout())
+
+	_, err = ParseDocuments([]byte(`sources: []`))
+	require.EqualError(t, err, errNoDocuments)
+
+	_, err = ParseDocuments([]byte(`sources: {`))
+	require.Error(t, err)
+}
+
+func Test_ValidateDocuments(t *testing.T) {
+	cases := []struct {
+		docs []Document
+		err  string
+	}{
+		{
+			docs: []Document{{Namespace: "hr.teams", File: "hr.json"}},
+			err:  fmt.Sprintf(errInvalidNamespace, "hr.teams"),
+		},
+		{
+			docs: []Document{{Namespace: "hr", File: "hr.json"}, {Namespace: "hr", URL: "http://hr"}},
+			err:  fmt.Sprintf(errDuplicateNamespace, "hr"),
+		},
+		{
+			docs: []Document{{Namespace: "hr"}},
+			err:  fmt.Sprintf(errURLOrFileIsRequired, "hr"),
+		},
+		{
+			docs: []Document{{Namespace: "hr", File: "hr.json", URL: "http://hr"}},
+			err:  fmt.Sprintf(errURLOrFileIsRequired, "hr"),
+		},
+		{
+			docs: []Document{{Namespace: "hr", File: "hr.json", Timeout: -time.Second}},
+			err:  fmt.Sprintf(errNegativeDurations, "hr"),
+		},
+		{
+			docs: []Document{{Namespace: "hr", File: "hr.json", Interval: time.Minute, MaxStaleness: time.Minute}},
+			err:  fmt.Sprintf(errStalenessNotAboveInterval, "hr"),
+		},
+	}
+
+	for _, c := range cases {
+		assert.EqualError(t, ValidateDocuments(c.docs), c.err)
+	}
+}
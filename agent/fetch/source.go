@@ -0,0 +1,389 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/goauthlink/authlink/pkg/logging"
+	"github.com/goauthlink/authlink/pkg/metrics"
+)
+
+const maxDocumentSize = 256 << 20
+
+const (
+	errInvalidJSON      = "document isn't valid json"
+	errDocumentTooLarge = "document is too large"
+)
+
+var errNotModified = errors.New("document not modified")
+
+// Status is the state of a document of the source
+type Status struct {
+	Namespace string
+	// Updated is the time of the last successful fetch, zero if the document wasn't fetched yet
+	Updated time.Time
+	// Err is the error of the last fetch, nil if it succeeded
+	Err error
+	// Stale reports that the last good value is older than MaxStaleness of the document
+	Stale bool
+}
+
+type document struct {
+	Document
+	fetched bool
+	value   json.RawMessage
+	etag    string
+	updated time.Time
+	err     error
+	stale   bool
+}
+
+// Source is the data source combining documents fetched on their own intervals,
+// every document is mounted under its namespace. A failed fetch keeps the last good
+// value of the document and doesn't block other documents.
+type Source struct {
+	mux    sync.RWMutex
+	docs   []*document
+	client *http.Client
+	logger *slog.Logger
+	now    func() time.Time
+	meter  *metrics.Meter
+
+	counterFetchFailed metrics.Metric
+	histFetchDuration  metrics.Metric
+}
+
+type SourceOpt func(*Source)
+
+func WithLogger(logger *slog.Logger) SourceOpt {
+	return func(s *Source) {
+		s.logger = logger
+	}
+}
+
+func WithHTTPClient(client *http.Client) SourceOpt {
+	return func(s *Source) {
+		s.client = client
+	}
+}
+
+// WithMeter creates fetch metrics by the meter instead of the global meter provider
+func WithMeter(meter *metrics.Meter) SourceOpt {
+	return func(s *Source) {
+		s.meter = meter
+	}
+}
+
+func NewSource(docs []Document, opts ...SourceOpt) (*Source, error) {
+	if err := ValidateDocuments(docs); err != nil {
+		return nil, err
+	}
+
+	s := &Source{
+		client: http.DefaultClient,
+		now:    time.Now,
+	}
+	for _, doc := range docs {
+		s.docs = append(s.docs, &document{Document: doc})
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	if s.logger == nil {
+		s.logger = logging.NewNullLogger()
+	}
+
+	counterFetchFailed, err := s.meter.NewCounter("data_fetch_failed", "A counter of failed data document fetches")
+	if err != nil {
+		return nil, err
+	}
+	s.counterFetchFailed = counterFetchFailed
+
+	histFetchDuration, err := s.meter.NewHistogram("data_fetch_duration", "Duration of data document fetches (seconds)",
+		0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30)
+	if err != nil {
+		return nil, err
+	}
+	s.histFetchDuration = histFetchDuration
+
+	err = s.meter.NewObservableGauges("data_fetch_age_seconds", "Age of the last good value of data documents (seconds)", s.observeAge)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.meter.NewObservableGauges("data_fetch_stale", "1 if the last good value of the data document is older than its maxStaleness", s.observeStale)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Load returns the data with the last good value of every document under its namespace.
+// Documents which haven't been fetched yet are fetched concurrently first,
+// documents without a good value are omitted.
+func (s *Source) Load(ctx context.Context) ([]byte, error) {
+	wg := sync.WaitGroup{}
+	for _, doc := range s.docs {
+		s.mux.RLock()
+		fetched := doc.fetched
+		s.mux.RUnlock()
+
+		if !fetched {
+			wg.Add(1)
+			go func(doc *document) {
+				defer wg.Done()
+				s.fetch(ctx, doc)
+			}(doc)
+		}
+	}
+	wg.Wait()
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	data := map[string]json.RawMessage{}
+	for _, doc := range s.docs {
+		if doc.value != nil {
+			data[doc.Namespace] = doc.value
+		}
+	}
+
+	return json.Marshal(data)
+}
+
+// Subscribe fetches every document on its interval until the context is done,
+// notify is called when a document is changed
+func (s *Source) Subscribe(ctx context.Context, notify func()) error {
+	wg := sync.WaitGroup{}
+	for _, doc := range s.docs {
+		wg.Add(1)
+		go func(doc *document) {
+			defer wg.Done()
+
+			ticker := time.NewTicker(doc.interval())
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+
+				if s.fetch(ctx, doc) {
+					notify()
+				}
+			}
+		}(doc)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// Status returns the state of every document
+func (s *Source) Status() []Status {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	statuses := make([]Status, 0, len(s.docs))
+	for _, doc := range s.docs {
+		s.updateStale(doc)
+		statuses = append(statuses, Status{
+			Namespace: doc.Namespace,
+			Updated:   doc.updated,
+			Err:       doc.err,
+			Stale:     doc.stale,
+		})
+	}
+
+	return statuses
+}
+
+// LastUpdated returns the time of the oldest last good value of the documents,
+// zero if any document hasn't been fetched successfully yet
+func (s *Source) LastUpdated() time.Time {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	var oldest time.Time
+	for i, doc := range s.docs {
+		if doc.updated.IsZero() {
+			return time.Time{}
+		}
+		if i == 0 || doc.updated.Before(oldest) {
+			oldest = doc.updated
+		}
+	}
+
+	return oldest
+}
+
+// observeAge returns ages of documents which have a good value
+func (s *Source) observeAge() []metrics.Observation {
+	observations := []metrics.Observation{}
+	for _, status := range s.Status() {
+		if status.Updated.IsZero() {
+			continue
+		}
+		observations = append(observations, metrics.Observation{
+			Value:  s.now().Sub(status.Updated).Seconds(),
+			Labels: map[string]string{"namespace": status.Namespace},
+		})
+	}
+
+	return observations
+}
+
+// observeStale returns staleness of documents with maxStaleness
+func (s *Source) observeStale() []metrics.Observation {
+	observations := []metrics.Observation{}
+	for i, status := range s.Status() {
+		if s.docs[i].MaxStaleness == 0 {
+			continue
+		}
+		value := 0.0
+		if status.Stale {
+			value = 1
+		}
+		observations = append(observations, metrics.Observation{
+			Value:  value,
+			Labels: map[string]string{"namespace": status.Namespace},
+		})
+	}
+
+	return observations
+}
+
+// fetch updates the document and reports whether its value was changed
+func (s *Source) fetch(ctx context.Context, doc *document) bool {
+	attrs := map[string]string{"namespace": doc.Namespace}
+
+	start := time.Now()
+	content, etag, err := s.read(ctx, doc)
+	s.histFetchDuration.Record(time.Since(start).Seconds(), attrs)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	doc.fetched = true
+	defer s.updateStale(doc)
+
+	if errors.Is(err, errNotModified) {
+		doc.err = nil
+		doc.updated = s.now()
+		return false
+	}
+
+	if err != nil {
+		doc.err = err
+		s.counterFetchFailed.Record(1, attrs)
+		s.logger.Error(fmt.Sprintf("fetching data source %s from %s failed, the last good value is used: %s",
+			doc.Namespace, doc.location(), err.Error()))
+		return false
+	}
+
+	doc.err = nil
+	doc.etag = etag
+	doc.updated = s.now()
+
+	if bytes.Equal(doc.value, content) {
+		return false
+	}
+	doc.value = content
+
+	s.logger.Info(fmt.Sprintf("data source %s updated", doc.Namespace))
+
+	return true
+}
+
+// updateStale marks the document stale if its last good value is too old, it's called under mux
+func (s *Source) updateStale(doc *document) {
+	if doc.MaxStaleness == 0 || !doc.fetched {
+		return
+	}
+
+	stale := doc.updated.IsZero() || s.now().Sub(doc.updated) > doc.MaxStaleness
+	if stale && !doc.stale {
+		s.logger.Warn(fmt.Sprintf("data source %s is stale, last updated at %s", doc.Namespace, doc.updated.Format(time.RFC3339)))
+	}
+	doc.stale = stale
+}
+
+func (s *Source) read(ctx context.Context, doc *document) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, doc.timeout())
+	defer cancel()
+
+	var content []byte
+	var etag string
+	var err error
+
+	if len(doc.File) > 0 {
+		content, err = os.ReadFile(doc.File)
+	} else {
+		s.mux.RLock()
+		etag = doc.etag
+		s.mux.RUnlock()
+
+		content, etag, err = s.get(ctx, doc.URL, etag)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !json.Valid(content) {
+		return nil, "", errors.New(errInvalidJSON)
+	}
+
+	return content, etag, nil
+}
+
+func (s *Source) get(ctx context.Context, url, etag string) ([]byte, string, error) {
+	rq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(etag) > 0 {
+		rq.Header.Set("If-None-Match", etag)
+	}
+
+	rsp, err := s.client.Do(rq)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rsp.Body.Close()
+
+	switch rsp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, "", errNotModified
+	default:
+		return nil, "", fmt.Errorf("unexpected response status %d", rsp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(rsp.Body, maxDocumentSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(body) > maxDocumentSize {
+		return nil, "", errors.New(errDocumentTooLarge)
+	}
+
+	return body, rsp.Header.Get("ETag"), nil
+}
@@ -0,0 +1,232 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goauthlink/authlink/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+type testServer struct {
+	mux      sync.Mutex
+	content  string
+	fail     bool
+	requests atomic.Int32
+}
+
+func (ts *testServer) set(content string, fail bool) {
+	ts.mux.Lock()
+	defer ts.mux.Unlock()
+
+	ts.content = content
+	ts.fail = fail
+}
+
+func (ts *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ts.mux.Lock()
+	defer ts.mux.Unlock()
+
+	ts.requests.Add(1)
+
+	if ts.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	etag := `"` + ts.content + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Write([]byte(ts.content)) //nolint: errcheck
+}
+
+func Test_SourceLoad(t *testing.T) {
+	ts := &testServer{content: `{"teams":["team1"]}`}
+	srv := httptest.NewServer(ts)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "crm.json"), []byte(`{"accounts":["account1"]}`), 0o600))
+
+	source, err := NewSource([]Document{
+		{Namespace: "hr", URL: srv.URL},
+		{Namespace: "crm", File: filepath.Join(dir, "crm.json")},
+		{Namespace: "broken", File: filepath.Join(dir, "unknown.json")},
+	})
+	require.NoError(t, err)
+
+	data, err := source.Load(context.Background())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"hr":{"teams":["team1"]},"crm":{"accounts":["account1"]}}`, string(data))
+
+	statuses := source.Status()
+	require.Len(t, statuses, 3)
+	assert.False(t, statuses[0].Updated.IsZero())
+	assert.NoError(t, statuses[0].Err)
+	assert.Error(t, statuses[2].Err)
+	assert.True(t, statuses[2].Updated.IsZero())
+	assert.True(t, source.LastUpdated().IsZero())
+
+	// fetched documents aren't fetched again by loading
+	_, err = source.Load(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), ts.requests.Load())
+}
+
+func Test_SourceSubscribe(t *testing.T) {
+	hr := &testServer{content: `{"teams":["team1"]}`}
+	hrSrv := httptest.NewServer(hr)
+	defer hrSrv.Close()
+
+	crm := &testServer{content: `{"accounts":["account1"]}`}
+	crmSrv := httptest.NewServer(crm)
+	defer crmSrv.Close()
+
+	source, err := NewSource([]Document{
+		{Namespace: "hr", URL: hrSrv.URL, Interval: 10 * time.Millisecond},
+		{Namespace: "crm", URL: crmSrv.URL, Interval: 10 * time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	_, err = source.Load(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	notified := atomic.Int32{}
+	done := make(chan error)
+	go func() {
+		done <- source.Subscribe(ctx, func() { notified.Add(1) })
+	}()
+	defer func() {
+		cancel()
+		require.NoError(t, <-done)
+	}()
+
+	// unchanged documents don't notify
+	requests := hr.requests.Load()
+	assert.Eventually(t, func() bool { return hr.requests.Load() > requests+2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(0), notified.Load())
+
+	// the failed document keeps the last good value, the other one is updated
+	hr.set(`{"teams":["team2"]}`, true)
+	crm.set(`{"accounts":["account2"]}`, false)
+
+	assert.Eventually(t, func() bool { return notified.Load() > 0 }, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return source.Status()[0].Err != nil }, time.Second, 5*time.Millisecond)
+
+	data, err := source.Load(context.Background())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"hr":{"teams":["team1"]},"crm":{"accounts":["account2"]}}`, string(data))
+
+	// invalid json is rejected
+	crm.set(`{`, false)
+	assert.Eventually(t, func() bool { return source.Status()[1].Err != nil }, time.Second, 5*time.Millisecond)
+	assert.EqualError(t, source.Status()[1].Err, errInvalidJSON)
+
+	hr.set(`{"teams":["team2"]}`, false)
+	assert.Eventually(t, func() bool {
+		data, err := source.Load(context.Background())
+		return err == nil && string(data) == `{"crm":{"accounts":["account2"]},"hr":{"teams":["team2"]}}`
+	}, time.Second, 5*time.Millisecond)
+}
+
+func Test_SourceStaleness(t *testing.T) {
+	ts := &testServer{content: `{"teams":["team1"]}`}
+	srv := httptest.NewServer(ts)
+	defer srv.Close()
+
+	source, err := NewSource([]Document{
+		{Namespace: "hr", URL: srv.URL, Interval: time.Minute, MaxStaleness: 10 * time.Minute},
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	source.now = func() time.Time { return now }
+
+	_, err = source.Load(context.Background())
+	require.NoError(t, err)
+	assert.False(t, source.Status()[0].Stale)
+	assert.Equal(t, now, source.LastUpdated())
+
+	ts.set("", true)
+	now = now.Add(11 * time.Minute)
+	assert.False(t, source.fetch(context.Background(), source.docs[0]))
+
+	status := source.Status()[0]
+	assert.True(t, status.Stale)
+	assert.Error(t, status.Err)
+
+	// not modified response refreshes the document
+	ts.set(`{"teams":["team1"]}`, false)
+	assert.False(t, source.fetch(context.Background(), source.docs[0]))
+
+	status = source.Status()[0]
+	assert.False(t, status.Stale)
+	assert.NoError(t, status.Err)
+	assert.Equal(t, now, status.Updated)
+}
+
+func Test_SourceStatusMetrics(t *testing.T) {
+	ts := &testServer{content: `{"teams":["team1"]}`}
+	srv := httptest.NewServer(ts)
+	defer srv.Close()
+
+	reader := metric.NewManualReader()
+	source, err := NewSource([]Document{
+		{Namespace: "hr", URL: srv.URL, Interval: time.Minute, MaxStaleness: 10 * time.Minute},
+		{Namespace: "teams", URL: srv.URL + "/unavailable", Interval: time.Minute},
+	}, WithMeter(metrics.NewMeter(metric.NewMeterProvider(metric.WithReader(reader)))))
+	require.NoError(t, err)
+
+	now := time.Now()
+	source.now = func() time.Time { return now }
+
+	source.fetch(context.Background(), source.docs[0])
+
+	gauges := func(name string) map[string]float64 {
+		rm := metricdata.ResourceMetrics{}
+		require.NoError(t, reader.Collect(context.Background(), &rm))
+
+		values := map[string]float64{}
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				if m.Name != name {
+					continue
+				}
+				for _, dp := range m.Data.(metricdata.Gauge[float64]).DataPoints {
+					namespace, _ := dp.Attributes.Value("namespace")
+					values[namespace.AsString()] = dp.Value
+				}
+			}
+		}
+
+		return values
+	}
+
+	// documents without a good value have no age, documents without maxStaleness are never stale
+	now = now.Add(5 * time.Minute)
+	assert.Equal(t, map[string]float64{"hr": 300}, gauges("data_fetch_age_seconds"))
+	assert.Equal(t, map[string]float64{"hr": 0}, gauges("data_fetch_stale"))
+
+	now = now.Add(6 * time.Minute)
+	assert.Equal(t, map[string]float64{"hr": 660}, gauges("data_fetch_age_seconds"))
+	assert.Equal(t, map[string]float64{"hr": 1}, gauges("data_fetch_stale"))
+}
@@ -0,0 +1,340 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goauthlink/authlink/pkg/logging"
+)
+
+const (
+	DefaultBranch     = "main"
+	DefaultPolicyPath = "policy.yaml"
+
+	// SignatureHeader carries HMAC-SHA256 signature of the webhook body
+	SignatureHeader = "X-Hub-Signature-256"
+
+	maxWebhookBodySize = 1 << 20
+)
+
+const (
+	errRepoIsRequired   = "git repository is required"
+	errInvalidSignature = "invalid webhook signature"
+	errFileNotFound     = "file %s not found in revision %s"
+)
+
+// ApplyFunc activates policy and data of the revision, data is nil if the data path isn't set
+type ApplyFunc func(revision string, policy, data []byte) error
+
+// Source tracks a branch of a git repository and applies the policy and data
+// at the configured paths when the branch head changes. The repository may be
+// a local path or a remote reachable over any transport of the git binary, e.g. file or ssh.
+type Source struct {
+	repo       string
+	branch     string
+	policyPath string
+	dataPath   string
+	dir        string
+	// tempDir is set if the directory was created by the source
+	tempDir  bool
+	interval time.Duration
+	logger   *slog.Logger
+	confirm  func()
+
+	// syncMux serializes syncs of the polling loop and webhooks
+	syncMux  sync.Mutex
+	revision string
+	// failedRevision isn't applied again until the head moves
+	failedRevision string
+	trigger        chan struct{}
+}
+
+type SourceOpt func(*Source)
+
+func WithLogger(logger *slog.Logger) SourceOpt {
+	return func(s *Source) {
+		s.logger = logger
+	}
+}
+
+// WithBranch sets the tracked branch, DefaultBranch by default
+func WithBranch(branch string) SourceOpt {
+	return func(s *Source) {
+		s.branch = branch
+	}
+}
+
+// WithPolicyPath sets path of the policy file in the repository, DefaultPolicyPath by default
+func WithPolicyPath(path string) SourceOpt {
+	return func(s *Source) {
+		s.policyPath = path
+	}
+}
+
+// WithDataPath sets path of the data file in the repository, the data isn't loaded by default
+func WithDataPath(path string) SourceOpt {
+	return func(s *Source) {
+		s.dataPath = path
+	}
+}
+
+// WithDir sets the directory of the local clone, a temporary directory is used by default
+func WithDir(dir string) SourceOpt {
+	return func(s *Source) {
+		s.dir = dir
+	}
+}
+
+// WithConfirmFunc sets the function called when a sync finds the applied revision is still the head
+func WithConfirmFunc(confirm func()) SourceOpt {
+	return func(s *Source) {
+		s.confirm = confirm
+	}
+}
+
+func NewSource(repo string, interval time.Duration, opts ...SourceOpt) (*Source, error) {
+	if len(repo) == 0 {
+		return nil, errors.New(errRepoIsRequired)
+	}
+
+	s := &Source{
+		repo:       repo,
+		branch:     DefaultBranch,
+		policyPath: DefaultPolicyPath,
+		interval:   interval,
+		trigger:    make(chan struct{}, 1),
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	if s.logger == nil {
+		s.logger = logging.NewNullLogger()
+	}
+
+	if len(s.dir) == 0 {
+		dir, err := os.MkdirTemp("", "authlink-git-")
+		if err != nil {
+			return nil, fmt.Errorf("create git directory: %w", err)
+		}
+		s.dir = dir
+		s.tempDir = true
+	} else if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create git directory: %w", err)
+	}
+
+	return s, nil
+}
+
+// Close removes the local clone if it's in the temporary directory
+func (s *Source) Close() error {
+	if !s.tempDir {
+		return nil
+	}
+
+	s.syncMux.Lock()
+	defer s.syncMux.Unlock()
+
+	return os.RemoveAll(s.dir)
+}
+
+// Revision returns the commit sha of the applied revision, empty if nothing was applied
+func (s *Source) Revision() string {
+	s.syncMux.Lock()
+	defer s.syncMux.Unlock()
+
+	return s.revision
+}
+
+// Sync fetches the branch and applies the policy and data of its head if the head
+// has changed since the last applied revision. It reports whether a revision was applied.
+func (s *Source) Sync(ctx context.Context, apply ApplyFunc) (bool, error) {
+	s.syncMux.Lock()
+	defer s.syncMux.Unlock()
+
+	if err := s.fetch(ctx); err != nil {
+		return false, err
+	}
+
+	head, err := s.git(ctx, "rev-parse", "--verify", "refs/heads/"+s.branch+"^{commit}")
+	if err != nil {
+		return false, fmt.Errorf("resolve branch %s: %w", s.branch, err)
+	}
+	revision := strings.TrimSpace(string(head))
+
+	if revision == s.revision {
+		if s.confirm != nil {
+			s.confirm()
+		}
+		return false, nil
+	}
+	if revision == s.failedRevision {
+		s.logger.Debug(fmt.Sprintf("git revision %s failed to apply, waiting for a new one", revision))
+		return false, nil
+	}
+
+	if err := s.apply(ctx, revision, apply); err != nil {
+		if ctx.Err() == nil {
+			s.failedRevision = revision
+		}
+		return false, err
+	}
+	s.revision = revision
+	s.failedRevision = ""
+
+	s.logger.Info(fmt.Sprintf("git revision %s applied", revision))
+
+	return true, nil
+}
+
+// apply reads the policy and data of the revision and applies them
+func (s *Source) apply(ctx context.Context, revision string, apply ApplyFunc) error {
+	policy, err := s.show(ctx, revision, s.policyPath)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if len(s.dataPath) > 0 {
+		data, err = s.show(ctx, revision, s.dataPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := apply(revision, policy, data); err != nil {
+		return fmt.Errorf("apply git revision %s: %w", revision, err)
+	}
+
+	return nil
+}
+
+// Trigger schedules a sync, triggers are merged until the sync starts
+func (s *Source) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run syncs the repository every interval and on triggers until the context is done
+func (s *Source) Run(ctx context.Context, apply ApplyFunc) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.trigger:
+		}
+
+		if _, err := s.Sync(ctx, apply); err != nil {
+			s.logger.Error(fmt.Sprintf("syncing git repository failed: %s", err.Error()))
+		}
+	}
+}
+
+// WebhookHandler triggers a sync on push notifications. The body must be signed with the secret
+// as GitHub does: X-Hub-Signature-256 header with sha256=<hex hmac>, nothing is accepted
+// with an empty secret.
+func (s *Source) WebhookHandler(secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if len(secret) == 0 || !validSignature(secret, body, r.Header.Get(SignatureHeader)) {
+			http.Error(w, errInvalidSignature, http.StatusUnauthorized)
+			return
+		}
+
+		s.Trigger()
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+func validSignature(secret string, body []byte, header string) bool {
+	signature, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// fetch updates the local bare clone, the repository is cloned on the first fetch
+func (s *Source) fetch(ctx context.Context) error {
+	if _, err := os.Stat(s.dir + "/HEAD"); err != nil {
+		if _, err := s.git(ctx, "init", "--bare", "--quiet"); err != nil {
+			return fmt.Errorf("init git directory: %w", err)
+		}
+	}
+
+	refspec := fmt.Sprintf("+refs/heads/%s:refs/heads/%s", s.branch, s.branch)
+	if _, err := s.git(ctx, "fetch", "--quiet", "--no-tags", "--", s.repo, refspec); err != nil {
+		return fmt.Errorf("fetch git repository %s: %w", s.repo, err)
+	}
+
+	return nil
+}
+
+func (s *Source) show(ctx context.Context, revision, path string) ([]byte, error) {
+	if _, err := s.git(ctx, "cat-file", "-e", revision+":"+path); err != nil {
+		return nil, fmt.Errorf(errFileNotFound, path, revision)
+	}
+
+	content, err := s.git(ctx, "cat-file", "blob", revision+":"+path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s of revision %s: %w", path, revision, err)
+	}
+
+	return content, nil
+}
+
+func (s *Source) git(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = s.dir
+	// remote transports must not ask for credentials interactively
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,243 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package git
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRepo is a local bare repository with a working clone pushing to it
+type testRepo struct {
+	t      *testing.T
+	remote string
+	work   string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	dir := t.TempDir()
+	r := &testRepo{
+		t:      t,
+		remote: filepath.Join(dir, "remote.git"),
+		work:   filepath.Join(dir, "work"),
+	}
+
+	r.run(dir, "init", "--bare", "--quiet", "--initial-branch=main", r.remote)
+	r.run(dir, "init", "--quiet", "--initial-branch=main", r.work)
+	r.run(r.work, "config", "user.email", "test@authlink.io")
+	r.run(r.work, "config", "user.name", "test")
+
+	return r
+}
+
+func (r *testRepo) run(dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(r.t, err, string(out))
+
+	return strings.TrimSpace(string(out))
+}
+
+// commit commits the files to the branch and pushes it, it returns sha of the commit
+func (r *testRepo) commit(branch string, files map[string]string) string {
+	r.run(r.work, "checkout", "--quiet", "-B", branch)
+	for name, content := range files {
+		require.NoError(r.t, os.MkdirAll(filepath.Dir(filepath.Join(r.work, name)), 0o755))
+		require.NoError(r.t, os.WriteFile(filepath.Join(r.work, name), []byte(content), 0o600))
+		r.run(r.work, "add", name)
+	}
+	r.run(r.work, "commit", "--quiet", "-m", "update")
+	r.run(r.work, "push", "--quiet", "--force", r.remote, branch)
+
+	return r.run(r.work, "rev-parse", "HEAD")
+}
+
+type applied struct {
+	revision string
+	policy   string
+	data     string
+}
+
+func Test_Sync(t *testing.T) {
+	repo := newTestRepo(t)
+	rev1 := repo.commit("main", map[string]string{"policy.yaml": "policy1", "data.json": `{"users":["user1"]}`})
+
+	confirmed := 0
+	source, err := NewSource("file://"+repo.remote, time.Minute, WithDataPath("data.json"), WithDir(t.TempDir()),
+		WithConfirmFunc(func() { confirmed++ }))
+	require.NoError(t, err)
+
+	var last applied
+	apply := func(revision string, policy, data []byte) error {
+		last = applied{revision: revision, policy: string(policy), data: string(data)}
+		return nil
+	}
+
+	updated, err := source.Sync(context.Background(), apply)
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, applied{revision: rev1, policy: "policy1", data: `{"users":["user1"]}`}, last)
+	assert.Equal(t, rev1, source.Revision())
+
+	assert.Equal(t, 0, confirmed)
+
+	// unchanged head isn't applied again, but confirms the applied revision
+	updated, err = source.Sync(context.Background(), apply)
+	require.NoError(t, err)
+	assert.False(t, updated)
+	assert.Equal(t, 1, confirmed)
+
+	// a failed revision isn't recorded as applied
+	repo.commit("main", map[string]string{"policy.yaml": "policy2"})
+	_, err = source.Sync(context.Background(), func(string, []byte, []byte) error {
+		return errors.New("invalid policy")
+	})
+	require.ErrorContains(t, err, "invalid policy")
+	assert.Equal(t, rev1, source.Revision())
+	assert.Equal(t, 1, confirmed)
+
+	// the failed revision isn't applied again and doesn't confirm the applied one
+	updated, err = source.Sync(context.Background(), apply)
+	require.NoError(t, err)
+	assert.False(t, updated)
+	assert.Equal(t, rev1, source.Revision())
+	assert.Equal(t, 1, confirmed)
+
+	rev3 := repo.commit("main", map[string]string{"policy.yaml": "policy3"})
+	updated, err = source.Sync(context.Background(), apply)
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, applied{revision: rev3, policy: "policy3", data: `{"users":["user1"]}`}, last)
+
+	// other branches aren't tracked
+	repo.commit("feature", map[string]string{"policy.yaml": "policy4"})
+	updated, err = source.Sync(context.Background(), apply)
+	require.NoError(t, err)
+	assert.False(t, updated)
+}
+
+func Test_SyncLocalPathAndBranch(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("main", map[string]string{"policy.yaml": "policy1"})
+	rev := repo.commit("prod", map[string]string{"policies/prod.yaml": "prod"})
+
+	source, err := NewSource(repo.remote, time.Minute, WithBranch("prod"), WithPolicyPath("policies/prod.yaml"))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, source.Close())
+		assert.NoDirExists(t, source.dir)
+	}()
+
+	var last applied
+	_, err = source.Sync(context.Background(), func(revision string, policy, data []byte) error {
+		last = applied{revision: revision, policy: string(policy), data: string(data)}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, applied{revision: rev, policy: "prod"}, last)
+}
+
+func Test_SyncErrors(t *testing.T) {
+	repo := newTestRepo(t)
+	rev := repo.commit("main", map[string]string{"policy.yaml": "policy1"})
+
+	apply := func(string, []byte, []byte) error { return nil }
+
+	source, err := NewSource(repo.remote, time.Minute, WithDataPath("data.json"), WithDir(t.TempDir()))
+	require.NoError(t, err)
+	_, err = source.Sync(context.Background(), apply)
+	require.EqualError(t, err, "file data.json not found in revision "+rev)
+
+	source, err = NewSource(repo.remote, time.Minute, WithBranch("unknown"), WithDir(t.TempDir()))
+	require.NoError(t, err)
+	_, err = source.Sync(context.Background(), apply)
+	require.ErrorContains(t, err, "fetch git repository")
+
+	_, err = NewSource("", time.Minute)
+	require.EqualError(t, err, errRepoIsRequired)
+}
+
+func Test_Webhook(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("main", map[string]string{"policy.yaml": "policy1"})
+
+	source, err := NewSource(repo.remote, time.Hour, WithDir(t.TempDir()))
+	require.NoError(t, err)
+
+	revisions := make(chan string, 10)
+	apply := func(revision string, policy, data []byte) error {
+		revisions <- revision
+		return nil
+	}
+
+	_, err = source.Sync(context.Background(), apply)
+	require.NoError(t, err)
+	<-revisions
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		source.Run(ctx, apply)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	srv := httptest.NewServer(source.WebhookHandler("secret"))
+	defer srv.Close()
+
+	body := `{"ref":"refs/heads/main"}`
+
+	rsp, err := http.Post(srv.URL, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	rsp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, rsp.StatusCode)
+
+	// nothing is accepted without a secret
+	unsigned := httptest.NewServer(source.WebhookHandler(""))
+	defer unsigned.Close()
+
+	rsp, err = http.Post(unsigned.URL, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	rsp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, rsp.StatusCode)
+
+	rev2 := repo.commit("main", map[string]string{"policy.yaml": "policy2"})
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(body))
+	rq, err := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(body))
+	require.NoError(t, err)
+	rq.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	rsp, err = http.DefaultClient.Do(rq)
+	require.NoError(t, err)
+	rsp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, rsp.StatusCode)
+
+	select {
+	case revision := <-revisions:
+		assert.Equal(t, rev2, revision)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook didn't trigger sync")
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	return strings.TrimSpace(string(out))
+}
+
+// commitPolicies pushes policy and data to the branch of the bare repository, it returns sha of the commit
+func commitPolicies(t *testing.T, remote, work, policy, data string) string {
+	require.NoError(t, os.MkdirAll(filepath.Join(work, "authz"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(work, "authz", "policy.yaml"), []byte(policy), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(work, "authz", "data.json"), []byte(data), 0o600))
+	runGit(t, work, "add", ".")
+	runGit(t, work, "commit", "--quiet", "-m", "update")
+	runGit(t, work, "push", "--quiet", remote, "main")
+
+	return runGit(t, work, "rev-parse", "HEAD")
+}
+
+func Test_InitGit(t *testing.T) {
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote.git")
+	work := filepath.Join(dir, "work")
+
+	runGit(t, dir, "init", "--bare", "--quiet", "--initial-branch=main", remote)
+	runGit(t, dir, "init", "--quiet", "--initial-branch=main", work)
+	runGit(t, work, "config", "user.email", "test@authlink.io")
+	runGit(t, work, "config", "user.name", "test")
+
+	rev1 := commitPolicies(t, remote, work, testPolicy, testData)
+
+	config := DefaultConfig()
+	config.LogLevel = slog.LevelError
+	config.HttpAddr = "127.0.0.1:0"
+	config.MonitoringAddr = "127.0.0.1:0"
+	config.PolicyFilePath = ""
+	config.GitRepo = "file://" + remote
+	config.GitPolicyPath = "authz/policy.yaml"
+	config.GitDataPath = "authz/data.json"
+	config.GitDir = filepath.Join(dir, "clone")
+
+	agent, err := Init(config)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(testPolicy), agent.policy.Policy())
+	assert.Equal(t, map[string]interface{}{"users": []interface{}{"user1", "user2"}}, agent.policy.Data())
+	assert.Equal(t, rev1, agent.Revision())
+
+	stop := make(chan struct{}, 1)
+	go func() {
+		agent.Run(stop) //nolint: errcheck
+	}()
+	defer func() {
+		stop <- struct{}{}
+		agent.WaitUntilCompletion()
+	}()
+
+	// a push applied on the webhook trigger
+	rev2 := commitPolicies(t, remote, work, testPolicy, `{"users":["user3"]}`)
+	agent.gitSource.Trigger()
+
+	assert.Eventually(t, func() bool { return agent.Revision() == rev2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, map[string]interface{}{"users": []interface{}{"user3"}}, agent.policy.Data())
+
+	// unknown path of the policy
+	config.GitPolicyPath = "unknown.yaml"
+	config.GitDir = filepath.Join(dir, "clone2")
+	_, err = Init(config)
+	require.ErrorContains(t, err, "file unknown.yaml not found")
+}
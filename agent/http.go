@@ -13,6 +13,7 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/goauthlink/authlink/pkg/logging"
@@ -25,6 +26,8 @@ type HttpServer struct {
 	cert       *tls.Certificate
 	logger     *slog.Logger
 	policy     *Policy
+	meter      *metrics.Meter
+	listening  atomic.Bool
 }
 
 type ServerOpt func(*HttpServer)
@@ -41,6 +44,13 @@ func WithLogger(logger *slog.Logger) ServerOpt {
 	}
 }
 
+// WithServerMeter creates metrics of requests by the meter instead of the global meter provider
+func WithServerMeter(meter *metrics.Meter) ServerOpt {
+	return func(s *HttpServer) {
+		s.meter = meter
+	}
+}
+
 func NewHttpServer(addr string, policy *Policy, opts ...ServerOpt) (*HttpServer, error) {
 	httpSrv := &HttpServer{
 		httpserver: &http.Server{
@@ -64,7 +74,7 @@ func NewHttpServer(addr string, policy *Policy, opts ...ServerOpt) (*HttpServer,
 	router := http.NewServeMux()
 	router.Handle("POST /check", routerPostCheckHandler(httpSrv.policy, httpSrv.logger))
 
-	metricsMiddleware, err := metrics.NewHTTPMiddleware(router)
+	metricsMiddleware, err := httpSrv.meter.NewHTTPMiddleware(router, metrics.WithServerLabel("http"))
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +102,9 @@ func (srv *HttpServer) Start(_ context.Context) error {
 	}
 	defer listener.Close()
 
+	srv.listening.Store(true)
+	defer srv.listening.Store(false)
+
 	err = srv.httpserver.Serve(listener)
 	if err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("https server listening: %w", err)
@@ -100,6 +113,11 @@ func (srv *HttpServer) Start(_ context.Context) error {
 	return nil
 }
 
+// Listening reports whether the server accepts connections
+func (srv *HttpServer) Listening() bool {
+	return srv.listening.Load()
+}
+
 func (httpServer *HttpServer) Shutdown(ctx context.Context) error {
 	ctxd, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
@@ -126,7 +144,7 @@ func routerPostCheckHandler(policy *Policy, logger *slog.Logger) http.Handler {
 			in.Headers[strings.ToLower(key)] = strings.Join(headerVal, ",")
 		}
 
-		result, err := policy.Check(context.Background(), in)
+		result, err := policy.Check(r.Context(), in)
 		if err != nil {
 			logger.Error(fmt.Sprintf("http check handler: %s", err.Error()))
 			w.WriteHeader(http.StatusInternalServerError)
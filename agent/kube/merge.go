@@ -0,0 +1,193 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package kube
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/goauthlink/authlink/sdk/policy"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	errObjectIsEmpty      = "object contains neither " + PolicyKey + " nor " + DataKey
+	errNamespaceNoPolicy  = "namespace doesn't contain a policy"
+	errCnConflict         = "cn conflicts with %s"
+	errDefaultConflict    = "default conflicts with %s"
+	errVariableConflict   = "variable %s conflicts with %s"
+	errDataKeyConflict    = "data key %s conflicts with %s"
+	errDataIsNotAnObject  = "data must be a json object"
+	errPolicyParseFailure = "parse policy: %s"
+	errDataParseFailure   = "parse data: %s"
+)
+
+// merged is a policy and data of all valid objects of a namespace
+type merged struct {
+	policy []byte
+	data   []byte
+	// applied are keys of the objects which are merged
+	applied []objectKey
+	// invalid are objects excluded from the merge with the reason
+	invalid map[objectKey]error
+}
+
+type parsedObject struct {
+	key    objectKey
+	config *policy.Config
+	data   map[string]interface{}
+}
+
+// mergeObjects merges objects ordered by name, so the result doesn't depend on the events order.
+// Policies are concatenated, cn and default must be the same in all objects defining them,
+// variables and top-level data keys must not be redefined with a different value.
+func mergeObjects(objects []*object) (*merged, error) {
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].key.less(objects[j].key)
+	})
+
+	result := &merged{invalid: map[objectKey]error{}}
+
+	var (
+		config      policy.Config
+		data        map[string]interface{}
+		hasPolicy   bool
+		cnFrom      string
+		defaultFrom string
+		varsFrom    = map[string]string{}
+		dataFrom    = map[string]string{}
+	)
+
+	for _, obj := range objects {
+		parsed, err := parseObject(obj)
+		if err != nil {
+			result.invalid[obj.key] = err
+			continue
+		}
+
+		if err := checkConflicts(parsed, &config, data, cnFrom, defaultFrom, varsFrom, dataFrom); err != nil {
+			result.invalid[obj.key] = err
+			continue
+		}
+
+		source := obj.key.String()
+
+		if parsed.config != nil {
+			hasPolicy = true
+
+			if len(parsed.config.Cn) > 0 && len(cnFrom) == 0 {
+				config.Cn = parsed.config.Cn
+				cnFrom = source
+			}
+			if !parsed.config.Default.IsZero() && len(defaultFrom) == 0 {
+				config.Default = parsed.config.Default
+				defaultFrom = source
+			}
+			for name, values := range parsed.config.Vars {
+				if config.Vars == nil {
+					config.Vars = policy.Variables{}
+				}
+				if _, ok := varsFrom[name]; !ok {
+					config.Vars[name] = values
+					varsFrom[name] = source
+				}
+			}
+			config.Policies = append(config.Policies, parsed.config.Policies...)
+		}
+
+		for k, v := range parsed.data {
+			if data == nil {
+				data = map[string]interface{}{}
+			}
+			if _, ok := dataFrom[k]; !ok {
+				data[k] = v
+				dataFrom[k] = source
+			}
+		}
+
+		result.applied = append(result.applied, obj.key)
+	}
+
+	if !hasPolicy {
+		return result, errors.New(errNamespaceNoPolicy)
+	}
+
+	policyData, err := yaml.Marshal(config)
+	if err != nil {
+		return result, fmt.Errorf("marshal merged policy: %w", err)
+	}
+	result.policy = policyData
+
+	if data != nil {
+		result.data, err = json.Marshal(data)
+		if err != nil {
+			return result, fmt.Errorf("marshal merged data: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+func parseObject(obj *object) (*parsedObject, error) {
+	if len(obj.policy) == 0 && len(obj.data) == 0 {
+		return nil, errors.New(errObjectIsEmpty)
+	}
+
+	parsed := &parsedObject{key: obj.key}
+
+	if len(obj.policy) > 0 {
+		parsed.config = &policy.Config{}
+		if err := yaml.Unmarshal(obj.policy, parsed.config); err != nil {
+			return nil, fmt.Errorf(errPolicyParseFailure, err.Error())
+		}
+	}
+
+	if len(obj.data) > 0 {
+		var data interface{}
+		if err := json.Unmarshal(obj.data, &data); err != nil {
+			return nil, fmt.Errorf(errDataParseFailure, err.Error())
+		}
+		dataMap, ok := data.(map[string]interface{})
+		if !ok {
+			return nil, errors.New(errDataIsNotAnObject)
+		}
+		parsed.data = dataMap
+	}
+
+	return parsed, nil
+}
+
+func checkConflicts(
+	parsed *parsedObject,
+	config *policy.Config,
+	data map[string]interface{},
+	cnFrom, defaultFrom string,
+	varsFrom, dataFrom map[string]string,
+) error {
+	if parsed.config != nil {
+		if len(parsed.config.Cn) > 0 && len(cnFrom) > 0 && !reflect.DeepEqual(parsed.config.Cn, config.Cn) {
+			return fmt.Errorf(errCnConflict, cnFrom)
+		}
+		if !parsed.config.Default.IsZero() && len(defaultFrom) > 0 && !reflect.DeepEqual(parsed.config.Default, config.Default) {
+			return fmt.Errorf(errDefaultConflict, defaultFrom)
+		}
+		for name, values := range parsed.config.Vars {
+			if from, ok := varsFrom[name]; ok && !reflect.DeepEqual(values, config.Vars[name]) {
+				return fmt.Errorf(errVariableConflict, name, from)
+			}
+		}
+	}
+
+	for k, v := range parsed.data {
+		if from, ok := dataFrom[k]; ok && !reflect.DeepEqual(v, data[k]) {
+			return fmt.Errorf(errDataKeyConflict, k, from)
+		}
+	}
+
+	return nil
+}
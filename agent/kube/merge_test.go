@@ -0,0 +1,158 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package kube
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestObject(name, policy, data string) *object {
+	return &object{
+		key:    objectKey{kind: KindConfigMap, namespace: "ns", name: name},
+		policy: []byte(policy),
+		data:   []byte(data),
+	}
+}
+
+func Test_MergeObjects(t *testing.T) {
+	tests := []struct {
+		name    string
+		objects []*object
+		policy  string
+		data    string
+		invalid map[string]string
+		err     error
+	}{
+		{
+			name: "merged in name order",
+			objects: []*object{
+				newTestObject("b", `policies:
+  - uri: ["/b"]
+    allow: ["client-b"]`, `{"b":1}`),
+				newTestObject("a", `cn:
+  - header: x-source
+default: ["admin"]
+vars:
+  admins: ["admin"]
+policies:
+  - uri: ["/a"]
+    allow: ["client-a"]`, `{"a":1}`),
+			},
+			policy: `cn:
+    - prefix: ""
+      header: x-source
+vars:
+    admins:
+        - admin
+default:
+    - admin
+policies:
+    - uri:
+        - /a
+      method: []
+      allow:
+        - client-a
+    - uri:
+        - /b
+      method: []
+      allow:
+        - client-b
+`,
+			data:    `{"a":1,"b":1}`,
+			invalid: map[string]string{},
+		},
+		{
+			name: "the same values aren't conflicts",
+			objects: []*object{
+				newTestObject("a", `vars:
+  admins: ["admin"]
+default: ["admin"]`, `{"a":1}`),
+				newTestObject("b", `vars:
+  admins: ["admin"]
+default: ["admin"]`, `{"a":1}`),
+			},
+			policy: `cn: []
+vars:
+    admins:
+        - admin
+default:
+    - admin
+policies: []
+`,
+			data:    `{"a":1}`,
+			invalid: map[string]string{},
+		},
+		{
+			name: "conflicts",
+			objects: []*object{
+				newTestObject("a", `cn:
+  - header: x-source
+vars:
+  admins: ["admin"]
+default: ["admin"]`, `{"a":1}`),
+				newTestObject("b", `cn:
+  - header: x-source2`, ""),
+				newTestObject("c", `default: ["user"]`, ""),
+				newTestObject("d", `vars:
+  admins: ["user"]`, ""),
+				newTestObject("e", "", `{"a":2}`),
+				newTestObject("f", "", `[1]`),
+				newTestObject("g", "", ""),
+				newTestObject("h", "policies: {", ""),
+			},
+			policy: `cn:
+    - prefix: ""
+      header: x-source
+vars:
+    admins:
+        - admin
+default:
+    - admin
+policies: []
+`,
+			data: `{"a":1}`,
+			invalid: map[string]string{
+				"b": "cn conflicts with ConfigMap ns/a",
+				"c": "default conflicts with ConfigMap ns/a",
+				"d": "variable admins conflicts with ConfigMap ns/a",
+				"e": "data key a conflicts with ConfigMap ns/a",
+				"f": errDataIsNotAnObject,
+				"g": errObjectIsEmpty,
+				"h": "parse policy: yaml: line 1: did not find expected node content",
+			},
+		},
+		{
+			name: "data only",
+			objects: []*object{
+				newTestObject("a", "", `{"a":1}`),
+			},
+			err:     errors.New(errNamespaceNoPolicy),
+			invalid: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := mergeObjects(tt.objects)
+			if tt.err != nil {
+				require.EqualError(t, err, tt.err.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.policy, string(result.policy))
+				assert.Equal(t, tt.data, string(result.data))
+			}
+
+			invalid := map[string]string{}
+			for key, err := range result.invalid {
+				invalid[key.name] = err.Error()
+			}
+			assert.Equal(t, tt.invalid, invalid)
+		})
+	}
+}
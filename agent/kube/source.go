@@ -0,0 +1,550 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package kube
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/goauthlink/authlink/pkg/logging"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// PolicyKey and DataKey are keys of ConfigMap and Secret data
+	PolicyKey = "policy.yaml"
+	DataKey   = "data.json"
+
+	// DefaultLabelSelector selects ConfigMaps and Secrets containing policies
+	DefaultLabelSelector = "authlink.io/policy=true"
+
+	// StatusAnnotation and ErrorAnnotation report the status on ConfigMaps and Secrets
+	StatusAnnotation = "authlink.io/status"
+	ErrorAnnotation  = "authlink.io/error"
+)
+
+// Statuses reported back on the objects
+const (
+	StatusApplied = "Applied"
+	StatusInvalid = "Invalid"
+	StatusFailed  = "Failed"
+)
+
+// AuthPolicyResource is the AuthPolicy custom resource, its spec contains
+// the policy as a yaml string and optional data as a json object
+var AuthPolicyResource = schema.GroupVersionResource{
+	Group:    "authlink.io",
+	Version:  "v1alpha1",
+	Resource: "authpolicies",
+}
+
+type Kind string
+
+const (
+	KindConfigMap  Kind = "ConfigMap"
+	KindSecret     Kind = "Secret"
+	KindAuthPolicy Kind = "AuthPolicy"
+)
+
+type objectKey struct {
+	kind      Kind
+	namespace string
+	name      string
+}
+
+func (k objectKey) String() string {
+	return fmt.Sprintf("%s %s/%s", k.kind, k.namespace, k.name)
+}
+
+func (k objectKey) less(other objectKey) bool {
+	if k.name != other.name {
+		return k.name < other.name
+	}
+	return k.kind < other.kind
+}
+
+type object struct {
+	key    objectKey
+	policy []byte
+	data   []byte
+	// raw is the last seen version of the object, it's used to report the status
+	raw interface{}
+}
+
+type namespaceState struct {
+	hash     [sha256.Size]byte
+	applyErr error
+}
+
+// ApplyFunc activates the merged policy and data of the namespace
+type ApplyFunc func(namespace string, policy, data []byte) error
+
+// Source watches labelled ConfigMaps and Secrets and AuthPolicy resources,
+// merges them per namespace and optionally reports statuses back on the objects.
+type Source struct {
+	client        kubernetes.Interface
+	dynamic       dynamic.Interface
+	namespace     string
+	labelSelector string
+	resync        time.Duration
+	logger        *slog.Logger
+	apply         ApplyFunc
+	reportStatus  bool
+
+	ctx        context.Context
+	mux        sync.Mutex
+	synced     bool
+	objects    map[objectKey]*object
+	namespaces map[string]*namespaceState
+
+	// pendingStatuses are written to the objects by the status goroutine outside of mux,
+	// only the last status of an object is written
+	statusMux       sync.Mutex
+	pendingStatuses map[objectKey]statusReport
+	statusCh        chan struct{}
+}
+
+type SourceOpt func(*Source)
+
+func WithLogger(logger *slog.Logger) SourceOpt {
+	return func(s *Source) {
+		s.logger = logger
+	}
+}
+
+// WithClient enables watching ConfigMaps and Secrets
+func WithClient(client kubernetes.Interface) SourceOpt {
+	return func(s *Source) {
+		s.client = client
+	}
+}
+
+// WithDynamicClient enables watching AuthPolicy resources
+func WithDynamicClient(client dynamic.Interface) SourceOpt {
+	return func(s *Source) {
+		s.dynamic = client
+	}
+}
+
+// WithNamespace restricts watching to the namespace, all namespaces are watched by default
+func WithNamespace(namespace string) SourceOpt {
+	return func(s *Source) {
+		s.namespace = namespace
+	}
+}
+
+// WithLabelSelector overrides DefaultLabelSelector of ConfigMaps and Secrets
+func WithLabelSelector(selector string) SourceOpt {
+	return func(s *Source) {
+		s.labelSelector = selector
+	}
+}
+
+// WithStatusReports enables writing statuses to the objects, it should be enabled
+// for one replica only, since every replica would write the same statuses
+func WithStatusReports() SourceOpt {
+	return func(s *Source) {
+		s.reportStatus = true
+	}
+}
+
+func WithResync(resync time.Duration) SourceOpt {
+	return func(s *Source) {
+		s.resync = resync
+	}
+}
+
+func NewSource(apply ApplyFunc, opts ...SourceOpt) (*Source, error) {
+	s := &Source{
+		labelSelector: DefaultLabelSelector,
+		resync:        10 * time.Minute,
+		apply:         apply,
+		objects:       map[objectKey]*object{},
+		namespaces:    map[string]*namespaceState{},
+
+		pendingStatuses: map[objectKey]statusReport{},
+		statusCh:        make(chan struct{}, 1),
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	if s.client == nil && s.dynamic == nil {
+		return nil, errors.New("kubernetes client is required")
+	}
+
+	if s.logger == nil {
+		s.logger = logging.NewNullLogger()
+	}
+
+	return s, nil
+}
+
+// Start runs informers until the context is done. It waits for the caches to be synced
+// and applies all namespaces, so an error of the initial apply is returned.
+func (s *Source) Start(ctx context.Context) error {
+	s.ctx = ctx
+
+	informersList := []cache.SharedIndexInformer{}
+	starts := []func(stopCh <-chan struct{}){}
+
+	if s.client != nil {
+		factory := informers.NewSharedInformerFactoryWithOptions(s.client, s.resync,
+			informers.WithNamespace(s.namespace),
+			informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+				o.LabelSelector = s.labelSelector
+			}),
+		)
+		informersList = append(informersList,
+			factory.Core().V1().ConfigMaps().Informer(),
+			factory.Core().V1().Secrets().Informer(),
+		)
+		starts = append(starts, factory.Start)
+	}
+
+	if s.dynamic != nil {
+		factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(s.dynamic, s.resync, s.namespace, nil)
+		informersList = append(informersList, factory.ForResource(AuthPolicyResource).Informer())
+		starts = append(starts, factory.Start)
+	}
+
+	hasSynced := []cache.InformerSynced{}
+	for _, informer := range informersList {
+		registration, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    s.onUpdate,
+			UpdateFunc: func(_, obj interface{}) { s.onUpdate(obj) },
+			DeleteFunc: s.onDelete,
+		})
+		if err != nil {
+			return fmt.Errorf("add event handler: %w", err)
+		}
+		// the handler is synced when it received all objects of the initial list
+		hasSynced = append(hasSynced, registration.HasSynced)
+	}
+
+	for _, start := range starts {
+		start(ctx.Done())
+	}
+
+	if s.reportStatus {
+		go s.runStatusReports(ctx)
+	}
+
+	if !cache.WaitForCacheSync(ctx.Done(), hasSynced...) {
+		return errors.New("kubernetes caches aren't synced")
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.synced = true
+
+	var errs []error
+	for namespace := range s.namespacesOfObjects() {
+		if err := s.syncNamespace(namespace); err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", namespace, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *Source) onUpdate(raw interface{}) {
+	obj, err := toObject(raw)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.objects[obj.key] = obj
+
+	if s.synced {
+		s.syncNamespace(obj.key.namespace) //nolint: errcheck
+	}
+}
+
+func (s *Source) onDelete(raw interface{}) {
+	if tombstone, ok := raw.(cache.DeletedFinalStateUnknown); ok {
+		raw = tombstone.Obj
+	}
+
+	obj, err := toObject(raw)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	delete(s.objects, obj.key)
+
+	if s.synced {
+		s.syncNamespace(obj.key.namespace) //nolint: errcheck
+	}
+}
+
+func (s *Source) namespacesOfObjects() map[string]struct{} {
+	namespaces := map[string]struct{}{}
+	for key := range s.objects {
+		namespaces[key.namespace] = struct{}{}
+	}
+
+	return namespaces
+}
+
+// syncNamespace merges objects of the namespace, applies the result if it has changed
+// and reports statuses, it's called under the lock.
+func (s *Source) syncNamespace(namespace string) error {
+	objects := []*object{}
+	for key, obj := range s.objects {
+		if key.namespace == namespace {
+			objects = append(objects, obj)
+		}
+	}
+
+	if len(objects) == 0 {
+		// the last applied policy stays active
+		s.logger.Warn(fmt.Sprintf("namespace %s doesn't contain policy objects anymore", namespace))
+		delete(s.namespaces, namespace)
+		return nil
+	}
+
+	result, err := mergeObjects(objects)
+	if err == nil {
+		err = s.applyMerged(namespace, result)
+	}
+
+	statuses := map[objectKey]objectStatus{}
+	for _, key := range result.applied {
+		if err != nil {
+			statuses[key] = objectStatus{status: StatusFailed, err: err.Error()}
+		} else {
+			statuses[key] = objectStatus{status: StatusApplied}
+		}
+	}
+	for key, invalidErr := range result.invalid {
+		statuses[key] = objectStatus{status: StatusInvalid, err: invalidErr.Error()}
+		s.logger.Error(fmt.Sprintf("%s is invalid: %s", key, invalidErr.Error()))
+	}
+
+	if s.reportStatus {
+		for _, obj := range objects {
+			s.queueStatus(obj, statuses[obj.key])
+		}
+	}
+
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("applying namespace %s failed: %s", namespace, err.Error()))
+	}
+
+	return err
+}
+
+func (s *Source) applyMerged(namespace string, result *merged) error {
+	hash := sha256.New()
+	hash.Write(result.policy)
+	hash.Write([]byte{0})
+	hash.Write(result.data)
+	var mergedHash [sha256.Size]byte
+	copy(mergedHash[:], hash.Sum(nil))
+
+	state, ok := s.namespaces[namespace]
+	if ok && state.hash == mergedHash {
+		return state.applyErr
+	}
+
+	err := s.apply(namespace, result.policy, result.data)
+	s.namespaces[namespace] = &namespaceState{hash: mergedHash, applyErr: err}
+
+	if err == nil {
+		s.logger.Info(fmt.Sprintf("policy of namespace %s applied from %d objects", namespace, len(result.applied)))
+	}
+
+	return err
+}
+
+func toObject(raw interface{}) (*object, error) {
+	switch o := raw.(type) {
+	case *corev1.ConfigMap:
+		obj := &object{
+			key: objectKey{kind: KindConfigMap, namespace: o.Namespace, name: o.Name},
+			raw: o,
+		}
+		if policy, ok := o.Data[PolicyKey]; ok {
+			obj.policy = []byte(policy)
+		}
+		if data, ok := o.Data[DataKey]; ok {
+			obj.data = []byte(data)
+		}
+		return obj, nil
+	case *corev1.Secret:
+		return &object{
+			key:    objectKey{kind: KindSecret, namespace: o.Namespace, name: o.Name},
+			raw:    o,
+			policy: o.Data[PolicyKey],
+			data:   o.Data[DataKey],
+		}, nil
+	case *unstructured.Unstructured:
+		obj := &object{
+			key: objectKey{kind: KindAuthPolicy, namespace: o.GetNamespace(), name: o.GetName()},
+			raw: o,
+		}
+		policy, _, err := unstructured.NestedString(o.Object, "spec", "policy")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", obj.key, err)
+		}
+		obj.policy = []byte(policy)
+		if data, ok, _ := unstructured.NestedFieldNoCopy(o.Object, "spec", "data"); ok && data != nil {
+			obj.data, err = json.Marshal(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", obj.key, err)
+			}
+		}
+		return obj, nil
+	default:
+		return nil, fmt.Errorf("unexpected kubernetes object %T", raw)
+	}
+}
+
+type objectStatus struct {
+	status string
+	err    string
+}
+
+type statusReport struct {
+	obj    *object
+	status objectStatus
+}
+
+// queueStatus schedules writing the status to the object, it replaces the pending status of the object
+func (s *Source) queueStatus(obj *object, st objectStatus) {
+	s.statusMux.Lock()
+	s.pendingStatuses[obj.key] = statusReport{obj: obj, status: st}
+	s.statusMux.Unlock()
+
+	select {
+	case s.statusCh <- struct{}{}:
+	default:
+	}
+}
+
+// runStatusReports writes pending statuses until the context is done, so api requests
+// don't block handling of object events
+func (s *Source) runStatusReports(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.statusCh:
+		}
+
+		s.statusMux.Lock()
+		reports := s.pendingStatuses
+		s.pendingStatuses = map[objectKey]statusReport{}
+		s.statusMux.Unlock()
+
+		for key, report := range reports {
+			if err := s.writeStatus(report.obj, report.status); err != nil {
+				s.logger.Error(fmt.Sprintf("report status of %s: %s", key, err.Error()))
+			}
+		}
+	}
+}
+
+// writeStatus updates the status only if it differs, so the update event of the object
+// caused by the status doesn't lead to another update.
+func (s *Source) writeStatus(obj *object, st objectStatus) error {
+	switch o := obj.raw.(type) {
+	case *corev1.ConfigMap:
+		if !annotationsChanged(o.Annotations, st) {
+			return nil
+		}
+		cm := o.DeepCopy()
+		cm.Annotations = withStatusAnnotations(cm.Annotations, st)
+		_, err := s.client.CoreV1().ConfigMaps(cm.Namespace).Update(s.ctx, cm, metav1.UpdateOptions{})
+		return err
+	case *corev1.Secret:
+		if !annotationsChanged(o.Annotations, st) {
+			return nil
+		}
+		secret := o.DeepCopy()
+		secret.Annotations = withStatusAnnotations(secret.Annotations, st)
+		_, err := s.client.CoreV1().Secrets(secret.Namespace).Update(s.ctx, secret, metav1.UpdateOptions{})
+		return err
+	case *unstructured.Unstructured:
+		status := map[string]interface{}{
+			"state":              st.status,
+			"observedGeneration": o.GetGeneration(),
+		}
+		if len(st.err) > 0 {
+			status["error"] = st.err
+		}
+		current, _, _ := unstructured.NestedMap(o.Object, "status")
+		if equalStatus(current, status) {
+			return nil
+		}
+		policy := o.DeepCopy()
+		if err := unstructured.SetNestedMap(policy.Object, status, "status"); err != nil {
+			return err
+		}
+		_, err := s.dynamic.Resource(AuthPolicyResource).Namespace(policy.GetNamespace()).UpdateStatus(s.ctx, policy, metav1.UpdateOptions{})
+		return err
+	}
+
+	return nil
+}
+
+func annotationsChanged(annotations map[string]string, st objectStatus) bool {
+	return annotations[StatusAnnotation] != st.status || annotations[ErrorAnnotation] != st.err
+}
+
+func withStatusAnnotations(annotations map[string]string, st objectStatus) map[string]string {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[StatusAnnotation] = st.status
+	if len(st.err) > 0 {
+		annotations[ErrorAnnotation] = st.err
+	} else {
+		delete(annotations, ErrorAnnotation)
+	}
+
+	return annotations
+}
+
+// equalStatus compares statuses by json, the generation may be int64 or float64 after decoding
+func equalStatus(a, b map[string]interface{}) bool {
+	aJSON, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bJSON, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(aJSON, bJSON)
+}
@@ -0,0 +1,321 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package kube
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goauthlink/authlink/sdk/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	testPolicyCommon = `cn:
+  - header: "x-source"
+vars:
+  admins: ["admin"]`
+	testPolicyUsers = `policies:
+  - uri: ["/users"]
+    allow: ["$admins", "{.users[*]}"]`
+	testPolicyOrders = `policies:
+  - uri: ["/orders"]
+    allow: ["$admins", "{.orders[*]}"]`
+)
+
+// testApplier activates merged policies with a real checker per namespace
+type testApplier struct {
+	mux      sync.Mutex
+	checkers map[string]*policy.Checker
+	applied  int
+}
+
+func newTestApplier() *testApplier {
+	return &testApplier{checkers: map[string]*policy.Checker{}}
+}
+
+func (a *testApplier) apply(namespace string, policyData, data []byte) error {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	checker, ok := a.checkers[namespace]
+	if !ok {
+		checker = policy.NewChecker()
+	}
+	if err := checker.SetPolicyWithData(policyData, data); err != nil {
+		return err
+	}
+
+	a.checkers[namespace] = checker
+	a.applied++
+
+	return nil
+}
+
+func (a *testApplier) allowed(namespace, client, uri string) bool {
+	a.mux.Lock()
+	checker, ok := a.checkers[namespace]
+	a.mux.Unlock()
+	if !ok {
+		return false
+	}
+
+	result, err := checker.Check(policy.CheckInput{
+		Uri:     uri,
+		Method:  http.MethodGet,
+		Headers: map[string]string{"x-source": client},
+	})
+
+	return err == nil && result.Allow
+}
+
+func (a *testApplier) appliedCount() int {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	return a.applied
+}
+
+func newConfigMap(namespace, name string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    map[string]string{"authlink.io/policy": "true"},
+		},
+		Data: data,
+	}
+}
+
+func startSource(t *testing.T, opts ...SourceOpt) (*testApplier, func()) {
+	applier := newTestApplier()
+
+	source, err := NewSource(applier.apply, opts...)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, source.Start(ctx))
+
+	return applier, cancel
+}
+
+func getAnnotations(t *testing.T, client *fake.Clientset, namespace, name string) map[string]string {
+	cm, err := client.CoreV1().ConfigMaps(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+
+	return cm.Annotations
+}
+
+// assertStatus waits for the status written to the configmap
+func assertStatus(t *testing.T, client *fake.Clientset, namespace, name, status string) {
+	assert.Eventually(t, func() bool {
+		return getAnnotations(t, client, namespace, name)[StatusAnnotation] == status
+	}, 2*time.Second, 10*time.Millisecond, "%s/%s", namespace, name)
+}
+
+func Test_SourceConfigMaps(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newConfigMap("ns1", "common", map[string]string{PolicyKey: testPolicyCommon}),
+		newConfigMap("ns1", "users", map[string]string{PolicyKey: testPolicyUsers, DataKey: `{"users":["user1"]}`}),
+		newConfigMap("ns2", "orders", map[string]string{PolicyKey: testPolicyCommon + "\n" + testPolicyOrders, DataKey: `{"orders":["user2"]}`}),
+		// not labelled
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "other"},
+			Data:       map[string]string{PolicyKey: "invalid"},
+		},
+	)
+
+	applier, stop := startSource(t, WithClient(client), WithStatusReports())
+	defer stop()
+
+	// objects are merged per namespace
+	assert.True(t, applier.allowed("ns1", "user1", "/users"))
+	assert.True(t, applier.allowed("ns1", "admin", "/users"))
+	assert.False(t, applier.allowed("ns1", "user2", "/orders"))
+	assert.True(t, applier.allowed("ns2", "user2", "/orders"))
+	assert.False(t, applier.allowed("ns2", "user1", "/users"))
+
+	assertStatus(t, client, "ns1", "users", StatusApplied)
+	assert.Empty(t, getAnnotations(t, client, "ns1", "other"))
+
+	// a new object of the namespace is merged
+	_, err := client.CoreV1().ConfigMaps("ns1").Create(context.Background(),
+		newConfigMap("ns1", "orders", map[string]string{PolicyKey: testPolicyOrders, DataKey: `{"orders":["user2"]}`}),
+		metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return applier.allowed("ns1", "user2", "/orders")
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// updates of the status annotations don't apply the policy again
+	assertStatus(t, client, "ns1", "orders", StatusApplied)
+	applied := applier.appliedCount()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, applied, applier.appliedCount())
+
+	// a deleted object is excluded
+	require.NoError(t, client.CoreV1().ConfigMaps("ns1").Delete(context.Background(), "users", metav1.DeleteOptions{}))
+
+	assert.Eventually(t, func() bool {
+		return !applier.allowed("ns1", "user1", "/users")
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.True(t, applier.allowed("ns1", "user2", "/orders"))
+}
+
+func Test_SourceInvalidObjects(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newConfigMap("ns1", "common", map[string]string{PolicyKey: testPolicyCommon}),
+		newConfigMap("ns1", "users", map[string]string{PolicyKey: testPolicyUsers, DataKey: `{"users":["user1"]}`}),
+	)
+
+	applier, stop := startSource(t, WithClient(client), WithNamespace("ns1"), WithStatusReports())
+	defer stop()
+
+	assert.True(t, applier.allowed("ns1", "user1", "/users"))
+
+	// invalid object is excluded, the rest is still applied
+	_, err := client.CoreV1().ConfigMaps("ns1").Create(context.Background(),
+		newConfigMap("ns1", "conflict", map[string]string{PolicyKey: `vars:
+  admins: ["user3"]`}),
+		metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		annotations := getAnnotations(t, client, "ns1", "conflict")
+		return annotations[StatusAnnotation] == StatusInvalid &&
+			annotations[ErrorAnnotation] == "variable admins conflicts with ConfigMap ns1/common"
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.True(t, applier.allowed("ns1", "admin", "/users"))
+	assertStatus(t, client, "ns1", "users", StatusApplied)
+
+	// merged policy failed to apply, the previous one stays active
+	cm := newConfigMap("ns1", "users", map[string]string{PolicyKey: `policies:
+  - uri: ["/users"]
+    allow: ["$undefined"]`, DataKey: `{"users":["user1"]}`})
+	_, err = client.CoreV1().ConfigMaps("ns1").Update(context.Background(), cm, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		annotations := getAnnotations(t, client, "ns1", "users")
+		return annotations[StatusAnnotation] == StatusFailed && len(annotations[ErrorAnnotation]) > 0
+	}, 2*time.Second, 10*time.Millisecond)
+	assertStatus(t, client, "ns1", "common", StatusFailed)
+	assert.True(t, applier.allowed("ns1", "user1", "/users"))
+}
+
+func Test_SourceSecrets(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newConfigMap("ns1", "common", map[string]string{PolicyKey: testPolicyCommon + "\n" + testPolicyUsers}),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns1",
+				Name:      "users",
+				Labels:    map[string]string{"authlink.io/policy": "true"},
+			},
+			Data: map[string][]byte{DataKey: []byte(`{"users":["user1"]}`)},
+		},
+	)
+
+	applier, stop := startSource(t, WithClient(client), WithStatusReports())
+	defer stop()
+
+	assert.True(t, applier.allowed("ns1", "user1", "/users"))
+
+	assert.Eventually(t, func() bool {
+		secret, err := client.CoreV1().Secrets("ns1").Get(context.Background(), "users", metav1.GetOptions{})
+		require.NoError(t, err)
+		return secret.Annotations[StatusAnnotation] == StatusApplied
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func Test_SourceWithoutStatusReports(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newConfigMap("ns1", "common", map[string]string{PolicyKey: testPolicyCommon}),
+		newConfigMap("ns1", "users", map[string]string{PolicyKey: testPolicyUsers, DataKey: `{"users":["user1"]}`}),
+		newConfigMap("ns1", "invalid", map[string]string{PolicyKey: "policies: {"}),
+	)
+
+	applier, stop := startSource(t, WithClient(client))
+	defer stop()
+
+	// statuses are written by the replica which enables them
+	assert.True(t, applier.allowed("ns1", "user1", "/users"))
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, getAnnotations(t, client, "ns1", "users"))
+	assert.Empty(t, getAnnotations(t, client, "ns1", "invalid"))
+}
+
+func newAuthPolicy(namespace, name, policy string, data map[string]interface{}) *unstructured.Unstructured {
+	spec := map[string]interface{}{"policy": policy}
+	if data != nil {
+		spec["data"] = data
+	}
+
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "authlink.io/v1alpha1",
+		"kind":       "AuthPolicy",
+		"metadata": map[string]interface{}{
+			"namespace":  namespace,
+			"name":       name,
+			"generation": int64(1),
+		},
+		"spec": spec,
+	}}
+}
+
+func Test_SourceAuthPolicies(t *testing.T) {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{AuthPolicyResource: "AuthPolicyList"},
+		newAuthPolicy("ns1", "users", testPolicyCommon+"\n"+testPolicyUsers, map[string]interface{}{
+			"users": []interface{}{"user1"},
+		}),
+		newAuthPolicy("ns1", "invalid", "policies: {", nil),
+	)
+	client := fake.NewSimpleClientset(
+		newConfigMap("ns1", "orders", map[string]string{PolicyKey: testPolicyOrders, DataKey: `{"orders":["user2"]}`}),
+	)
+
+	applier, stop := startSource(t, WithClient(client), WithDynamicClient(dynamicClient), WithStatusReports())
+	defer stop()
+
+	// custom resources are merged with configmaps
+	assert.True(t, applier.allowed("ns1", "user1", "/users"))
+	assert.True(t, applier.allowed("ns1", "user2", "/orders"))
+
+	getStatus := func(name string) map[string]interface{} {
+		obj, err := dynamicClient.Resource(AuthPolicyResource).Namespace("ns1").Get(context.Background(), name, metav1.GetOptions{})
+		require.NoError(t, err)
+		status, _, _ := unstructured.NestedMap(obj.Object, "status")
+		return status
+	}
+
+	assert.Eventually(t, func() bool {
+		return getStatus("users")["state"] == StatusApplied
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.EqualValues(t, 1, getStatus("users")["observedGeneration"])
+
+	assert.Eventually(t, func() bool {
+		return getStatus("invalid")["state"] == StatusInvalid
+	}, 2*time.Second, 10*time.Millisecond)
+	invalidStatus := getStatus("invalid")
+	assert.Contains(t, invalidStatus["error"], "parse policy")
+}
+
+func Test_SourceNoClient(t *testing.T) {
+	_, err := NewSource(func(namespace string, policy, data []byte) error { return nil })
+	require.Error(t, err)
+}
@@ -0,0 +1,199 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/goauthlink/authlink/pkg/metrics"
+	"github.com/goauthlink/authlink/sdk/policy"
+	api "go.opentelemetry.io/otel/metric"
+)
+
+// DefaultMetricsMaxSeries is the default cap of distinct label sets of decision metrics
+const DefaultMetricsMaxSeries = 1000
+
+const (
+	resultAllow = "allow"
+	resultDeny  = "deny"
+	resultError = "error"
+
+	// labelOther replaces unlisted clients, unknown methods and labels of series over the cap
+	labelOther = "other"
+	// labelNone is the value of labels absent in the decision
+	labelNone = "none"
+)
+
+var knownMethods = map[string]struct{}{
+	"GET": {}, "HEAD": {}, "POST": {}, "PUT": {}, "PATCH": {}, "DELETE": {}, "CONNECT": {}, "OPTIONS": {}, "TRACE": {},
+}
+
+// WithMeterProvider records metrics of the agent with the provider instead of the exporters of the config,
+// the provider is owned by the caller and the scrape endpoint of the monitoring server is disabled
+func WithMeterProvider(provider api.MeterProvider) Option {
+	return func(a *Agent) {
+		a.meterProvider = provider
+	}
+}
+
+// initMetrics creates the provider of the agent serving the scrape endpoint and pushing
+// to the OTLP collector of the config
+func (a *Agent) initMetrics() error {
+	provider, err := metrics.NewProvider(context.Background(), metrics.Config{
+		Prometheus:   true,
+		OTLPEndpoint: a.config.MetricsOTLPEndpoint,
+		OTLPInsecure: a.config.MetricsOTLPInsecure,
+		OTLPInterval: a.config.MetricsOTLPInterval,
+	})
+	if err != nil {
+		return fmt.Errorf("init metrics: %w", err)
+	}
+
+	a.meterProvider = provider
+	a.metricsHandler = provider.Handler()
+	a.shutdownMetrics = provider.Shutdown
+
+	return nil
+}
+
+// Meter returns the meter of the agent, so servers added by AddServer export metrics along with the agent
+func (a *Agent) Meter() *metrics.Meter {
+	return a.meter
+}
+
+// decisionLabels are labels of check_rq_total
+type decisionLabels struct {
+	result   string
+	rule     string
+	method   string
+	cnSource string
+	client   string
+}
+
+func (l decisionLabels) attrs() map[string]string {
+	return map[string]string{
+		"result":    l.result,
+		"rule":      l.rule,
+		"method":    l.method,
+		"cn_source": l.cnSource,
+		"client":    l.client,
+	}
+}
+
+// seriesLimiter bounds cardinality of decision metrics: clients outside the allow-list are
+// counted as other, and labels of new series over the cap except the result are replaced with other
+type seriesLimiter struct {
+	clients   map[string]struct{}
+	maxSeries int
+	// series holds label sets seen, it's read without locking on every check
+	series    sync.Map
+	seriesMux sync.Mutex
+	count     int
+}
+
+func newSeriesLimiter(clients []string, maxSeries int) *seriesLimiter {
+	l := &seriesLimiter{
+		clients:   make(map[string]struct{}, len(clients)),
+		maxSeries: maxSeries,
+	}
+	for _, client := range clients {
+		l.clients[client] = struct{}{}
+	}
+
+	return l
+}
+
+// labels returns labels of the decision, err is the error of the failed check
+func (l *seriesLimiter) labels(in policy.CheckInput, result *policy.CheckResult, err error) decisionLabels {
+	labels := decisionLabels{
+		result:   resultError,
+		rule:     labelNone,
+		method:   labelOther,
+		cnSource: labelNone,
+		client:   labelNone,
+	}
+
+	if _, ok := knownMethods[in.Method]; ok {
+		labels.method = in.Method
+	} else if len(in.Method) == 0 {
+		labels.method = labelNone
+	}
+
+	if err == nil {
+		var invalidCn policy.ErrInvalidClientName
+		switch {
+		case result.Allow:
+			labels.result = resultAllow
+		case result.Err != nil && !errors.As(result.Err, &invalidCn):
+			// evaluation faults, e.g. of jsonpath queries, aren't denials
+			labels.result = resultError
+		default:
+			labels.result = resultDeny
+		}
+		if len(result.Endpoint) > 0 {
+			labels.rule = result.Endpoint
+		}
+		if len(result.ClientNameSource) > 0 {
+			labels.cnSource = result.ClientNameSource
+		}
+		if len(result.ClientName) > 0 {
+			labels.client = labelOther
+			if _, ok := l.clients[result.ClientName]; ok {
+				labels.client = result.ClientName
+			}
+		}
+	}
+
+	return l.limit(labels)
+}
+
+func (l *seriesLimiter) limit(labels decisionLabels) decisionLabels {
+	if _, ok := l.series.Load(labels); ok {
+		return labels
+	}
+
+	l.seriesMux.Lock()
+	defer l.seriesMux.Unlock()
+
+	if _, ok := l.series.Load(labels); ok {
+		return labels
+	}
+
+	if l.count >= l.maxSeries {
+		return decisionLabels{
+			result:   labels.result,
+			rule:     labelOther,
+			method:   labelOther,
+			cnSource: labelOther,
+			client:   labelOther,
+		}
+	}
+
+	l.series.Store(labels, struct{}{})
+	l.count++
+
+	return labels
+}
+
+// invalidClientNameLabels returns labels of check_invalid_client_name_total if the client name of the decision is invalid
+func invalidClientNameLabels(result *policy.CheckResult) (map[string]string, bool) {
+	var invalidCn policy.ErrInvalidClientName
+	if result == nil || !errors.As(result.Err, &invalidCn) {
+		return nil, false
+	}
+
+	source := invalidCn.Source()
+	if len(source) == 0 {
+		source = labelNone
+	}
+
+	return map[string]string{
+		"reason":    string(invalidCn.Reason()),
+		"cn_source": source,
+	}, true
+}
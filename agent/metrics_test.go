@@ -0,0 +1,183 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goauthlink/authlink/pkg/logging"
+	"github.com/goauthlink/authlink/sdk/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func Test_DecisionLabels(t *testing.T) {
+	checker := policy.NewChecker()
+	require.NoError(t, checker.SetPolicy([]byte(`
+cn:
+  - header: "x-source"
+    name: "service"
+policies:
+  - uri: ["/endpoint"]
+    allow: ["orders", "payments"]`)))
+
+	limiter := newSeriesLimiter([]string{"orders"}, 100)
+
+	cases := []struct {
+		in     policy.CheckInput
+		labels decisionLabels
+	}{
+		{
+			in:     policy.CheckInput{Uri: "/endpoint", Method: "GET", Headers: map[string]string{"x-source": "orders"}},
+			labels: decisionLabels{result: "allow", rule: "/endpoint", method: "GET", cnSource: "service", client: "orders"},
+		},
+		{
+			// clients outside the allow-list are bucketed
+			in:     policy.CheckInput{Uri: "/endpoint", Method: "POST", Headers: map[string]string{"x-source": "payments"}},
+			labels: decisionLabels{result: "allow", rule: "/endpoint", method: "POST", cnSource: "service", client: "other"},
+		},
+		{
+			in:     policy.CheckInput{Uri: "/other", Method: "PURGE", Headers: map[string]string{"x-source": "orders"}},
+			labels: decisionLabels{result: "deny", rule: "default", method: "other", cnSource: "service", client: "orders"},
+		},
+		{
+			in:     policy.CheckInput{Uri: "/endpoint"},
+			labels: decisionLabels{result: "deny", rule: "none", method: "none", cnSource: "none", client: "none"},
+		},
+	}
+
+	for _, c := range cases {
+		result, err := checker.Check(c.in)
+		require.NoError(t, err)
+		assert.Equal(t, c.labels, limiter.labels(c.in, result, nil), c.in)
+	}
+
+	labels := limiter.labels(policy.CheckInput{Uri: "/endpoint", Method: "GET"}, nil, assert.AnError)
+	assert.Equal(t, decisionLabels{result: "error", rule: "none", method: "GET", cnSource: "none", client: "none"}, labels)
+
+	// evaluation errors of the matched rule aren't counted as denials
+	labels = limiter.labels(policy.CheckInput{Uri: "/endpoint", Method: "GET"},
+		&policy.CheckResult{Endpoint: "/endpoint", Err: errors.New("jsonpath finding results failure")}, nil)
+	assert.Equal(t, decisionLabels{result: "error", rule: "/endpoint", method: "GET", cnSource: "none", client: "none"}, labels)
+}
+
+func Test_DecisionLabelsMaxSeries(t *testing.T) {
+	limiter := newSeriesLimiter(nil, 2)
+
+	get := decisionLabels{result: "allow", rule: "/a", method: "GET", cnSource: "none", client: "none"}
+	post := decisionLabels{result: "allow", rule: "/a", method: "POST", cnSource: "none", client: "none"}
+	put := decisionLabels{result: "deny", rule: "/b", method: "PUT", cnSource: "none", client: "none"}
+
+	assert.Equal(t, get, limiter.limit(get))
+	assert.Equal(t, post, limiter.limit(post))
+
+	// labels of series over the cap except the result are replaced
+	assert.Equal(t, decisionLabels{result: "deny", rule: "other", method: "other", cnSource: "other", client: "other"}, limiter.limit(put))
+
+	// known series keep their labels
+	assert.Equal(t, get, limiter.limit(get))
+}
+
+func Test_InvalidClientNameLabels(t *testing.T) {
+	checker := policy.NewChecker()
+	require.NoError(t, checker.SetPolicy([]byte(`
+cn:
+  - jwt:
+      payload: "user"
+      header: "Authorization"
+    name: "user"
+policies:
+  - uri: ["/endpoint"]
+    allow: ["jhon"]`)))
+
+	result, err := checker.Check(policy.CheckInput{Uri: "/endpoint"})
+	require.NoError(t, err)
+	labels, ok := invalidClientNameLabels(result)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"reason": "undefined", "cn_source": "none"}, labels)
+
+	result, err = checker.Check(policy.CheckInput{Uri: "/endpoint", Headers: map[string]string{"Authorization": "token"}})
+	require.NoError(t, err)
+	labels, ok = invalidClientNameLabels(result)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"reason": "invalid_jwt", "cn_source": "user"}, labels)
+
+	_, ok = invalidClientNameLabels(&policy.CheckResult{Allow: true})
+	assert.False(t, ok)
+	_, ok = invalidClientNameLabels(nil)
+	assert.False(t, ok)
+}
+
+// checkCount returns the sum of check_rq_total collected by the reader
+func checkCount(t *testing.T, reader sdkmetric.Reader) float64 {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	total := 0.0
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[float64]); ok && m.Name == "check_rq_total" {
+				for _, dp := range sum.DataPoints {
+					total += dp.Value
+				}
+			}
+		}
+	}
+
+	return total
+}
+
+func Test_MeterProvider(t *testing.T) {
+	newAgent := func() (*Agent, sdkmetric.Reader) {
+		reader := sdkmetric.NewManualReader()
+		agent, err := New(
+			WithAgentLogger(logging.NewNullLogger()),
+			WithPolicySource(NewMemorySource([]byte(testPolicy))),
+			WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		)
+		require.NoError(t, err)
+
+		return agent, reader
+	}
+
+	agent1, reader1 := newAgent()
+	agent2, reader2 := newAgent()
+
+	for range 3 {
+		_, err := agent1.Policy().Check(context.Background(), policy.CheckInput{Uri: "/endpoint", Method: "GET"})
+		require.NoError(t, err)
+	}
+	_, err := agent2.Policy().Check(context.Background(), policy.CheckInput{Uri: "/endpoint", Method: "GET"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3.0, checkCount(t, reader1))
+	assert.Equal(t, 1.0, checkCount(t, reader2))
+
+	// the scrape endpoint serves only the provider created by the agent
+	assert.Nil(t, agent1.metricsHandler)
+}
+
+func Test_MetricsHandler(t *testing.T) {
+	agent, err := New(
+		WithAgentLogger(logging.NewNullLogger()),
+		WithPolicySource(NewMemorySource([]byte(testPolicy))),
+	)
+	require.NoError(t, err)
+	require.NotNil(t, agent.metricsHandler)
+
+	_, err = agent.Policy().Check(context.Background(), policy.CheckInput{Uri: "/endpoint", Method: "GET"})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	agent.metricsHandler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `check_rq_total{client="none",cn_source="none",method="GET",result="deny",rule="none"} 1`)
+}
@@ -6,17 +6,34 @@ package monitoring
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 
+	"github.com/goauthlink/authlink/pkg/logging"
 	"github.com/goauthlink/authlink/pkg/metrics"
 )
 
+// DefaultMetricsPath is the path of the prometheus scrape endpoint
+const DefaultMetricsPath = "/metrics"
+
 type Server struct {
-	srv    *http.Server
-	logger *slog.Logger
+	srv            *http.Server
+	logger         *slog.Logger
+	healthCheck    func() error
+	healthDetails  func() interface{}
+	readinessCheck func() error
+	handlers       map[string]http.Handler
+	meter          *metrics.Meter
+	// metricsSet disables the exporter of the global meter provider
+	metricsSet     bool
+	metricsPath    string
+	metricsHandler http.Handler
+	listening      atomic.Bool
 }
 
 type ServerOpt func(*Server)
@@ -27,20 +44,60 @@ func WithLogger(logger *slog.Logger) ServerOpt {
 	}
 }
 
-func NewServer(addr string, opts ...ServerOpt) (*Server, error) {
-	promhandler, err := metrics.RegisterPrometheusExporter()
-	if err != nil {
-		return nil, fmt.Errorf("init monitoring server: %w", err)
+// WithHealthCheck sets a check whose error is reported by the health endpoint,
+// e.g. a failure of the last policy update.
+func WithHealthCheck(check func() error) ServerOpt {
+	return func(s *Server) {
+		s.healthCheck = check
 	}
+}
 
-	router := http.NewServeMux()
-	router.Handle("GET /metrics", promhandler)
-	router.Handle("GET /health", routerGetHealtzHandler())
+// WithHealthDetails sets the source of details added to the health response, e.g. the reload status
+func WithHealthDetails(details func() interface{}) ServerOpt {
+	return func(s *Server) {
+		s.healthDetails = details
+	}
+}
+
+// WithReadinessCheck sets a check whose error makes the readiness endpoint respond 503,
+// e.g. stale data.
+func WithReadinessCheck(check func() error) ServerOpt {
+	return func(s *Server) {
+		s.readinessCheck = check
+	}
+}
 
+// WithHandler serves the handler at the pattern of http.ServeMux, e.g. a webhook of the policy source
+func WithHandler(pattern string, handler http.Handler) ServerOpt {
+	return func(s *Server) {
+		if s.handlers == nil {
+			s.handlers = map[string]http.Handler{}
+		}
+		s.handlers[pattern] = handler
+	}
+}
+
+// WithMetricsHandler serves the scrape handler at the path instead of the prometheus exporter
+// of the global meter provider, e.g. the handler of metrics.Provider. A nil handler disables the endpoint.
+func WithMetricsHandler(path string, handler http.Handler) ServerOpt {
+	return func(s *Server) {
+		s.metricsSet = true
+		s.metricsPath = path
+		s.metricsHandler = handler
+	}
+}
+
+// WithMeter creates metrics of requests by the meter instead of the global meter provider
+func WithMeter(meter *metrics.Meter) ServerOpt {
+	return func(s *Server) {
+		s.meter = meter
+	}
+}
+
+func NewServer(addr string, opts ...ServerOpt) (*Server, error) {
 	monitoringSrv := &Server{
 		srv: &http.Server{
-			Addr:    addr,
-			Handler: router,
+			Addr: addr,
 		},
 	}
 
@@ -48,25 +105,111 @@ func NewServer(addr string, opts ...ServerOpt) (*Server, error) {
 		o(monitoringSrv)
 	}
 
+	if monitoringSrv.logger == nil {
+		monitoringSrv.logger = logging.NewNullLogger()
+	}
+
+	if !monitoringSrv.metricsSet {
+		promhandler, err := metrics.RegisterPrometheusExporter()
+		if err != nil {
+			return nil, fmt.Errorf("init monitoring server: %w", err)
+		}
+		monitoringSrv.metricsHandler = promhandler
+	}
+	if len(monitoringSrv.metricsPath) == 0 {
+		monitoringSrv.metricsPath = DefaultMetricsPath
+	}
+
+	router := http.NewServeMux()
+	if monitoringSrv.metricsHandler != nil {
+		router.Handle("GET "+monitoringSrv.metricsPath, monitoringSrv.metricsHandler)
+	}
+	router.Handle("GET /health", routerGetHealtzHandler(monitoringSrv.healthCheck, monitoringSrv.healthDetails))
+	router.Handle("GET /ready", routerGetReadyHandler(monitoringSrv.readinessCheck))
+	for pattern, handler := range monitoringSrv.handlers {
+		router.Handle(pattern, handler)
+	}
+
+	metricsMiddleware, err := monitoringSrv.meter.NewHTTPMiddleware(router, metrics.WithServerLabel("monitoring"))
+	if err != nil {
+		return nil, err
+	}
+
+	monitoringSrv.srv.Handler = metricsMiddleware
+
 	return monitoringSrv, nil
 }
 
-func routerGetHealtzHandler() http.Handler {
+type healthResponse struct {
+	Status  string      `json:"status"`
+	Error   string      `json:"error,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// routerGetHealtzHandler is the liveness check, it always responds 200 while the agent is alive.
+// A failed check is reported in the body as degraded status.
+func routerGetHealtzHandler(check func() error, details func() interface{}) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rsp := healthResponse{Status: "ok"}
+		if check != nil {
+			if err := check(); err != nil {
+				rsp.Status = "degraded"
+				rsp.Error = err.Error()
+			}
+		}
+		if details != nil {
+			rsp.Details = details()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(rsp) //nolint: errcheck
+	})
+}
+
+// routerGetReadyHandler responds 503 while the readiness check fails
+func routerGetReadyHandler(check func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusOK
+		rsp := healthResponse{Status: "ready"}
+		if check != nil {
+			if err := check(); err != nil {
+				status = http.StatusServiceUnavailable
+				rsp.Status = "unready"
+				rsp.Error = err.Error()
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(rsp) //nolint: errcheck
 	})
 }
 
 func (monitorSrv *Server) Start(_ context.Context) error {
 	monitorSrv.logger.Info(fmt.Sprintf("monitor server is starting on %s", monitorSrv.srv.Addr))
 
-	if err := monitorSrv.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	listener, err := net.Listen("tcp", monitorSrv.srv.Addr)
+	if err != nil {
+		return fmt.Errorf("monitoring server listening: %w", err)
+	}
+	defer listener.Close()
+
+	monitorSrv.listening.Store(true)
+	defer monitorSrv.listening.Store(false)
+
+	if err := monitorSrv.srv.Serve(listener); err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("monitoring server listening: %w", err)
 	}
 
 	return nil
 }
 
+// Listening reports whether the server accepts connections
+func (monitorSrv *Server) Listening() bool {
+	return monitorSrv.listening.Load()
+}
+
 func (monitorSrv *Server) Shutdown(ctx context.Context) error {
 	ctxd, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
@@ -5,9 +5,12 @@
 package monitoring
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -24,4 +27,112 @@ func Test_GetHealtHandler(t *testing.T) {
 	server.srv.Handler.ServeHTTP(w, request)
 
 	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func Test_GetHealtHandlerDegraded(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	server, err := NewServer(":9191", WithHealthCheck(func() error {
+		return errors.New("policy and data updating failed")
+	}))
+	require.NoError(t, err)
+
+	request := httptest.NewRequest(http.MethodGet, "http://localhost:9191/health", nil)
+
+	server.srv.Handler.ServeHTTP(w, request)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"degraded","error":"policy and data updating failed"}`, w.Body.String())
+}
+
+func Test_GetHealtHandlerDetails(t *testing.T) {
+	server, err := NewServer(":9191", WithHealthDetails(func() interface{} {
+		return map[string]interface{}{"attempts": 3, "revision": "abc"}
+	}))
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	server.srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:9191/health", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok","details":{"attempts":3,"revision":"abc"}}`, w.Body.String())
+}
+
+func Test_GetReadyHandler(t *testing.T) {
+	var readinessErr error
+
+	server, err := NewServer(":9191", WithReadinessCheck(func() error {
+		return readinessErr
+	}))
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	server.srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:9191/ready", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ready"}`, w.Body.String())
+
+	readinessErr = errors.New("data is stale")
+
+	w = httptest.NewRecorder()
+	server.srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:9191/ready", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"status":"unready","error":"data is stale"}`, w.Body.String())
+}
+
+func Test_Handler(t *testing.T) {
+	server, err := NewServer(":9191", WithHandler("POST /webhook", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})))
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	server.srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://localhost:9191/webhook", nil))
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+}
+
+func Test_Listening(t *testing.T) {
+	server, err := NewServer("127.0.0.1:0")
+	require.NoError(t, err)
+	assert.False(t, server.Listening())
+
+	done := make(chan error)
+	go func() {
+		done <- server.Start(context.Background())
+	}()
+
+	assert.Eventually(t, server.Listening, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, server.Shutdown(context.Background()))
+	require.NoError(t, <-done)
+	assert.False(t, server.Listening())
+}
+
+func Test_MetricsHandler(t *testing.T) {
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("check_rq_total 1")) //nolint: errcheck
+	})
+
+	server, err := NewServer(":9191", WithMetricsHandler("/stats/prometheus", metrics))
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	server.srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:9191/stats/prometheus", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "check_rq_total 1", w.Body.String())
+
+	w = httptest.NewRecorder()
+	server.srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:9191/metrics", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// a nil handler disables the endpoint
+	server, err = NewServer(":9191", WithMetricsHandler(DefaultMetricsPath, nil))
+	require.NoError(t, err)
+
+	w = httptest.NewRecorder()
+	server.srv.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:9191/metrics", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
@@ -0,0 +1,113 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/goauthlink/authlink/sdk/policy"
+)
+
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// Event is a policy and data reload of the agent
+type Event struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Status is StatusSuccess or StatusFailure
+	Status string `json:"status"`
+	// Error of the failed reload, the previous policy and data stay active
+	Error string `json:"error,omitempty"`
+	// Revision, PolicyRevision and DataRevision are active after the reload
+	Revision       string `json:"revision,omitempty"`
+	PolicyRevision string `json:"policyRevision,omitempty"`
+	DataRevision   string `json:"dataRevision,omitempty"`
+	// Diff summarizes changes of the successful reload
+	Diff *Diff `json:"diff,omitempty"`
+}
+
+// Diff is a summary of changes of policy and data
+type Diff struct {
+	PolicyChanged bool `json:"policyChanged"`
+	DataChanged   bool `json:"dataChanged"`
+	// RulesAdded and RulesRemoved are rules as "methods endpoint", e.g. "GET,POST /orders".
+	// Rules with changed allow lists or conditions are neither added nor removed.
+	RulesAdded   []string `json:"rulesAdded,omitempty"`
+	RulesRemoved []string `json:"rulesRemoved,omitempty"`
+	// DataKeysChanged are added, removed and changed top-level keys of the data object
+	DataKeysChanged []string `json:"dataKeysChanged,omitempty"`
+}
+
+// NewDiff compares rules and data before and after the reload, policyChanged reports any change of the policy content
+func NewDiff(policyChanged bool, prevRules, rules []policy.RuleStats, prevData, data interface{}) *Diff {
+	prevIDs := ruleIDs(prevRules)
+	ids := ruleIDs(rules)
+
+	diff := &Diff{
+		PolicyChanged: policyChanged,
+		DataChanged:   !reflect.DeepEqual(prevData, data),
+	}
+	for _, id := range ids {
+		if !slices.Contains(prevIDs, id) {
+			diff.RulesAdded = append(diff.RulesAdded, id)
+		}
+	}
+	for _, id := range prevIDs {
+		if !slices.Contains(ids, id) {
+			diff.RulesRemoved = append(diff.RulesRemoved, id)
+		}
+	}
+
+	if diff.DataChanged {
+		diff.DataKeysChanged = changedKeys(prevData, data)
+	}
+
+	return diff
+}
+
+func ruleIDs(rules []policy.RuleStats) []string {
+	ids := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Endpoint == policy.DefaultRule {
+			continue
+		}
+		ids = append(ids, strings.Join(rule.Method, ",")+" "+rule.Endpoint)
+	}
+	slices.Sort(ids)
+
+	return ids
+}
+
+// changedKeys returns sorted top-level keys which differ, nil if any of the data isn't an object
+func changedKeys(prevData, data interface{}) []string {
+	prev, ok := prevData.(map[string]interface{})
+	if !ok && prevData != nil {
+		return nil
+	}
+	next, ok := data.(map[string]interface{})
+	if !ok && data != nil {
+		return nil
+	}
+
+	keys := []string{}
+	for key, value := range next {
+		if prevValue, ok := prev[key]; !ok || !reflect.DeepEqual(prevValue, value) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range prev {
+		if _, ok := next[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	return keys
+}
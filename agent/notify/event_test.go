@@ -0,0 +1,46 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"testing"
+
+	"github.com/goauthlink/authlink/sdk/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewDiff(t *testing.T) {
+	prevRules := []policy.RuleStats{
+		{Endpoint: "/orders", Method: []string{"GET"}},
+		{Endpoint: "/legacy", Method: []string{"*"}},
+		{Endpoint: policy.DefaultRule},
+	}
+	rules := []policy.RuleStats{
+		{Endpoint: "/orders", Method: []string{"GET"}},
+		{Endpoint: "/orders", Method: []string{"POST"}},
+		{Endpoint: policy.DefaultRule},
+	}
+	prevData := map[string]interface{}{"users": []interface{}{"user1"}, "admins": []interface{}{"admin"}}
+	data := map[string]interface{}{"users": []interface{}{"user2"}, "teams": []interface{}{}}
+
+	assert.Equal(t, &Diff{
+		PolicyChanged:   true,
+		DataChanged:     true,
+		RulesAdded:      []string{"POST /orders"},
+		RulesRemoved:    []string{"* /legacy"},
+		DataKeysChanged: []string{"admins", "teams", "users"},
+	}, NewDiff(true, prevRules, rules, prevData, data))
+
+	// unchanged rules and data
+	assert.Equal(t, &Diff{PolicyChanged: true}, NewDiff(true, rules, rules, data, data))
+
+	// the first data
+	assert.Equal(t, []string{"teams", "users"}, NewDiff(false, nil, nil, nil, data).DataKeysChanged)
+
+	// keys of data which isn't an object aren't compared
+	diff := NewDiff(false, nil, nil, data, []interface{}{"user1"})
+	assert.True(t, diff.DataChanged)
+	assert.Nil(t, diff.DataKeysChanged)
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/goauthlink/authlink/pkg/logging"
+)
+
+const (
+	DefaultTimeout    = 10 * time.Second
+	DefaultBufferSize = 100
+)
+
+// Webhook posts reload events as json to the url on its own goroutine, so reloads aren't blocked.
+// Events are dropped when the buffer is full or the webhook is closed.
+type Webhook struct {
+	url     string
+	headers map[string]string
+	timeout time.Duration
+	client  *http.Client
+	logger  *slog.Logger
+	events  chan Event
+	done    chan struct{}
+	// closeMux guards sending to events against closing them
+	closeMux sync.RWMutex
+	closed   bool
+}
+
+type WebhookOpt func(*Webhook)
+
+func WithLogger(logger *slog.Logger) WebhookOpt {
+	return func(w *Webhook) {
+		w.logger = logger
+	}
+}
+
+// WithHeaders sets headers sent with every event, e.g. authorization of the receiver
+func WithHeaders(headers map[string]string) WebhookOpt {
+	return func(w *Webhook) {
+		w.headers = headers
+	}
+}
+
+// WithTimeout limits a single post, DefaultTimeout by default
+func WithTimeout(timeout time.Duration) WebhookOpt {
+	return func(w *Webhook) {
+		w.timeout = timeout
+	}
+}
+
+func NewWebhook(url string, opts ...WebhookOpt) *Webhook {
+	w := &Webhook{
+		url:     url,
+		timeout: DefaultTimeout,
+		client:  http.DefaultClient,
+		events:  make(chan Event, DefaultBufferSize),
+		done:    make(chan struct{}),
+	}
+
+	for _, o := range opts {
+		o(w)
+	}
+
+	if w.logger == nil {
+		w.logger = logging.NewNullLogger()
+	}
+
+	go w.run()
+
+	return w
+}
+
+// Notify schedules posting of the event, events notified after Close are dropped
+func (w *Webhook) Notify(event Event) {
+	w.closeMux.RLock()
+	defer w.closeMux.RUnlock()
+
+	if w.closed {
+		return
+	}
+
+	select {
+	case w.events <- event:
+	default:
+		w.logger.Error(fmt.Sprintf("reload webhook buffer is full, event of revision %s is dropped", event.Revision))
+	}
+}
+
+// Close posts scheduled events and stops the webhook
+func (w *Webhook) Close() {
+	w.closeMux.Lock()
+	if w.closed {
+		w.closeMux.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.events)
+	w.closeMux.Unlock()
+
+	<-w.done
+}
+
+func (w *Webhook) run() {
+	defer close(w.done)
+
+	for event := range w.events {
+		if err := w.post(event); err != nil {
+			w.logger.Error(fmt.Sprintf("posting reload event to webhook failed: %s", err.Error()))
+		}
+	}
+}
+
+func (w *Webhook) post(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	rq, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	rq.Header.Set("Content-Type", "application/json")
+	for name, value := range w.headers {
+		rq.Header.Set(name, value)
+	}
+
+	rsp, err := w.client.Do(rq)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	io.Copy(io.Discard, rsp.Body) //nolint: errcheck
+
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %d", rsp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Webhook(t *testing.T) {
+	var (
+		mux     sync.Mutex
+		events  []Event
+		headers []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := Event{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+
+		mux.Lock()
+		defer mux.Unlock()
+		events = append(events, event)
+		headers = append(headers, r.Header.Get("Authorization"))
+
+		// a failed post doesn't stop the next ones
+		if len(events) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	webhook := NewWebhook(srv.URL, WithHeaders(map[string]string{"Authorization": "Bearer token"}))
+	webhook.Notify(Event{Status: StatusFailure, Error: "invalid policy"})
+	webhook.Notify(Event{Status: StatusSuccess, Revision: "abc", Diff: &Diff{PolicyChanged: true}})
+	webhook.Close()
+
+	require.Len(t, events, 2)
+	assert.Equal(t, "invalid policy", events[0].Error)
+	assert.Equal(t, "abc", events[1].Revision)
+	assert.True(t, events[1].Diff.PolicyChanged)
+	assert.Equal(t, []string{"Bearer token", "Bearer token"}, headers)
+
+	// events of updates applied while the agent stops are dropped
+	assert.NotPanics(t, func() {
+		webhook.Notify(Event{Status: StatusSuccess, Revision: "def"})
+		webhook.Close()
+	})
+	assert.Len(t, events, 2)
+}
@@ -9,54 +9,134 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/goauthlink/authlink/agent/decision"
 	"github.com/goauthlink/authlink/pkg/metrics"
 	"github.com/goauthlink/authlink/sdk/policy"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Policy struct {
 	checker             *policy.Checker
-	checkLogger         *CheckLogger
+	decisions           *decision.Logger
 	counterRqTotal      metrics.Metric
 	counterRqFailed     metrics.Metric
+	counterInvalidCn    metrics.Metric
 	histogramRqDuration metrics.Metric
+	// series bounds cardinality of labels of check_rq_total
+	series           *seriesLimiter
+	metricsClients   []string
+	metricsMaxSeries int
+	// dataStale reports whether the data is older than the allowed staleness
+	dataStale func() bool
+	// failClosed disables jsonpath allow entries while the data is stale
+	failClosed bool
+	// revision returns the active revision of policy and data for decision events
+	revision func() string
+	// tracer traces checks, nil disables tracing
+	tracer trace.Tracer
+	// meter creates metrics of checks, the global meter provider is used if it's nil
+	meter *metrics.Meter
+}
+
+type PolicyOpt func(*Policy)
+
+// WithMetricsClients sets client names labelled in decision metrics, other clients are counted as other
+func WithMetricsClients(clients []string) PolicyOpt {
+	return func(p *Policy) {
+		p.metricsClients = clients
+	}
+}
+
+// WithMetricsMaxSeries caps distinct label sets of decision metrics, DefaultMetricsMaxSeries by default
+func WithMetricsMaxSeries(maxSeries int) PolicyOpt {
+	return func(p *Policy) {
+		p.metricsMaxSeries = maxSeries
+	}
+}
+
+// WithTracer traces checks by the tracer
+func WithTracer(tracer trace.Tracer) PolicyOpt {
+	return func(p *Policy) {
+		p.tracer = tracer
+	}
+}
+
+// WithMeter creates metrics of checks by the meter instead of the global meter provider
+func WithMeter(meter *metrics.Meter) PolicyOpt {
+	return func(p *Policy) {
+		p.meter = meter
+	}
 }
 
 func NewPolicy(
 	checker *policy.Checker,
-	checkLogger *CheckLogger,
+	decisions *decision.Logger,
+	opts ...PolicyOpt,
 ) *Policy {
-	counterRqTotal, _ := metrics.NewCounter("check_rq_total", "A counter of check requests")
-	counterRqFailed, _ := metrics.NewCounter("check_rq_failed", "A counter of failed check requests (500 response code)")
-	histogramRqDuration, _ := metrics.NewHistogram("check_rq_duration_ms", "A histogram of duration for check requests",
+	p := &Policy{
+		checker:          checker,
+		decisions:        decisions,
+		metricsMaxSeries: DefaultMetricsMaxSeries,
+	}
+
+	for _, o := range opts {
+		o(p)
+	}
+
+	p.counterRqTotal, _ = p.meter.NewCounter("check_rq_total", "A counter of check requests by result, rule, method, client name source and client")
+	p.counterRqFailed, _ = p.meter.NewCounter("check_rq_failed", "A counter of failed check requests (500 response code)")
+	p.counterInvalidCn, _ = p.meter.NewCounter("check_invalid_client_name_total", "A counter of denied check requests with invalid client name by reason and client name source")
+	p.histogramRqDuration, _ = p.meter.NewHistogram("check_rq_duration_ms", "A histogram of duration for check requests",
 		1, 2, 5, 10, 20, 100, 1000,
 	)
 
-	return &Policy{
-		checker:             checker,
-		checkLogger:         checkLogger,
-		counterRqTotal:      counterRqTotal,
-		counterRqFailed:     counterRqFailed,
-		histogramRqDuration: histogramRqDuration,
-	}
+	p.series = newSeriesLimiter(p.metricsClients, p.metricsMaxSeries)
+
+	return p
 }
 
-func (p *Policy) Check(_ context.Context, in policy.CheckInput) (*policy.CheckResult, error) {
+func (p *Policy) Check(ctx context.Context, in policy.CheckInput) (result *policy.CheckResult, err error) {
 	start := time.Now()
 
 	defer func() {
 		finish := time.Since(start)
-		p.histogramRqDuration.Record(float64(finish.Milliseconds()), nil)
-		p.counterRqTotal.Record(1, nil)
+		labels := p.series.labels(in, result, err)
+		p.histogramRqDuration.Record(float64(finish.Milliseconds()), map[string]string{"result": labels.result})
+		p.counterRqTotal.Record(1, labels.attrs())
+		if invalidCnLabels, ok := invalidClientNameLabels(result); ok {
+			p.counterInvalidCn.Record(1, invalidCnLabels)
+		}
 	}()
 
-	result, err := p.checker.Check(in)
+	staleData := p.dataStale != nil && p.dataStale()
+
+	opts := []policy.CheckOpt{}
+	if staleData && p.failClosed {
+		opts = append(opts, policy.WithoutData())
+	}
+
+	var span trace.Span
+	if p.tracer != nil {
+		ctx, span = p.startCheckSpan(ctx, in)
+		opts = append(opts, policy.WithContext(ctx))
+	}
+
+	result, err = p.checker.Check(in, opts...)
+	if span != nil {
+		p.endCheckSpan(span, result, staleData, err)
+	}
 	if err != nil {
 		p.counterRqFailed.Record(1, nil)
 		return result, fmt.Errorf("policy check failed: %w", err)
 	}
 
-	if p.checkLogger != nil {
-		p.checkLogger.Log(in, *result)
+	if p.decisions != nil {
+		event := decision.NewEvent(in, result, time.Since(start))
+		event.StaleData = staleData && result.DataUsed
+		if p.revision != nil {
+			event.Revision = p.revision()
+		}
+		p.decisions.Log(event)
 	}
 
 	return result, nil
@@ -76,6 +156,11 @@ func (p *Policy) SetPolicy(policy []byte) error {
 	return p.checker.SetPolicy(policy)
 }
 
+// SetPolicyWithData activates the policy and the data at once, the previous ones stay active on failure
+func (p *Policy) SetPolicyWithData(policy, data []byte) error {
+	return p.checker.SetPolicyWithData(policy, data)
+}
+
 func (p *Policy) Policy() []byte {
 	return p.checker.Policy()
 }
@@ -0,0 +1,148 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"time"
+
+	"github.com/goauthlink/authlink/agent/admin"
+	"github.com/goauthlink/authlink/agent/notify"
+)
+
+// ReloadStatus describes policy and data updates since the agent was created
+type ReloadStatus struct {
+	// Attempts counts updates from the sources including unchanged content, Failures counts failed ones
+	Attempts uint64 `json:"attempts"`
+	Failures uint64 `json:"failures"`
+	// LastAttempt and LastSuccess are times of the last update and the last successful one
+	LastAttempt *time.Time `json:"lastAttempt,omitempty"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	// LastError is the error of the last update, the previous policy and data stay active
+	LastError string `json:"lastError,omitempty"`
+	// Revision is the revision of the source, PolicyRevision and DataRevision are digests
+	// of the active policy and data
+	Revision       string `json:"revision,omitempty"`
+	PolicyRevision string `json:"policyRevision,omitempty"`
+	DataRevision   string `json:"dataRevision,omitempty"`
+}
+
+// contentRevision is the revision of policy or data as the admin api reports it, empty for absent content
+func contentRevision(content []byte) string {
+	if content == nil {
+		return ""
+	}
+
+	return admin.Revision(content)
+}
+
+// content returns the active policy and data as they were activated
+func (a *Agent) content() ([]byte, []byte) {
+	a.statusMux.RLock()
+	defer a.statusMux.RUnlock()
+
+	return a.policyContent, a.dataContent
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+// ReloadStatus returns the status of policy and data updates
+func (a *Agent) ReloadStatus() ReloadStatus {
+	a.statusMux.RLock()
+	defer a.statusMux.RUnlock()
+
+	status := ReloadStatus{
+		Attempts:       a.updateAttempts,
+		Failures:       a.updateFailures,
+		LastAttempt:    timePtr(a.lastUpdate),
+		LastSuccess:    timePtr(a.dataUpdated),
+		Revision:       a.revision,
+		PolicyRevision: a.policyRevision,
+		DataRevision:   a.dataRevision,
+	}
+	if a.lastUpdateErr != nil {
+		status.LastError = a.lastUpdateErr.Error()
+	}
+
+	return status
+}
+
+// initReloadMetrics registers metrics of updates and the webhook of reload events
+func (a *Agent) initReloadMetrics() error {
+	counterUpdateFailed, err := a.meter.NewCounter("policy_update_failed", "A counter of failed policy and data updates")
+	if err != nil {
+		return err
+	}
+	a.counterUpdateFailed = counterUpdateFailed
+
+	counterUpdateTotal, err := a.meter.NewCounter("policy_update_total", "A counter of policy and data update attempts")
+	if err != nil {
+		return err
+	}
+	a.counterUpdateTotal = counterUpdateTotal
+
+	err = a.meter.NewObservableGauge("policy_update_last_success_timestamp_seconds", "Unix time of the last successful policy and data update (seconds)", func() float64 {
+		a.statusMux.RLock()
+		defer a.statusMux.RUnlock()
+
+		if a.dataUpdated.IsZero() {
+			return 0
+		}
+		return float64(a.dataUpdated.UnixNano()) / float64(time.Second)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = a.meter.NewObservableInfo("policy_revision_info", "Revisions of the active policy and data", func() map[string]string {
+		status := a.ReloadStatus()
+		if status.LastSuccess == nil {
+			return nil
+		}
+
+		return map[string]string{
+			"revision":        status.Revision,
+			"policy_revision": status.PolicyRevision,
+			"data_revision":   status.DataRevision,
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(a.config.ReloadWebhookURL) > 0 {
+		a.webhook = notify.NewWebhook(a.config.ReloadWebhookURL, notify.WithLogger(a.logger))
+	}
+
+	return nil
+}
+
+// notifyUpdate posts the event of the changed or failed update to the webhook, it's called under updateMux
+func (a *Agent) notifyUpdate(err error, diff *notify.Diff) {
+	if a.webhook == nil {
+		return
+	}
+
+	status := a.ReloadStatus()
+	event := notify.Event{
+		Timestamp:      time.Now().UTC(),
+		Status:         notify.StatusSuccess,
+		Revision:       status.Revision,
+		PolicyRevision: status.PolicyRevision,
+		DataRevision:   status.DataRevision,
+		Diff:           diff,
+	}
+	if err != nil {
+		event.Status = notify.StatusFailure
+		event.Error = err.Error()
+	}
+
+	a.webhook.Notify(event)
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goauthlink/authlink/agent/notify"
+	"github.com/goauthlink/authlink/test/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testWebhook struct {
+	mux    sync.Mutex
+	events []notify.Event
+}
+
+func (h *testWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	event := notify.Event{}
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	h.mux.Lock()
+	h.events = append(h.events, event)
+	h.mux.Unlock()
+}
+
+func (h *testWebhook) received() []notify.Event {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	return append([]notify.Event{}, h.events...)
+}
+
+func Test_ReloadStatus(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	webhook := &testWebhook{}
+	webhookSrv := httptest.NewServer(webhook)
+	defer webhookSrv.Close()
+
+	config := DefaultConfig()
+	config.LogLevel = slog.LevelError
+	config.PolicyFilePath = rootDir + "/policy.yaml"
+	config.DataFilePath = rootDir + "/data.json"
+	config.ReloadWebhookURL = webhookSrv.URL
+
+	agent, err := Init(config)
+	require.NoError(t, err)
+
+	status := agent.ReloadStatus()
+	assert.Equal(t, uint64(1), status.Attempts)
+	assert.Equal(t, uint64(0), status.Failures)
+	require.NotNil(t, status.LastSuccess)
+	assert.Equal(t, status.LastAttempt, status.LastSuccess)
+	assert.Equal(t, agent.Revision(), status.Revision)
+	assert.Equal(t, contentRevision([]byte(testPolicy)), status.PolicyRevision)
+	assert.Equal(t, contentRevision([]byte(testData)), status.DataRevision)
+
+	// unchanged content isn't sent
+	require.NoError(t, agent.reloadSources(context.Background()))
+	assert.Equal(t, uint64(2), agent.ReloadStatus().Attempts)
+
+	require.NoError(t, util.ReWriteFileContent(rootDir+"/data.json", []byte(`{"users":`)))
+	require.Error(t, agent.reloadSources(context.Background()))
+
+	status = agent.ReloadStatus()
+	assert.Equal(t, uint64(3), status.Attempts)
+	assert.Equal(t, uint64(1), status.Failures)
+	assert.Contains(t, status.LastError, "invalid json format")
+	assert.True(t, status.LastAttempt.After(*status.LastSuccess))
+	assert.Equal(t, contentRevision([]byte(testData)), status.DataRevision)
+
+	newPolicy := testPolicy + `
+  - uri: ["/endpoint2"]
+    allow: ["client2"]`
+	require.NoError(t, util.ReWriteFileContent(rootDir+"/policy.yaml", []byte(newPolicy)))
+	require.NoError(t, util.ReWriteFileContent(rootDir+"/data.json", []byte(`{"users":["user1"],"admins":["admin"]}`)))
+	require.NoError(t, agent.reloadSources(context.Background()))
+
+	status = agent.ReloadStatus()
+	assert.Empty(t, status.LastError)
+	assert.Equal(t, contentRevision([]byte(newPolicy)), status.PolicyRevision)
+
+	agent.webhook.Close()
+	events := webhook.received()
+	require.Len(t, events, 3)
+
+	assert.Equal(t, notify.StatusSuccess, events[0].Status)
+	assert.Equal(t, []string{"* /endpoint"}, events[0].Diff.RulesAdded)
+
+	assert.Equal(t, notify.StatusFailure, events[1].Status)
+	assert.Contains(t, events[1].Error, "invalid json format")
+	assert.Nil(t, events[1].Diff)
+
+	assert.Equal(t, notify.StatusSuccess, events[2].Status)
+	assert.Equal(t, status.PolicyRevision, events[2].PolicyRevision)
+	assert.Equal(t, &notify.Diff{
+		PolicyChanged:   true,
+		DataChanged:     true,
+		RulesAdded:      []string{"* /endpoint2"},
+		DataKeysChanged: []string{"admins", "users"},
+	}, events[2].Diff)
+	assert.WithinDuration(t, time.Now(), events[2].Timestamp, time.Minute)
+}
@@ -0,0 +1,182 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/goauthlink/authlink/pkg/logging"
+)
+
+// PolicySource provides the policy of the agent.
+type PolicySource interface {
+	// Load returns the current policy.
+	Load(ctx context.Context) ([]byte, error)
+	// Subscribe calls notify when the policy may have changed until the context is done.
+	// Notifications without changes are allowed, unchanged content isn't applied again.
+	Subscribe(ctx context.Context, notify func()) error
+}
+
+// DataSource provides dynamic data of the policy.
+type DataSource interface {
+	// Load returns the current data, nil if there is no data.
+	Load(ctx context.Context) ([]byte, error)
+	// Subscribe calls notify when the data may have changed until the context is done.
+	// Notifications without changes are allowed, unchanged content isn't applied again.
+	Subscribe(ctx context.Context, notify func()) error
+}
+
+// DataUpdateTimer is implemented by data sources which know when their data was
+// last confirmed current, e.g. by the time of the last successful fetch. For other
+// sources the time of the last successful load is used.
+type DataUpdateTimer interface {
+	// LastUpdated returns the time the data was last confirmed current, zero if it never was
+	LastUpdated() time.Time
+}
+
+// FileSource reads the policy or data from a file
+type FileSource struct {
+	path     string
+	interval time.Duration
+	watch    bool
+	logger   *slog.Logger
+}
+
+type FileSourceOpt func(*FileSource)
+
+// WithFileUpdateInterval notifies about possible changes every interval
+func WithFileUpdateInterval(interval time.Duration) FileSourceOpt {
+	return func(s *FileSource) {
+		s.interval = interval
+	}
+}
+
+// WithFileWatch notifies about changes using file system notifications
+func WithFileWatch() FileSourceOpt {
+	return func(s *FileSource) {
+		s.watch = true
+	}
+}
+
+func WithFileLogger(logger *slog.Logger) FileSourceOpt {
+	return func(s *FileSource) {
+		s.logger = logger
+	}
+}
+
+func NewFileSource(path string, opts ...FileSourceOpt) *FileSource {
+	s := &FileSource{path: path}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	if s.logger == nil {
+		s.logger = logging.NewNullLogger()
+	}
+
+	return s
+}
+
+func (s *FileSource) Load(_ context.Context) ([]byte, error) {
+	return os.ReadFile(s.path)
+}
+
+func (s *FileSource) Subscribe(ctx context.Context, notify func()) error {
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+
+	if s.watch {
+		watcher, err := newFilesWatcher([]string{s.path}, watchFilesDebounce, s.logger)
+		if err != nil {
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			watcher.Run(ctx, notify)
+		}()
+	}
+
+	if s.interval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ticker := time.NewTicker(s.interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					notify()
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	<-ctx.Done()
+
+	return nil
+}
+
+// MemorySource holds the policy or data set by the program embedding the agent
+type MemorySource struct {
+	mux         sync.Mutex
+	content     []byte
+	subscribers []*func()
+}
+
+func NewMemorySource(content []byte) *MemorySource {
+	return &MemorySource{content: slices.Clone(content)}
+}
+
+// Set replaces the content and notifies subscribers, the agent applies it asynchronously
+func (s *MemorySource) Set(content []byte) {
+	s.mux.Lock()
+	s.content = slices.Clone(content)
+	subscribers := slices.Clone(s.subscribers)
+	s.mux.Unlock()
+
+	for _, notify := range subscribers {
+		(*notify)()
+	}
+}
+
+func (s *MemorySource) Load(_ context.Context) ([]byte, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return slices.Clone(s.content), nil
+}
+
+func (s *MemorySource) Subscribe(ctx context.Context, notify func()) error {
+	subscriber := &notify
+
+	s.mux.Lock()
+	s.subscribers = append(s.subscribers, subscriber)
+	s.mux.Unlock()
+
+	// the content may have been set before subscribing
+	notify()
+
+	<-ctx.Done()
+
+	s.mux.Lock()
+	s.subscribers = slices.DeleteFunc(s.subscribers, func(sub *func()) bool {
+		return sub == subscriber
+	})
+	s.mux.Unlock()
+
+	return nil
+}
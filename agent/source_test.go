@@ -0,0 +1,282 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goauthlink/authlink/pkg/logging"
+	"github.com/goauthlink/authlink/sdk/policy"
+	"github.com/goauthlink/authlink/test/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FileSource(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	source := NewFileSource(rootDir+"/policy.yaml", WithFileUpdateInterval(20*time.Millisecond), WithFileWatch())
+
+	content, err := source.Load(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []byte(testPolicy), content)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	notified := atomic.Int32{}
+	done := make(chan error)
+	go func() {
+		done <- source.Subscribe(ctx, func() { notified.Add(1) })
+	}()
+
+	assert.Eventually(t, func() bool { return notified.Load() > 1 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	_, err = NewFileSource(rootDir + "/unknown.yaml").Load(context.Background())
+	require.Error(t, err)
+}
+
+func Test_MemorySource(t *testing.T) {
+	source := NewMemorySource([]byte("content1"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	notified := make(chan struct{}, 10)
+	done := make(chan error)
+	go func() {
+		done <- source.Subscribe(ctx, func() { notified <- struct{}{} })
+	}()
+
+	// the subscriber is notified right after subscribing
+	<-notified
+
+	source.Set([]byte("content2"))
+	<-notified
+
+	content, err := source.Load(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []byte("content2"), content)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	// unsubscribed
+	source.Set([]byte("content3"))
+	assert.Empty(t, notified)
+}
+
+func Test_NewWithSources(t *testing.T) {
+	config := DefaultConfig()
+	config.HttpAddr = "127.0.0.1:0"
+	config.MonitoringAddr = "127.0.0.1:0"
+
+	policySource := NewMemorySource([]byte(`cn:
+  - header: "x-source"
+policies:
+  - uri: ["/endpoint"]
+    allow: ["{.users[*]}"]`))
+	dataSource := NewMemorySource([]byte(testData))
+
+	agent, err := New(
+		WithConfig(config),
+		WithAgentLogger(logging.NewNullLogger()),
+		WithPolicySource(policySource),
+		WithDataSource(dataSource),
+	)
+	require.NoError(t, err)
+
+	check := func(client string) bool {
+		result, err := agent.Policy().Check(context.Background(), policy.CheckInput{
+			Uri:     "/endpoint",
+			Method:  "GET",
+			Headers: map[string]string{"x-source": client},
+		})
+		return err == nil && result.Allow
+	}
+
+	assert.True(t, check("user1"))
+	assert.False(t, check("user3"))
+
+	stop := make(chan struct{}, 1)
+	go func() {
+		agent.Run(stop) //nolint: errcheck
+	}()
+	defer func() {
+		stop <- struct{}{}
+		agent.WaitUntilCompletion()
+	}()
+
+	dataSource.Set([]byte(`{"users":["user3"]}`))
+
+	assert.Eventually(t, func() bool { return check("user3") }, 2*time.Second, 10*time.Millisecond)
+	assert.False(t, check("user1"))
+
+	// invalid policy isn't applied
+	policySource.Set([]byte("policies: {"))
+
+	assert.Eventually(t, func() bool { return agent.LastUpdateErr() != nil }, 2*time.Second, 10*time.Millisecond)
+	assert.True(t, check("user3"))
+}
+
+func Test_NewDataSourceWithoutPolicy(t *testing.T) {
+	_, err := New(
+		WithAgentLogger(logging.NewNullLogger()),
+		WithDataSource(NewMemorySource([]byte(testData))),
+	)
+	require.EqualError(t, err, errDataSourceWithoutPolicySource)
+}
+
+func Test_ReloadMergesNotifications(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	config := DefaultConfig()
+	config.LogLevel = slog.LevelError
+	config.PolicyFilePath = rootDir + "/policy.yaml"
+	config.DataFilePath = rootDir + "/data.json"
+
+	agent, err := Init(config)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		agent.runReload(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	newPolicy := `cn:
+  - header: "x-source"
+policies:
+  - uri: ["/endpoint"]
+    allow: ["{.clients[*]}"]`
+
+	// notifications of the policy and data changes are merged into a single reload
+	require.NoError(t, util.ReWriteFileContent(rootDir+"/policy.yaml", []byte(newPolicy)))
+	agent.notifyReload()
+	require.NoError(t, util.ReWriteFileContent(rootDir+"/data.json", []byte(`{"clients":["client1"]}`)))
+	agent.notifyReload()
+
+	assert.Eventually(t, func() bool {
+		return string(agent.policy.Policy()) == newPolicy
+	}, 2*time.Second, 10*time.Millisecond)
+	require.NoError(t, agent.LastUpdateErr())
+	assert.Equal(t, map[string]interface{}{"clients": []interface{}{"client1"}}, agent.policy.Data())
+}
+
+// timedSource reports the time its data was last updated
+type timedSource struct {
+	*MemorySource
+	updated time.Time
+}
+
+func (s *timedSource) LastUpdated() time.Time {
+	return s.updated
+}
+
+func Test_DataStaleness(t *testing.T) {
+	policyContent := []byte(`cn:
+  - header: "x-source"
+policies:
+  - uri: ["/endpoint"]
+    allow: ["client", "{.users[*]}"]`)
+
+	for _, mode := range []StaleMode{StaleModeServe, StaleModeFailClosed, StaleModeUnready} {
+		config := DefaultConfig()
+		config.DataMaxStaleness = time.Minute
+		config.DataStaleMode = mode
+
+		dataSource := &timedSource{MemorySource: NewMemorySource([]byte(testData)), updated: time.Now()}
+
+		agent, err := New(
+			WithConfig(config),
+			WithAgentLogger(logging.NewNullLogger()),
+			WithPolicySource(NewMemorySource(policyContent)),
+			WithDataSource(dataSource),
+		)
+		require.NoError(t, err)
+		// servers aren't started, only the data staleness affects readiness
+		agent.servers = nil
+
+		check := func(client string) bool {
+			result, err := agent.Policy().Check(context.Background(), policy.CheckInput{
+				Uri:     "/endpoint",
+				Headers: map[string]string{"x-source": client},
+			})
+			require.NoError(t, err)
+			return result.Allow
+		}
+
+		assert.False(t, agent.DataStale(), mode)
+		assert.True(t, check("user1"), mode)
+		assert.NoError(t, agent.readinessCheck(), mode)
+
+		dataSource.updated = time.Now().Add(-2 * time.Minute)
+
+		assert.True(t, agent.DataStale(), mode)
+		assert.True(t, check("client"), mode)
+		assert.Equal(t, mode != StaleModeFailClosed, check("user1"), mode)
+
+		if mode == StaleModeUnready {
+			assert.ErrorContains(t, agent.readinessCheck(), "data is stale")
+		} else {
+			assert.NoError(t, agent.readinessCheck(), mode)
+		}
+	}
+}
+
+func Test_DataAgeWithoutUpdateTimer(t *testing.T) {
+	config := DefaultConfig()
+	config.DataMaxStaleness = time.Minute
+
+	agent, err := New(
+		WithConfig(config),
+		WithAgentLogger(logging.NewNullLogger()),
+		WithPolicySource(NewMemorySource([]byte(testPolicy))),
+		WithDataSource(NewMemorySource([]byte(testData))),
+	)
+	require.NoError(t, err)
+
+	// the time of the last successful load is used
+	assert.False(t, agent.DataUpdated().IsZero())
+	assert.Less(t, agent.DataAge(), time.Minute)
+	assert.False(t, agent.DataStale())
+}
+
+func Test_DataAgeInvalidContent(t *testing.T) {
+	dataSource := NewMemorySource([]byte(testData))
+
+	agent, err := New(
+		WithConfig(DefaultConfig()),
+		WithAgentLogger(logging.NewNullLogger()),
+		WithPolicySource(NewMemorySource([]byte(testPolicy))),
+		WithDataSource(dataSource),
+	)
+	require.NoError(t, err)
+
+	dataSource.Set([]byte("{invalid"))
+	_, err = agent.loadSources(context.Background())
+	require.Error(t, err)
+	updated := agent.DataUpdated()
+
+	// loading the same invalid content again doesn't confirm the active data
+	_, err = agent.loadSources(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, updated, agent.DataUpdated())
+
+	dataSource.Set([]byte(testData))
+	_, err = agent.loadSources(context.Background())
+	require.NoError(t, err)
+	assert.True(t, agent.DataUpdated().After(updated))
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/goauthlink/authlink/sdk/policy"
+)
+
+// RuleStatsPattern is the route of hit counters of rules on the monitoring server
+const RuleStatsPattern = "GET /v1/stats/rules"
+
+const errInvalidUnusedFor = "unusedFor must be a positive duration, e.g. 720h"
+
+type ruleStatsResponse struct {
+	Rules []policy.RuleStats `json:"rules"`
+	Error string             `json:"error,omitempty"`
+}
+
+// RuleStats returns hit counters of rules of the active policy
+func (p *Policy) RuleStats() []policy.RuleStats {
+	return p.checker.RuleStats()
+}
+
+// unusedRules returns rules which weren't hit during the period before now. Rules loaded later
+// than the period started aren't returned, as they weren't observed during the whole period.
+func unusedRules(stats []policy.RuleStats, period time.Duration, now time.Time) []policy.RuleStats {
+	since := now.Add(-period)
+
+	unused := []policy.RuleStats{}
+	for _, rule := range stats {
+		if rule.LoadedAt.After(since) {
+			continue
+		}
+		if rule.LastHit != nil && !rule.LastHit.Before(since) {
+			continue
+		}
+		unused = append(unused, rule)
+	}
+
+	return unused
+}
+
+// ruleStatsHandler responds hit counters of rules, with the unusedFor query parameter
+// only rules not hit during that period
+func ruleStatsHandler(p *Policy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		stats := p.RuleStats()
+		if stats == nil {
+			stats = []policy.RuleStats{}
+		}
+
+		if unusedFor := r.URL.Query().Get("unusedFor"); len(unusedFor) > 0 {
+			period, err := time.ParseDuration(unusedFor)
+			if err != nil || period <= 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(ruleStatsResponse{Error: errInvalidUnusedFor}) //nolint: errcheck
+				return
+			}
+			stats = unusedRules(stats, period, time.Now())
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(ruleStatsResponse{Rules: stats}) //nolint: errcheck
+	})
+}
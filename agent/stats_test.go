@@ -0,0 +1,76 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goauthlink/authlink/sdk/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RuleStatsHandler(t *testing.T) {
+	checker := policy.NewChecker()
+	require.NoError(t, checker.SetPolicy([]byte(`
+cn:
+  - header: "x-source"
+policies:
+  - uri: ["/ep1"]
+    allow: ["client1"]`)))
+	p := NewPolicy(checker, nil)
+
+	_, err := p.Check(context.Background(), policy.CheckInput{Uri: "/ep1", Headers: map[string]string{"x-source": "client1"}})
+	require.NoError(t, err)
+
+	handler := ruleStatsHandler(p)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:9191/v1/stats/rules", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	rsp := ruleStatsResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rsp))
+	require.Len(t, rsp.Rules, 2)
+	assert.Equal(t, "/ep1", rsp.Rules[0].Endpoint)
+	assert.Equal(t, uint64(1), rsp.Rules[0].Allow)
+	assert.Equal(t, policy.DefaultRule, rsp.Rules[1].Endpoint)
+
+	// rules loaded during the period aren't reported as unused
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:9191/v1/stats/rules?unusedFor=1h", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"rules":[]}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:9191/v1/stats/rules?unusedFor=week", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"rules":null,"error":"`+errInvalidUnusedFor+`"}`, w.Body.String())
+}
+
+func Test_UnusedRules(t *testing.T) {
+	now := time.Now()
+	hit := func(ago time.Duration) *time.Time {
+		t := now.Add(-ago)
+		return &t
+	}
+
+	stats := []policy.RuleStats{
+		{Endpoint: "/never", LoadedAt: now.Add(-48 * time.Hour)},
+		{Endpoint: "/old", LoadedAt: now.Add(-48 * time.Hour), LastHit: hit(30 * time.Hour)},
+		{Endpoint: "/recent", LoadedAt: now.Add(-48 * time.Hour), LastHit: hit(time.Hour)},
+		{Endpoint: "/new", LoadedAt: now.Add(-time.Hour)},
+	}
+
+	unused := unusedRules(stats, 24*time.Hour, now)
+	require.Len(t, unused, 2)
+	assert.Equal(t, "/never", unused[0].Endpoint)
+	assert.Equal(t, "/old", unused[1].Endpoint)
+}
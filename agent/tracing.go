@@ -0,0 +1,117 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/goauthlink/authlink/pkg/tracing"
+	"github.com/goauthlink/authlink/sdk/policy"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/goauthlink/authlink/agent"
+
+const (
+	SpanCheck = "authlink.check"
+
+	AttrMethod    = attribute.Key("http.request.method")
+	AttrPath      = attribute.Key("url.path")
+	AttrRule      = attribute.Key("authlink.rule")
+	AttrRevision  = attribute.Key("authlink.revision")
+	AttrStaleData = attribute.Key("authlink.stale_data")
+	// AttrError is the category of the error of the decision, messages aren't exported
+	// since they may contain request values
+	AttrError = attribute.Key("authlink.error")
+)
+
+// ErrorEvaluation is the error category of rules which couldn't be evaluated, e.g. a failed jsonpath query.
+// Invalid client names are categorized by policy.InvalidClientNameReason.
+const ErrorEvaluation = "evaluation"
+
+// WithTracerProvider traces checks with the provider instead of the OTLP exporter of the config,
+// the provider is owned by the caller
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(a *Agent) {
+		a.tracerProvider = provider
+	}
+}
+
+// initTracing creates the provider exporting spans to the OTLP collector of the config
+func (a *Agent) initTracing() error {
+	provider, err := tracing.NewProvider(context.Background(), tracing.Config{
+		Endpoint:    a.config.TracingEndpoint,
+		Insecure:    a.config.TracingInsecure,
+		SampleRatio: a.config.TracingSampleRatio,
+	})
+	if err != nil {
+		return fmt.Errorf("init tracing: %w", err)
+	}
+
+	a.tracerProvider = provider
+	a.shutdownTracing = provider.Shutdown
+
+	return nil
+}
+
+// startCheckSpan starts the span of the check as a child of the remote span of the context,
+// or of traceparent and tracestate headers of the input if the context doesn't contain it
+func (p *Policy) startCheckSpan(ctx context.Context, in policy.CheckInput) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = tracing.Propagator.Extract(ctx, propagation.MapCarrier(in.Headers))
+	}
+
+	// the query may carry tokens and personal data, so it isn't exported
+	path, _, _ := strings.Cut(in.Uri, "?")
+
+	return p.tracer.Start(ctx, SpanCheck,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			AttrMethod.String(in.Method),
+			AttrPath.String(path),
+		))
+}
+
+// endCheckSpan records the decision of the check and ends the span
+func (p *Policy) endCheckSpan(span trace.Span, result *policy.CheckResult, staleData bool, err error) {
+	defer span.End()
+
+	// messages of errors may contain request values, only their categories are exported
+	if err != nil {
+		category := errorCategory(err)
+		span.SetAttributes(AttrError.String(category))
+		span.SetStatus(codes.Error, category)
+		return
+	}
+
+	span.SetAttributes(
+		policy.AttrAllow.Bool(result.Allow),
+		policy.AttrClientName.String(result.ClientName),
+		AttrRule.String(result.Endpoint),
+		AttrStaleData.Bool(staleData && result.DataUsed),
+	)
+	if p.revision != nil {
+		span.SetAttributes(AttrRevision.String(p.revision()))
+	}
+	if result.Err != nil {
+		span.SetAttributes(AttrError.String(errorCategory(result.Err)))
+	}
+}
+
+// errorCategory returns the fixed category of the error of the decision
+func errorCategory(err error) string {
+	var invalidCn policy.ErrInvalidClientName
+	if errors.As(err, &invalidCn) {
+		return string(invalidCn.Reason())
+	}
+
+	return ErrorEvaluation
+}
@@ -0,0 +1,140 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/goauthlink/authlink/sdk/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_CheckTracing(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	config := DefaultConfig()
+	config.LogLevel = slog.LevelError
+	config.PolicyFilePath = rootDir + "/policy.yaml"
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	agent, err := New(WithConfig(config), WithTracerProvider(provider))
+	require.NoError(t, err)
+
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	_, err = agent.policy.Check(context.Background(), policy.CheckInput{
+		Uri:     "/endpoint",
+		Method:  "GET",
+		Headers: map[string]string{"x-source": "client", "traceparent": traceparent},
+	})
+	require.NoError(t, err)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range exporter.GetSpans().Snapshots() {
+		spans[span.Name()] = span
+	}
+
+	check, ok := spans[SpanCheck]
+	require.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", check.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", check.Parent().SpanID().String())
+	assert.True(t, check.Parent().IsRemote())
+
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, attr := range check.Attributes() {
+		attrs[attr.Key] = attr.Value
+	}
+	assert.Equal(t, "GET", attrs[AttrMethod].AsString())
+	assert.Equal(t, "/endpoint", attrs[AttrPath].AsString())
+	assert.True(t, attrs[policy.AttrAllow].AsBool())
+	assert.Equal(t, "client", attrs[policy.AttrClientName].AsString())
+	assert.Equal(t, "/endpoint", attrs[AttrRule].AsString())
+	assert.Equal(t, agent.Revision(), attrs[AttrRevision].AsString())
+	assert.False(t, attrs[AttrStaleData].AsBool())
+
+	// spans of the checker are children of the check
+	resolve, ok := spans[policy.SpanResolveClientName]
+	require.True(t, ok)
+	assert.Equal(t, check.SpanContext().SpanID(), resolve.Parent().SpanID())
+
+	// checks aren't traced without the provider
+	exporter.Reset()
+	agent, err = New(WithConfig(config))
+	require.NoError(t, err)
+	_, err = agent.policy.Check(context.Background(), policy.CheckInput{
+		Uri:     "/endpoint",
+		Headers: map[string]string{"x-source": "client", "traceparent": traceparent},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, exporter.GetSpans())
+}
+
+func Test_CheckTracingErrorCategory(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	config := DefaultConfig()
+	config.LogLevel = slog.LevelError
+	config.PolicyFilePath = rootDir + "/policy.yaml"
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	agent, err := New(WithConfig(config), WithTracerProvider(provider))
+	require.NoError(t, err)
+
+	_, err = agent.policy.Check(context.Background(), policy.CheckInput{
+		Uri:     "/endpoint?token=secret",
+		Headers: map[string]string{},
+	})
+	require.NoError(t, err)
+
+	checkSpan := func() sdktrace.ReadOnlySpan {
+		var check sdktrace.ReadOnlySpan
+		for _, span := range exporter.GetSpans().Snapshots() {
+			if span.Name() == SpanCheck {
+				check = span
+			}
+		}
+		require.NotNil(t, check)
+		return check
+	}
+	spanAttrs := func(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+		attrs := map[attribute.Key]attribute.Value{}
+		for _, attr := range span.Attributes() {
+			attrs[attr.Key] = attr.Value
+		}
+		return attrs
+	}
+
+	attrs := spanAttrs(checkSpan())
+	assert.Equal(t, string(policy.ReasonUndefinedClientName), attrs[AttrError].AsString())
+	// the query isn't exported
+	assert.Equal(t, "/endpoint", attrs[AttrPath].AsString())
+
+	// failed checks export the category only
+	exporter.Reset()
+	agent.policy.checker = policy.NewChecker()
+	_, err = agent.policy.Check(context.Background(), policy.CheckInput{Uri: "/endpoint"})
+	require.Error(t, err)
+
+	check := checkSpan()
+	assert.Equal(t, codes.Error, check.Status().Code)
+	assert.Equal(t, ErrorEvaluation, check.Status().Description)
+	assert.Empty(t, check.Events())
+	assert.Equal(t, ErrorEvaluation, spanAttrs(check)[AttrError].AsString())
+
+	assert.Equal(t, ErrorEvaluation, errorCategory(errors.New("jsonpath: unknown key token-value")))
+}
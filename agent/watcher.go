@@ -0,0 +1,108 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const watchFilesDebounce = 100 * time.Millisecond
+
+// filesWatcher watches directories of the files, so replacing a file by rename
+// and kubernetes configmap symlink swaps (..data) are noticed as well.
+type filesWatcher struct {
+	watcher  *fsnotify.Watcher
+	files    map[string]struct{}
+	debounce time.Duration
+	logger   *slog.Logger
+}
+
+func newFilesWatcher(paths []string, debounce time.Duration, logger *slog.Logger) (*filesWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("create files watcher: %w", err)
+	}
+
+	fw := &filesWatcher{
+		watcher:  watcher,
+		files:    map[string]struct{}{},
+		debounce: debounce,
+		logger:   logger,
+	}
+
+	dirs := map[string]struct{}{}
+	for _, path := range paths {
+		if len(path) == 0 {
+			continue
+		}
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			watcher.Close() //nolint: errcheck
+			return nil, fmt.Errorf("watch file %s: %w", path, err)
+		}
+		fw.files[absPath] = struct{}{}
+		dirs[filepath.Dir(absPath)] = struct{}{}
+	}
+
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close() //nolint: errcheck
+			return nil, fmt.Errorf("watch directory %s: %w", dir, err)
+		}
+	}
+
+	return fw, nil
+}
+
+// isRelevant reports whether the event may change content of the watched files
+func (fw *filesWatcher) isRelevant(ev fsnotify.Event) bool {
+	if ev.Has(fsnotify.Chmod) && !ev.Has(fsnotify.Write) {
+		return false
+	}
+
+	if _, ok := fw.files[filepath.Clean(ev.Name)]; ok {
+		return true
+	}
+
+	// kubernetes atomic writer swaps ..data symlink pointing to a timestamped directory
+	return strings.HasPrefix(filepath.Base(ev.Name), "..")
+}
+
+// Run calls onChange once per burst of events until the context is done
+func (fw *filesWatcher) Run(ctx context.Context, onChange func()) {
+	defer fw.watcher.Close() //nolint: errcheck
+
+	timer := time.NewTimer(fw.debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case ev, ok := <-fw.watcher.Events:
+			if !ok {
+				return
+			}
+			if fw.isRelevant(ev) {
+				timer.Reset(fw.debounce)
+			}
+		case err, ok := <-fw.watcher.Errors:
+			if !ok {
+				return
+			}
+			fw.logger.Error(fmt.Sprintf("files watcher: %s", err.Error()))
+		case <-timer.C:
+			onChange()
+		}
+	}
+}
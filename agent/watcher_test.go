@@ -0,0 +1,125 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goauthlink/authlink/pkg/logging"
+	"github.com/goauthlink/authlink/test/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runTestWatcher(t *testing.T, paths []string) (chan struct{}, func()) {
+	watcher, err := newFilesWatcher(paths, 50*time.Millisecond, logging.NewNullLogger())
+	require.NoError(t, err)
+
+	changes := make(chan struct{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watcher.Run(ctx, func() {
+			changes <- struct{}{}
+		})
+		close(done)
+	}()
+
+	return changes, func() {
+		cancel()
+		<-done
+	}
+}
+
+func waitChanges(changes chan struct{}, timeout time.Duration) int {
+	count := 0
+	deadline := time.After(timeout)
+	for {
+		select {
+		case <-changes:
+			count++
+		case <-deadline:
+			return count
+		}
+	}
+}
+
+func Test_WatcherDebounce(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	changes, stop := runTestWatcher(t, []string{rootDir + "/policy.yaml", rootDir + "/data.json"})
+	defer stop()
+
+	// a burst of writes is a single change
+	for i := 0; i < 5; i++ {
+		require.NoError(t, util.ReWriteFileContent(rootDir+"/policy.yaml", []byte(testPolicy)))
+		require.NoError(t, util.ReWriteFileContent(rootDir+"/data.json", []byte(testData)))
+	}
+	assert.Equal(t, 1, waitChanges(changes, 500*time.Millisecond))
+
+	// other files of the directory are ignored
+	require.NoError(t, util.ReWriteFileContent(rootDir+"/data.txt", []byte("text2")))
+	assert.Equal(t, 0, waitChanges(changes, 300*time.Millisecond))
+}
+
+func Test_WatcherConfigMapSwap(t *testing.T) {
+	rootDir, cleanFs, err := util.MakeTmpFs("", t.Name(), map[string][]byte{
+		"..2025_01_01/policy.yaml": []byte(testPolicy),
+	})
+	require.NoError(t, err)
+	defer cleanFs()
+
+	// layout of a mounted configmap: policy.yaml -> ..data/policy.yaml, ..data -> ..2025_01_01
+	require.NoError(t, os.Symlink("..2025_01_01", filepath.Join(rootDir, "..data")))
+	require.NoError(t, os.Symlink("..data/policy.yaml", filepath.Join(rootDir, "policy.yaml")))
+
+	changes, stop := runTestWatcher(t, []string{rootDir + "/policy.yaml"})
+	defer stop()
+
+	newPolicy := `cn:
+  - header: "x-source2"
+policies:
+  - uri: ["/endpoint2"]
+    allow: ["client2"]`
+
+	require.NoError(t, os.Mkdir(filepath.Join(rootDir, "..2025_01_02"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(rootDir, "..2025_01_02", "policy.yaml"), []byte(newPolicy), 0o644))
+	require.NoError(t, os.Symlink("..2025_01_02", filepath.Join(rootDir, "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(rootDir, "..data_tmp"), filepath.Join(rootDir, "..data")))
+
+	assert.Equal(t, 1, waitChanges(changes, 500*time.Millisecond))
+
+	content, err := os.ReadFile(rootDir + "/policy.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, newPolicy, string(content))
+}
+
+func Test_UpdateFilesUnchanged(t *testing.T) {
+	rootDir, cleanFs := createFiles(t)
+	defer cleanFs()
+
+	config := DefaultConfig()
+	config.LogLevel = slog.LevelError
+	config.PolicyFilePath = rootDir + "/policy.yaml"
+
+	agent, err := Init(config)
+	require.NoError(t, err)
+
+	updated, err := agent.loadSources(context.Background())
+	require.NoError(t, err)
+	assert.False(t, updated)
+
+	require.NoError(t, util.ReWriteFileContent(rootDir+"/policy.yaml", []byte(testPolicy+"\n")))
+
+	updated, err = agent.loadSources(context.Background())
+	require.NoError(t, err)
+	assert.True(t, updated)
+}
@@ -27,7 +27,10 @@ func (r *EnvoyExtension) ConfigRunCmd(cmd *cobra.Command) {
 }
 
 func (r *EnvoyExtension) Server(runArgs []string, agent *agent.Agent) (agent.Server, error) {
-	envoyServer, err := envoy.New(grpcAddr, agent.Policy(), envoy.WithLogger(agent.Logger()))
+	envoyServer, err := envoy.New(grpcAddr, agent.Policy(),
+		envoy.WithLogger(agent.Logger()),
+		envoy.WithMeter(agent.Meter()),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("start envoy server: %w", err)
 	}
@@ -18,8 +18,8 @@ type statshandler struct {
 	grpcRqDuration metrics.Metric
 }
 
-func newStatsHandler() (*statshandler, error) {
-	grpcRqDuration, err := metrics.NewHistogram(
+func newStatsHandler(meter *metrics.Meter) (*statshandler, error) {
+	grpcRqDuration, err := meter.NewHistogram(
 		"envoy_grpc_handler_duration_seconds",
 		"A histogram of duration for grpc requests.",
 		0.00005,
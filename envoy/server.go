@@ -9,10 +9,13 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"sync/atomic"
 
 	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"github.com/goauthlink/authlink/agent"
 	"github.com/goauthlink/authlink/pkg/logging"
+	"github.com/goauthlink/authlink/pkg/metrics"
+	"github.com/goauthlink/authlink/pkg/tracing"
 	"github.com/goauthlink/authlink/sdk/policy"
 	rpc_code "google.golang.org/genproto/googleapis/rpc/code"
 	rpc_status "google.golang.org/genproto/googleapis/rpc/status"
@@ -27,21 +30,26 @@ func WithLogger(logger *slog.Logger) ServerOpt {
 	}
 }
 
+// WithMeter creates metrics of grpc requests by the meter instead of the global meter provider,
+// e.g. the meter of the agent
+func WithMeter(meter *metrics.Meter) ServerOpt {
+	return func(s *Server) {
+		s.meter = meter
+	}
+}
+
 type Server struct {
 	server *grpc.Server
+	meter  *metrics.Meter
 	logger *slog.Logger
 	policy *agent.Policy
 	addr   string
+
+	listening atomic.Bool
 }
 
 func New(addr string, policy *agent.Policy, opts ...ServerOpt) (*Server, error) {
-	statshandler, err := newStatsHandler()
-	if err != nil {
-		return nil, err
-	}
-
 	srv := &Server{
-		server: grpc.NewServer(grpc.StatsHandler(statshandler)),
 		policy: policy,
 		addr:   addr,
 	}
@@ -54,6 +62,12 @@ func New(addr string, policy *agent.Policy, opts ...ServerOpt) (*Server, error)
 		srv.logger = logging.NewNullLogger()
 	}
 
+	statshandler, err := newStatsHandler(srv.meter)
+	if err != nil {
+		return nil, err
+	}
+	srv.server = grpc.NewServer(grpc.StatsHandler(statshandler))
+
 	return srv, nil
 }
 
@@ -67,6 +81,9 @@ func (s *Server) Start(_ context.Context) error {
 
 	s.logger.Info(fmt.Sprintf("grpc server is starting on %s", s.addr))
 
+	s.listening.Store(true)
+	defer s.listening.Store(false)
+
 	if err := s.server.Serve(listener); err != nil {
 		return fmt.Errorf("serve grpc server: %w", err)
 	}
@@ -74,6 +91,11 @@ func (s *Server) Start(_ context.Context) error {
 	return nil
 }
 
+// Listening reports whether the server accepts connections
+func (s *Server) Listening() bool {
+	return s.listening.Load()
+}
+
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.server.Stop()
 	s.logger.Info("grpc server stopped")
@@ -89,7 +111,8 @@ func (s *Server) Check(ctx context.Context, rq *authv3.CheckRequest) (*authv3.Ch
 
 	out := &authv3.CheckResponse{}
 
-	result, err := s.policy.Check(ctx, in)
+	// the span of the authorization call is the parent of the check, otherwise headers of the request are
+	result, err := s.policy.Check(tracing.ExtractIncoming(ctx), in)
 	if err != nil {
 		errmsg := fmt.Sprintf("envoy check handler: %s", err.Error())
 		s.logger.Error(errmsg)
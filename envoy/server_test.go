@@ -8,20 +8,24 @@ import (
 	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"github.com/goauthlink/authlink/agent"
 	"github.com/goauthlink/authlink/sdk/policy"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	rpc_code "google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/grpc/metadata"
 )
 
-func newTestServer(t *testing.T, pol string) *Server {
+func newTestServer(t *testing.T, pol string, opts ...agent.PolicyOpt) *Server {
 	checker := policy.NewChecker()
 	require.NoError(t, checker.SetPolicy([]byte(pol)))
 
-	policy := agent.NewPolicy(checker, nil)
+	policy := agent.NewPolicy(checker, nil, opts...)
 	srv, err := New(":0", policy)
 	require.NoError(t, err)
 
@@ -93,3 +97,60 @@ policies:
 
 	assert.Equal(t, int32(rpc_code.Code_PERMISSION_DENIED), out.Status.Code)
 }
+
+func Test_CheckTracing(t *testing.T) {
+	pol := `
+cn:
+  - header: "x-source"
+policies:
+  - uri: ["/endpoint"]
+    allow: ["client1"]`
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	srv := newTestServer(t, pol, agent.WithTracer(provider.Tracer("test")))
+
+	var req authv3.CheckRequest
+	require.NoError(t, json.Unmarshal([]byte(envoyRequest), &req))
+
+	// the span of the authorization call is the parent
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))
+
+	out, err := srv.Check(ctx, &req)
+	require.NoError(t, err)
+	assert.Equal(t, int32(rpc_code.Code_OK), out.Status.Code)
+
+	var check sdktrace.ReadOnlySpan
+	for _, span := range exporter.GetSpans().Snapshots() {
+		if span.Name() == agent.SpanCheck {
+			check = span
+		}
+	}
+	require.NotNil(t, check)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", check.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", check.Parent().SpanID().String())
+}
+
+func Test_Listening(t *testing.T) {
+	pol := `
+cn:
+  - header: "x-source"
+policies:
+  - uri: ["/endpoint"]
+    allow: ["client1"]`
+
+	srv := newTestServer(t, pol)
+	assert.False(t, srv.Listening())
+
+	done := make(chan error)
+	go func() {
+		done <- srv.Start(context.Background())
+	}()
+
+	assert.Eventually(t, srv.Listening, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, srv.Shutdown(context.Background()))
+	<-done
+	assert.False(t, srv.Listening())
+}
@@ -18,25 +18,36 @@ import (
 	"go.opentelemetry.io/otel/sdk/metric"
 )
 
-var meter = func() api.Meter {
-	return otel.GetMeterProvider().Meter("authlink-agent")
+const meterName = "authlink-agent"
+
+// Meter creates metrics recorded by its meter provider. The nil Meter and package functions
+// use the global provider at the time the metric is created.
+type Meter struct {
+	provider api.MeterProvider
+}
+
+// NewMeter creates metrics with the provider, the global provider is used if it's nil
+func NewMeter(provider api.MeterProvider) *Meter {
+	return &Meter{provider: provider}
+}
+
+func (m *Meter) meter() api.Meter {
+	if m == nil || m.provider == nil {
+		return otel.GetMeterProvider().Meter(meterName)
+	}
+
+	return m.provider.Meter(meterName)
 }
 
 type Metric interface {
 	Record(val float64, attr map[string]string)
 }
 
+// RegisterPrometheusExporter sets the global meter provider exporting metrics to the default prometheus registry
 func RegisterPrometheusExporter() (http.Handler, error) {
-	promOpts := []otelprom.Option{
-		otelprom.WithoutScopeInfo(),
-		otelprom.WithoutTargetInfo(),
-		otelprom.WithoutUnits(),
-		otelprom.WithoutCounterSuffixes(),
-	}
-
-	prom, err := otelprom.New(promOpts...)
+	prom, err := newPrometheusReader(prometheus.DefaultRegisterer)
 	if err != nil {
-		return nil, fmt.Errorf("creating prometheus client: %w", err)
+		return nil, err
 	}
 
 	mp := metric.NewMeterProvider(metric.WithReader(prom))
@@ -47,12 +58,31 @@ func RegisterPrometheusExporter() (http.Handler, error) {
 	return handler, nil
 }
 
+func newPrometheusReader(registerer prometheus.Registerer) (metric.Reader, error) {
+	prom, err := otelprom.New(
+		otelprom.WithRegisterer(registerer),
+		otelprom.WithoutScopeInfo(),
+		otelprom.WithoutTargetInfo(),
+		otelprom.WithoutUnits(),
+		otelprom.WithoutCounterSuffixes(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating prometheus client: %w", err)
+	}
+
+	return prom, nil
+}
+
 type counter struct {
 	c api.Float64Counter
 }
 
 func NewCounter(name, desc string) (Metric, error) {
-	apiCounter, err := meter().Float64Counter(name, api.WithDescription(desc))
+	return (*Meter)(nil).NewCounter(name, desc)
+}
+
+func (m *Meter) NewCounter(name, desc string) (Metric, error) {
+	apiCounter, err := m.meter().Float64Counter(name, api.WithDescription(desc))
 	if err != nil {
 		return nil, fmt.Errorf("new otel float64 counter %s: %w", name, err)
 	}
@@ -61,13 +91,17 @@ func NewCounter(name, desc string) (Metric, error) {
 	}, nil
 }
 
-func withAttrs(attr map[string]string) api.MeasurementOption {
+func attributes(attr map[string]string) []attribute.KeyValue {
 	kv := make([]attribute.KeyValue, 0, len(attr))
 	for k, v := range attr {
 		kv = append(kv, attribute.Key(k).String(v))
 	}
 
-	return api.WithAttributes(kv...)
+	return kv
+}
+
+func withAttrs(attr map[string]string) api.MeasurementOption {
+	return api.WithAttributes(attributes(attr)...)
 }
 
 func (c *counter) Record(val float64, attr map[string]string) {
@@ -79,12 +113,44 @@ func (c *counter) Record(val float64, attr map[string]string) {
 	c.c.Add(context.Background(), val, opts...)
 }
 
+type upDownCounter struct {
+	c api.Float64UpDownCounter
+}
+
+// NewUpDownCounter creates the counter which may be decreased by negative values, e.g. requests in flight
+func NewUpDownCounter(name, desc string) (Metric, error) {
+	return (*Meter)(nil).NewUpDownCounter(name, desc)
+}
+
+func (m *Meter) NewUpDownCounter(name, desc string) (Metric, error) {
+	apiCounter, err := m.meter().Float64UpDownCounter(name, api.WithDescription(desc))
+	if err != nil {
+		return nil, fmt.Errorf("new otel float64 up down counter %s: %w", name, err)
+	}
+
+	return &upDownCounter{
+		c: apiCounter,
+	}, nil
+}
+
+func (c *upDownCounter) Record(val float64, attr map[string]string) {
+	opts := []api.AddOption{}
+	if len(attr) > 0 {
+		opts = append(opts, withAttrs(attr))
+	}
+	c.c.Add(context.Background(), val, opts...)
+}
+
 type histogram struct {
 	h api.Float64Histogram
 }
 
 func NewHistogram(name, desc string, bounds ...float64) (Metric, error) {
-	apiHistogram, err := meter().Float64Histogram(name,
+	return (*Meter)(nil).NewHistogram(name, desc, bounds...)
+}
+
+func (m *Meter) NewHistogram(name, desc string, bounds ...float64) (Metric, error) {
+	apiHistogram, err := m.meter().Float64Histogram(name,
 		api.WithDescription(desc),
 		api.WithExplicitBucketBoundaries(bounds...),
 	)
@@ -105,3 +171,74 @@ func (h *histogram) Record(val float64, attr map[string]string) {
 
 	h.h.Record(context.Background(), val, opts...)
 }
+
+// NewObservableGauge registers the gauge whose value is taken from observe on every collection
+func NewObservableGauge(name, desc string, observe func() float64) error {
+	return (*Meter)(nil).NewObservableGauge(name, desc, observe)
+}
+
+func (m *Meter) NewObservableGauge(name, desc string, observe func() float64) error {
+	_, err := m.meter().Float64ObservableGauge(name,
+		api.WithDescription(desc),
+		api.WithFloat64Callback(func(_ context.Context, o api.Float64Observer) error {
+			o.Observe(observe())
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("new otel float64 observable gauge %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Observation is a value of a gauge with its labels
+type Observation struct {
+	Value  float64
+	Labels map[string]string
+}
+
+// NewObservableGauges registers the gauge whose values of every label set are taken from observe on every collection
+func NewObservableGauges(name, desc string, observe func() []Observation) error {
+	return (*Meter)(nil).NewObservableGauges(name, desc, observe)
+}
+
+func (m *Meter) NewObservableGauges(name, desc string, observe func() []Observation) error {
+	_, err := m.meter().Float64ObservableGauge(name,
+		api.WithDescription(desc),
+		api.WithFloat64Callback(func(_ context.Context, o api.Float64Observer) error {
+			for _, observation := range observe() {
+				o.Observe(observation.Value, api.WithAttributes(attributes(observation.Labels)...))
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("new otel float64 observable gauge %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// NewObservableInfo registers the gauge with value 1 whose labels are taken from observe on every collection,
+// e.g. the active version. Nothing is observed while observe returns nil.
+func NewObservableInfo(name, desc string, observe func() map[string]string) error {
+	return (*Meter)(nil).NewObservableInfo(name, desc, observe)
+}
+
+func (m *Meter) NewObservableInfo(name, desc string, observe func() map[string]string) error {
+	_, err := m.meter().Float64ObservableGauge(name,
+		api.WithDescription(desc),
+		api.WithFloat64Callback(func(_ context.Context, o api.Float64Observer) error {
+			if attr := observe(); attr != nil {
+				o.Observe(1, api.WithAttributes(attributes(attr)...))
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("new otel float64 observable gauge %s: %w", name, err)
+	}
+
+	return nil
+}
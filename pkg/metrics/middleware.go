@@ -7,19 +7,51 @@ package metrics
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
+const (
+	// RouteUnmatched is the route label of requests which didn't match a ServeMux pattern
+	RouteUnmatched = "unmatched"
+	// MethodOther is the method label of non-standard methods
+	MethodOther = "other"
+)
+
+var knownMethods = map[string]struct{}{
+	http.MethodGet: {}, http.MethodHead: {}, http.MethodPost: {}, http.MethodPut: {}, http.MethodPatch: {},
+	http.MethodDelete: {}, http.MethodConnect: {}, http.MethodOptions: {}, http.MethodTrace: {},
+}
+
+// responseWriter records the status code and the size of the response
 type responseWriter struct {
 	http.ResponseWriter
 	status int
+	size   int
 }
 
 func (rec *responseWriter) WriteHeader(code int) {
-	rec.status = code
+	if rec.status == 0 {
+		rec.status = code
+	}
 	rec.ResponseWriter.WriteHeader(code)
 }
 
+func (rec *responseWriter) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.size += n
+
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rec *responseWriter) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
 var httpTimeRqBucket = []float64{
 	0.00005,
 	0.0001,
@@ -36,31 +68,132 @@ var httpTimeRqBucket = []float64{
 	1,
 }
 
-func NewHTTPMiddleware(h http.Handler) (http.Handler, error) {
-	durationMetric, err := NewHistogram("http_request_time_seconds", "A histogram of duration for http requests.", httpTimeRqBucket...)
+var httpResponseSizeBucket = []float64{
+	100,
+	1000,
+	10000,
+	100000,
+	1000000,
+}
+
+type httpMiddleware struct {
+	next   http.Handler
+	server string
+
+	duration Metric
+	total    Metric
+	size     Metric
+	inFlight Metric
+}
+
+// HTTPOpt configures the http metrics middleware
+type HTTPOpt func(*httpMiddleware)
+
+// WithServerLabel sets the server label distinguishing servers whose middlewares share the meter,
+// the label is omitted if it's empty
+func WithServerLabel(server string) HTTPOpt {
+	return func(m *httpMiddleware) {
+		m.server = server
+	}
+}
+
+// NewHTTPMiddleware records metrics of requests served by the handler with the global meter provider
+func NewHTTPMiddleware(h http.Handler, opts ...HTTPOpt) (http.Handler, error) {
+	return (*Meter)(nil).NewHTTPMiddleware(h, opts...)
+}
+
+// NewHTTPMiddleware records metrics of requests served by the handler:
+//
+//   - http_request_time_seconds histogram and http_request_total counter labelled by route, method and code
+//   - http_response_size_bytes histogram labelled by route, method and code
+//   - http_requests_in_flight gauge labelled by method
+//
+// The route is the pattern of http.ServeMux matching the request without the method, e.g. /v1/policy
+// for "GET /v1/policy", so parameterised routes don't blow up label sets. Requests not matched by
+// a pattern and handlers other than ServeMux are labelled as RouteUnmatched, non-standard methods
+// as MethodOther. The middleware must wrap the ServeMux:
+//
+//	router := http.NewServeMux()
+//	router.HandleFunc("GET /v1/items/{id}", getItem)
+//	handler, err := meter.NewHTTPMiddleware(router, metrics.WithServerLabel("admin"))
+func (m *Meter) NewHTTPMiddleware(h http.Handler, opts ...HTTPOpt) (http.Handler, error) {
+	mw := &httpMiddleware{next: h}
+	for _, o := range opts {
+		o(mw)
+	}
+
+	var err error
+	mw.duration, err = m.NewHistogram("http_request_time_seconds", "A histogram of duration for http requests.", httpTimeRqBucket...)
 	if err != nil {
 		return nil, err
 	}
 
-	rqCount, err := NewCounter("http_request_total", "Aggregate HTTP response codes (e.g., 2xx, 3xx, etc.)")
+	mw.total, err = m.NewCounter("http_request_total", "A counter of http requests by route pattern, method and response status code.")
 	if err != nil {
 		return nil, err
 	}
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		startTime := time.Now()
-		rw := &responseWriter{w, 0}
+	mw.size, err = m.NewHistogram("http_response_size_bytes", "A histogram of size of http response bodies.", httpResponseSizeBucket...)
+	if err != nil {
+		return nil, err
+	}
+
+	mw.inFlight, err = m.NewUpDownCounter("http_requests_in_flight", "A number of http requests being served.")
+	if err != nil {
+		return nil, err
+	}
+
+	return mw, nil
+}
+
+func (mw *httpMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := r.Method
+	if _, ok := knownMethods[method]; !ok {
+		method = MethodOther
+	}
+
+	inFlightAttrs := mw.attrs(map[string]string{"method": method})
+	mw.inFlight.Record(1, inFlightAttrs)
+	defer mw.inFlight.Record(-1, inFlightAttrs)
 
-		h.ServeHTTP(rw, r)
+	startTime := time.Now()
+	rw := &responseWriter{ResponseWriter: w}
 
-		attrs := map[string]string{
-			"code": strconv.Itoa(rw.status),
-			"url":  r.URL.Path,
-		}
+	// ServeMux sets the pattern of the request it serves
+	mw.next.ServeHTTP(rw, r)
 
-		duration := time.Since(startTime).Seconds()
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+
+	attrs := mw.attrs(map[string]string{
+		"route":  route(r.Pattern),
+		"method": method,
+		"code":   strconv.Itoa(rw.status),
+	})
+
+	mw.duration.Record(time.Since(startTime).Seconds(), attrs)
+	mw.total.Record(1, attrs)
+	mw.size.Record(float64(rw.size), attrs)
+}
+
+func (mw *httpMiddleware) attrs(attrs map[string]string) map[string]string {
+	if len(mw.server) > 0 {
+		attrs["server"] = mw.server
+	}
+
+	return attrs
+}
+
+// route returns the path of the ServeMux pattern, patterns are "[METHOD ][HOST]/[PATH]"
+func route(pattern string) string {
+	if len(pattern) == 0 {
+		return RouteUnmatched
+	}
+
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return strings.TrimLeft(path, " \t")
+	}
 
-		durationMetric.Record(duration, attrs)
-		rqCount.Record(1, attrs)
-	}), nil
+	return pattern
 }
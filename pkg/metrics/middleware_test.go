@@ -0,0 +1,128 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// collect returns data points of the metric by their label sets
+func collect(t *testing.T, reader metric.Reader, name string) map[attribute.Distinct]float64 {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	points := map[attribute.Distinct]float64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			switch data := m.Data.(type) {
+			case metricdata.Sum[float64]:
+				for _, dp := range data.DataPoints {
+					points[dp.Attributes.Equivalent()] = dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					points[dp.Attributes.Equivalent()] = dp.Sum
+				}
+			}
+		}
+	}
+
+	return points
+}
+
+func labels(kv ...string) attribute.Distinct {
+	attrs := make([]attribute.KeyValue, 0, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		attrs = append(attrs, attribute.String(kv[i], kv[i+1]))
+	}
+
+	set := attribute.NewSet(attrs...)
+
+	return set.Equivalent()
+}
+
+func Test_HTTPMiddleware(t *testing.T) {
+	reader := metric.NewManualReader()
+	meter := NewMeter(metric.NewMeterProvider(metric.WithReader(reader)))
+
+	router := http.NewServeMux()
+	router.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("item " + r.PathValue("id"))) //nolint: errcheck
+	})
+	router.HandleFunc("/any", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	handler, err := meter.NewHTTPMiddleware(router, WithServerLabel("admin"))
+	require.NoError(t, err)
+
+	for _, rq := range []struct{ method, target string }{
+		{http.MethodGet, "/items/1"},
+		{http.MethodGet, "/items/2"},
+		{http.MethodGet, "/unknown"},
+		{"PURGE", "/any"},
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(rq.method, rq.target, nil))
+	}
+
+	// parameterised paths share the route of the pattern
+	assert.Equal(t, map[attribute.Distinct]float64{
+		labels("server", "admin", "route", "/items/{id}", "method", "GET", "code", "200"):  2,
+		labels("server", "admin", "route", RouteUnmatched, "method", "GET", "code", "404"): 1,
+		labels("server", "admin", "route", "/any", "method", MethodOther, "code", "202"):   1,
+	}, collect(t, reader, "http_request_total"))
+
+	sizes := collect(t, reader, "http_response_size_bytes")
+	assert.Equal(t, float64(len("item 1")+len("item 2")), sizes[labels("server", "admin", "route", "/items/{id}", "method", "GET", "code", "200")])
+	assert.Equal(t, 0.0, sizes[labels("server", "admin", "route", "/any", "method", MethodOther, "code", "202")])
+
+	assert.Len(t, collect(t, reader, "http_request_time_seconds"), 3)
+}
+
+func Test_HTTPMiddlewareInFlight(t *testing.T) {
+	reader := metric.NewManualReader()
+	meter := NewMeter(metric.NewMeterProvider(metric.WithReader(reader)))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := http.NewServeMux()
+	router.HandleFunc("POST /check", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	handler, err := meter.NewHTTPMiddleware(router)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/check", nil))
+		close(done)
+	}()
+
+	<-started
+	assert.Equal(t, map[attribute.Distinct]float64{labels("method", "POST"): 1}, collect(t, reader, "http_requests_in_flight"))
+
+	close(release)
+	<-done
+	assert.Equal(t, map[attribute.Distinct]float64{labels("method", "POST"): 0}, collect(t, reader, "http_requests_in_flight"))
+
+	// the server label is omitted if it isn't set
+	assert.Equal(t, map[attribute.Distinct]float64{
+		labels("route", "/check", "method", "POST", "code", "200"): 1,
+	}, collect(t, reader, "http_request_total"))
+}
@@ -0,0 +1,129 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/goauthlink/authlink/pkg"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const (
+	DefaultServiceName  = "authlink-agent"
+	DefaultOTLPInterval = time.Minute
+
+	errNoExporters       = "prometheus or otlp exporter of metrics is required"
+	errOTLPIntervalValue = "otlp push interval of metrics must not be negative"
+)
+
+// Config defines exporters of the meter provider
+type Config struct {
+	// Prometheus enables the pull exporter served by Provider.Handler
+	Prometheus bool
+	// OTLPEndpoint is the host:port of the OTLP gRPC collector receiving metrics, push is disabled if it's empty
+	OTLPEndpoint string
+	// OTLPInsecure disables TLS of the collector connection
+	OTLPInsecure bool
+	// OTLPInterval is the period of pushes, DefaultOTLPInterval by default
+	OTLPInterval time.Duration
+	// ServiceName is the service.name resource attribute of pushed metrics, DefaultServiceName by default
+	ServiceName string
+}
+
+func (c Config) Validate() error {
+	if !c.Prometheus && len(c.OTLPEndpoint) == 0 {
+		return errors.New(errNoExporters)
+	}
+
+	if c.OTLPInterval < 0 {
+		return errors.New(errOTLPIntervalValue)
+	}
+
+	return nil
+}
+
+// Provider is the meter provider with its own exporters, so metrics of several providers
+// in one process don't mix
+type Provider struct {
+	*metric.MeterProvider
+	handler http.Handler
+}
+
+// NewProvider creates the meter provider exporting to the prometheus registry of the provider
+// and pushing to the OTLP collector, it must be shut down to push the last metrics
+func NewProvider(ctx context.Context, config Config) (*Provider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &Provider{}
+	var opts []metric.Option
+
+	if config.Prometheus {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		)
+
+		prom, err := newPrometheusReader(registry)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, metric.WithReader(prom))
+		p.handler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	}
+
+	if len(config.OTLPEndpoint) > 0 {
+		exporterOpts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(config.OTLPEndpoint)}
+		if config.OTLPInsecure {
+			exporterOpts = append(exporterOpts, otlpmetricgrpc.WithInsecure())
+		}
+
+		exporter, err := otlpmetricgrpc.New(ctx, exporterOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("creating otlp metric exporter: %w", err)
+		}
+
+		interval := config.OTLPInterval
+		if interval == 0 {
+			interval = DefaultOTLPInterval
+		}
+
+		serviceName := config.ServiceName
+		if len(serviceName) == 0 {
+			serviceName = DefaultServiceName
+		}
+
+		opts = append(opts,
+			metric.WithReader(metric.NewPeriodicReader(exporter, metric.WithInterval(interval))),
+			metric.WithResource(resource.NewSchemaless(
+				attribute.String("service.name", serviceName),
+				attribute.String("service.version", pkg.Version),
+			)),
+		)
+	}
+
+	p.MeterProvider = metric.NewMeterProvider(opts...)
+
+	return p, nil
+}
+
+// Handler serves metrics in the prometheus exposition format, it's nil if prometheus is disabled
+func (p *Provider) Handler() http.Handler {
+	return p.handler
+}
@@ -0,0 +1,119 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+)
+
+func scrape(t *testing.T, handler http.Handler) string {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	return w.Body.String()
+}
+
+func Test_NewProvider(t *testing.T) {
+	p1, err := NewProvider(context.Background(), Config{Prometheus: true})
+	require.NoError(t, err)
+	defer p1.Shutdown(context.Background()) //nolint: errcheck
+
+	p2, err := NewProvider(context.Background(), Config{Prometheus: true})
+	require.NoError(t, err)
+	defer p2.Shutdown(context.Background()) //nolint: errcheck
+
+	// metrics with the same name don't mix between providers
+	c1, err := NewMeter(p1).NewCounter("test_provider_total", "A test counter")
+	require.NoError(t, err)
+	c2, err := NewMeter(p2).NewCounter("test_provider_total", "A test counter")
+	require.NoError(t, err)
+
+	c1.Record(3, map[string]string{"provider": "p1"})
+	c2.Record(5, map[string]string{"provider": "p2"})
+
+	body1 := scrape(t, p1.Handler())
+	assert.Contains(t, body1, `test_provider_total{provider="p1"} 3`)
+	assert.NotContains(t, body1, `provider="p2"`)
+	assert.Contains(t, body1, "go_goroutines")
+
+	body2 := scrape(t, p2.Handler())
+	assert.Contains(t, body2, `test_provider_total{provider="p2"} 5`)
+	assert.NotContains(t, body2, `provider="p1"`)
+}
+
+type testCollector struct {
+	collectormetrics.UnimplementedMetricsServiceServer
+	mux   sync.Mutex
+	names []string
+}
+
+func (c *testCollector) Export(_ context.Context, rq *collectormetrics.ExportMetricsServiceRequest) (*collectormetrics.ExportMetricsServiceResponse, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	for _, rm := range rq.GetResourceMetrics() {
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				c.names = append(c.names, m.GetName())
+			}
+		}
+	}
+
+	return &collectormetrics.ExportMetricsServiceResponse{}, nil
+}
+
+func (c *testCollector) received(name string) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return slices.Contains(c.names, name)
+}
+
+func Test_NewProviderOTLP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	collector := &testCollector{}
+	srv := grpc.NewServer()
+	collectormetrics.RegisterMetricsServiceServer(srv, collector)
+	go srv.Serve(listener) //nolint: errcheck
+	defer srv.Stop()
+
+	p, err := NewProvider(context.Background(), Config{
+		OTLPEndpoint: listener.Addr().String(),
+		OTLPInsecure: true,
+		OTLPInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	assert.Nil(t, p.Handler())
+
+	counter, err := NewMeter(p).NewCounter("test_pushed_total", "A test counter")
+	require.NoError(t, err)
+	counter.Record(1, nil)
+
+	assert.Eventually(t, func() bool { return collector.received("test_pushed_total") }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, p.Shutdown(context.Background()))
+}
+
+func Test_NewProviderInvalid(t *testing.T) {
+	_, err := NewProvider(context.Background(), Config{})
+	require.EqualError(t, err, errNoExporters)
+
+	_, err = NewProvider(context.Background(), Config{Prometheus: true, OTLPInterval: -1})
+	require.EqualError(t, err, errOTLPIntervalValue)
+}
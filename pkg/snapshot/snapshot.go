@@ -0,0 +1,163 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package snapshot defines resources streamed by the control plane to agents over
+// the aggregated discovery service of xDS
+package snapshot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// TypeURL is the resource type of discovery requests and responses carrying snapshots
+const TypeURL = "type.authlink.io/authlink.v1.Snapshot"
+
+const (
+	errInvalidResource = "snapshot resource is invalid: %w"
+	errMissingField    = "snapshot field %s is missing"
+	errEmptyVersion    = "snapshot version is empty"
+	errUnexpectedType  = "unexpected type %s"
+)
+
+// Snapshot is a versioned policy with data of a service
+type Snapshot struct {
+	Service string
+	// Version increases with every change of the policy or data of the service
+	Version string
+	// Revision is the digest of the policy and data
+	Revision string
+	Policy   []byte
+	// Data is nil if the service has no data
+	Data []byte
+}
+
+// Revision returns the digest of the policy and data
+func Revision(policy, data []byte) string {
+	hash := sha256.New()
+	hash.Write(policy)
+	hash.Write([]byte{0})
+	hash.Write(data)
+
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// fields of the snapshot message carried by the resource:
+//
+//	message Snapshot {
+//	  string service = 1;
+//	  string version = 2;
+//	  string revision = 3;
+//	  bytes policy = 4;
+//	  optional bytes data = 5;
+//	}
+const (
+	fieldService  protowire.Number = 1
+	fieldVersion  protowire.Number = 2
+	fieldRevision protowire.Number = 3
+	fieldPolicy   protowire.Number = 4
+	fieldData     protowire.Number = 5
+)
+
+// Encode wraps the snapshot into the resource of the discovery response,
+// the policy and data are carried as bytes as is
+func Encode(s Snapshot) (*anypb.Any, error) {
+	var value []byte
+	value = appendBytes(value, fieldService, []byte(s.Service))
+	value = appendBytes(value, fieldVersion, []byte(s.Version))
+	value = appendBytes(value, fieldRevision, []byte(s.Revision))
+	value = appendBytes(value, fieldPolicy, s.Policy)
+	if s.Data != nil {
+		value = appendBytes(value, fieldData, s.Data)
+	}
+
+	return &anypb.Any{TypeUrl: TypeURL, Value: value}, nil
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// Decode unwraps the snapshot from the resource of the discovery response
+func Decode(resource *anypb.Any) (Snapshot, error) {
+	if resource.GetTypeUrl() != TypeURL {
+		return Snapshot{}, fmt.Errorf(errInvalidResource, fmt.Errorf(errUnexpectedType, resource.GetTypeUrl()))
+	}
+
+	fields := map[protowire.Number][]byte{}
+	value := resource.GetValue()
+	for len(value) > 0 {
+		num, typ, n := protowire.ConsumeTag(value)
+		if n < 0 {
+			return Snapshot{}, fmt.Errorf(errInvalidResource, protowire.ParseError(n))
+		}
+		value = value[n:]
+
+		if typ != protowire.BytesType {
+			// unknown fields are skipped
+			n = protowire.ConsumeFieldValue(num, typ, value)
+			if n < 0 {
+				return Snapshot{}, fmt.Errorf(errInvalidResource, protowire.ParseError(n))
+			}
+			value = value[n:]
+			continue
+		}
+
+		v, n := protowire.ConsumeBytes(value)
+		if n < 0 {
+			return Snapshot{}, fmt.Errorf(errInvalidResource, protowire.ParseError(n))
+		}
+		value = value[n:]
+		fields[num] = v
+	}
+
+	field := func(num protowire.Number, name string) ([]byte, error) {
+		v, ok := fields[num]
+		if !ok {
+			return nil, fmt.Errorf(errMissingField, name)
+		}
+		return v, nil
+	}
+
+	s := Snapshot{}
+	for _, f := range []struct {
+		num  protowire.Number
+		name string
+		dst  *string
+	}{
+		{fieldService, "service", &s.Service},
+		{fieldVersion, "version", &s.Version},
+		{fieldRevision, "revision", &s.Revision},
+	} {
+		v, err := field(f.num, f.name)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		*f.dst = string(v)
+	}
+
+	policy, err := field(fieldPolicy, "policy")
+	if err != nil {
+		return Snapshot{}, err
+	}
+	s.Policy = bytes.Clone(policy)
+
+	if data, ok := fields[fieldData]; ok {
+		// present empty data stays non nil
+		s.Data = bytes.Clone(data)
+	}
+
+	if len(s.Version) == 0 {
+		return Snapshot{}, errors.New(errEmptyVersion)
+	}
+
+	return s, nil
+}
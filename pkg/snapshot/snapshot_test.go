@@ -0,0 +1,96 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package snapshot
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func Test_EncodeDecode(t *testing.T) {
+	policy := []byte("cn:\n  - header: x-source\n")
+	data := []byte(`{"users":["user1"]}`)
+
+	s := Snapshot{
+		Service:  "orders",
+		Version:  "3",
+		Revision: Revision(policy, data),
+		Policy:   policy,
+		Data:     data,
+	}
+
+	resource, err := Encode(s)
+	require.NoError(t, err)
+
+	decoded, err := Decode(resource)
+	require.NoError(t, err)
+	assert.Equal(t, s, decoded)
+
+	// data is optional
+	s.Data = nil
+	resource, err = Encode(s)
+	require.NoError(t, err)
+
+	decoded, err = Decode(resource)
+	require.NoError(t, err)
+	assert.Nil(t, decoded.Data)
+
+	// empty data is kept
+	s.Data = []byte{}
+	resource, err = Encode(s)
+	require.NoError(t, err)
+
+	decoded, err = Decode(resource)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{}, decoded.Data)
+}
+
+func Test_EncodeDecodeNonUTF8(t *testing.T) {
+	s := Snapshot{
+		Service:  "orders",
+		Version:  "1",
+		Revision: "r",
+		Policy:   []byte("cn: \xff\xfe"),
+		Data:     []byte{0xc3, 0x28},
+	}
+
+	resource, err := Encode(s)
+	require.NoError(t, err)
+
+	decoded, err := Decode(resource)
+	require.NoError(t, err)
+	assert.Equal(t, s, decoded)
+}
+
+func Test_DecodeInvalid(t *testing.T) {
+	resource, err := anypb.New(structpb.NewStringValue("policy"))
+	require.NoError(t, err)
+	_, err = Decode(resource)
+	require.Error(t, err)
+
+	var value []byte
+	value = appendBytes(value, fieldService, []byte("orders"))
+	value = appendBytes(value, fieldVersion, []byte("1"))
+	value = appendBytes(value, fieldRevision, []byte("r"))
+	_, err = Decode(&anypb.Any{TypeUrl: TypeURL, Value: value})
+	require.EqualError(t, err, fmt.Sprintf(errMissingField, "policy"))
+
+	_, err = Decode(&anypb.Any{TypeUrl: TypeURL, Value: []byte{0x22, 0x05}})
+	require.Error(t, err)
+
+	value = appendBytes(value, fieldPolicy, []byte("policy"))
+	_, err = Decode(&anypb.Any{TypeUrl: TypeURL, Value: value})
+	require.NoError(t, err)
+}
+
+func Test_Revision(t *testing.T) {
+	assert.Equal(t, Revision([]byte("policy"), nil), Revision([]byte("policy"), nil))
+	assert.NotEqual(t, Revision([]byte("policy"), []byte("{}")), Revision([]byte("policy{}"), nil))
+}
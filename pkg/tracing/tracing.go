@@ -0,0 +1,121 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/goauthlink/authlink/pkg"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	DefaultServiceName = "authlink-agent"
+
+	errEndpointIsRequired = "tracing endpoint is required"
+	errInvalidSampleRatio = "tracing sample ratio must be between 0 and 1"
+)
+
+// Propagator reads and writes W3C trace context: traceparent and tracestate headers
+var Propagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+// Config defines the OTLP collector receiving spans
+type Config struct {
+	// Endpoint is the host:port of the OTLP gRPC collector
+	Endpoint string
+	// Insecure disables TLS of the collector connection
+	Insecure bool
+	// SampleRatio is the share of sampled traces started by the agent, spans of sampled
+	// remote parents are always recorded
+	SampleRatio float64
+	// ServiceName is the service.name resource attribute, DefaultServiceName by default
+	ServiceName string
+}
+
+func (c Config) Validate() error {
+	if len(c.Endpoint) == 0 {
+		return errors.New(errEndpointIsRequired)
+	}
+
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return errors.New(errInvalidSampleRatio)
+	}
+
+	return nil
+}
+
+// NewProvider creates the tracer provider batching spans to the OTLP collector,
+// it must be shut down to flush the last spans
+func NewProvider(ctx context.Context, config Config) (*sdktrace.TracerProvider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(config.Endpoint)}
+	if config.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating otlp trace exporter: %w", err)
+	}
+
+	serviceName := config.ServiceName
+	if len(serviceName) == 0 {
+		serviceName = DefaultServiceName
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", serviceName),
+			attribute.String("service.version", pkg.Version),
+		)),
+	), nil
+}
+
+// MetadataCarrier adapts grpc metadata to propagation.TextMapCarrier
+type MetadataCarrier metadata.MD
+
+func (c MetadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func (c MetadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// ExtractIncoming returns the context with the remote span of the incoming grpc metadata
+func ExtractIncoming(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	return Propagator.Extract(ctx, MetadataCarrier(md))
+}
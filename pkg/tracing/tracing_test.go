@@ -0,0 +1,50 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func Test_ConfigValidate(t *testing.T) {
+	require.NoError(t, Config{Endpoint: "localhost:4317", SampleRatio: 0.5}.Validate())
+	require.EqualError(t, Config{SampleRatio: 1}.Validate(), errEndpointIsRequired)
+	require.EqualError(t, Config{Endpoint: "localhost:4317", SampleRatio: 1.5}.Validate(), errInvalidSampleRatio)
+	require.EqualError(t, Config{Endpoint: "localhost:4317", SampleRatio: -1}.Validate(), errInvalidSampleRatio)
+}
+
+func Test_NewProvider(t *testing.T) {
+	provider, err := NewProvider(context.Background(), Config{Endpoint: "localhost:4317", Insecure: true, SampleRatio: 1})
+	require.NoError(t, err)
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	_, err = NewProvider(context.Background(), Config{})
+	require.EqualError(t, err, errEndpointIsRequired)
+}
+
+func Test_ExtractIncoming(t *testing.T) {
+	// without metadata the context is unchanged
+	ctx := ExtractIncoming(context.Background())
+	assert.False(t, trace.SpanContextFromContext(ctx).IsValid())
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"tracestate", "vendor=value",
+	))
+
+	spanCtx := trace.SpanContextFromContext(ExtractIncoming(ctx))
+	require.True(t, spanCtx.IsValid())
+	assert.True(t, spanCtx.IsRemote())
+	assert.True(t, spanCtx.IsSampled())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spanCtx.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spanCtx.SpanID().String())
+	assert.Equal(t, "vendor=value", spanCtx.TraceState().String())
+}
@@ -0,0 +1,105 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AuditMode defines which decisions of a policy are logged
+type AuditMode string
+
+const (
+	// AuditAlways logs every decision
+	AuditAlways AuditMode = "always"
+	// AuditDenyOnly logs denied decisions
+	AuditDenyOnly AuditMode = "deny-only"
+	// AuditSample logs the share of decisions set by Audit.Rate
+	AuditSample AuditMode = "sample"
+	// AuditNever doesn't log decisions
+	AuditNever AuditMode = "never"
+)
+
+const validationErrInvalidAudit = "invalid audit `%s`, it must be always, deny-only, sample:N (N from 0 to 1) or never"
+
+// Audit is the parsed audit field of a policy, the zero value means it isn't set
+type Audit struct {
+	Mode AuditMode
+	// Rate is the share of logged decisions from 0 to 1 in AuditSample mode
+	Rate float64
+}
+
+// ParseAudit parses always, deny-only, sample:N or never, an empty value isn't set
+func ParseAudit(value string) (Audit, error) {
+	switch mode := AuditMode(value); mode {
+	case "":
+		return Audit{}, nil
+	case AuditAlways, AuditDenyOnly, AuditNever:
+		return Audit{Mode: mode}, nil
+	}
+
+	rate, ok := strings.CutPrefix(value, string(AuditSample)+":")
+	if !ok {
+		return Audit{}, fmt.Errorf(validationErrInvalidAudit, value)
+	}
+
+	parsed, err := strconv.ParseFloat(rate, 64)
+	if err != nil || parsed < 0 || parsed > 1 {
+		return Audit{}, fmt.Errorf(validationErrInvalidAudit, value)
+	}
+
+	return Audit{Mode: AuditSample, Rate: parsed}, nil
+}
+
+func (a Audit) String() string {
+	if a.Mode == AuditSample {
+		return string(AuditSample) + ":" + strconv.FormatFloat(a.Rate, 'f', -1, 64)
+	}
+
+	return string(a.Mode)
+}
+
+// DefaultPolicy is applied to requests which don't match any policy. It's set either
+// as a list of allowed clients or as an object with allow and audit fields:
+//
+//	default: ["client"]
+//
+//	default:
+//	  allow: ["client"]
+//	  audit: deny-only
+type DefaultPolicy struct {
+	Allow []string `yaml:"allow"`
+	Audit string   `yaml:"audit,omitempty"`
+}
+
+func (d *DefaultPolicy) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		return node.Decode(&d.Allow)
+	}
+
+	type plain DefaultPolicy
+
+	return node.Decode((*plain)(d))
+}
+
+// MarshalYAML keeps the list form if audit isn't set
+func (d DefaultPolicy) MarshalYAML() (interface{}, error) {
+	if len(d.Audit) == 0 {
+		return d.Allow, nil
+	}
+
+	type plain DefaultPolicy
+
+	return plain(d), nil
+}
+
+// IsZero reports whether the default policy isn't set
+func (d DefaultPolicy) IsZero() bool {
+	return len(d.Allow) == 0 && len(d.Audit) == 0
+}
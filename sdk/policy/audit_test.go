@@ -0,0 +1,59 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package policy
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_ParseAudit(t *testing.T) {
+	cases := map[string]Audit{
+		"":            {},
+		"always":      {Mode: AuditAlways},
+		"deny-only":   {Mode: AuditDenyOnly},
+		"never":       {Mode: AuditNever},
+		"sample:0.1":  {Mode: AuditSample, Rate: 0.1},
+		"sample:1":    {Mode: AuditSample, Rate: 1},
+		"sample:0":    {Mode: AuditSample},
+		"sample:0.05": {Mode: AuditSample, Rate: 0.05},
+	}
+
+	for value, want := range cases {
+		audit, err := ParseAudit(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, audit, value)
+		assert.Equal(t, value, audit.String())
+	}
+
+	for _, value := range []string{"sample", "sample:", "sample:-0.1", "sample:1.5", "sample:abc", "Always"} {
+		_, err := ParseAudit(value)
+		assert.EqualError(t, err, fmt.Sprintf(validationErrInvalidAudit, value))
+	}
+}
+
+func Test_DefaultPolicyYAML(t *testing.T) {
+	config := Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`default: ["client"]`), &config))
+	assert.Equal(t, DefaultPolicy{Allow: []string{"client"}}, config.Default)
+
+	out, err := yaml.Marshal(config.Default)
+	require.NoError(t, err)
+	assert.Equal(t, "- client\n", string(out))
+
+	config = Config{}
+	require.NoError(t, yaml.Unmarshal([]byte("default:\n  allow: [\"client\"]\n  audit: never"), &config))
+	assert.Equal(t, DefaultPolicy{Allow: []string{"client"}, Audit: "never"}, config.Default)
+
+	out, err = yaml.Marshal(config.Default)
+	require.NoError(t, err)
+	assert.Equal(t, "allow:\n    - client\naudit: never\n", string(out))
+
+	assert.True(t, Config{}.Default.IsZero())
+}
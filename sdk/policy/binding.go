@@ -0,0 +1,174 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// Jsonpath queries may refer to request attributes with placeholders, e.g.
+// `{.orders[?(@.id=="${path.id}")].owner}`. Placeholders are replaced with
+// the bound values before the query is parsed and evaluated.
+const (
+	bindingClient   = "client"
+	bindingPath     = "path."
+	bindingHeader   = "header."
+	bindingIdentity = "identity."
+)
+
+const (
+	validationErrUndefinedBinding = "undefined binding `${%s}`, expected ${client}, ${path.<name>}, ${header.<name>} or ${identity.<name>}"
+	validationErrUnquotedBinding  = "binding `${%s}` must be quoted, e.g. \"${%s}\""
+)
+
+// maxBoundQueries limits the number of cached bound queries,
+// the cache is dropped when the limit is reached.
+const maxBoundQueries = 10000
+
+var bindingRegexp = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+type binding struct {
+	placeholder string
+	ref         string
+}
+
+func prepareBindings(query string) ([]binding, error) {
+	bindings := []binding{}
+	for _, idx := range bindingRegexp.FindAllStringSubmatchIndex(query, -1) {
+		placeholder, ref := query[idx[0]:idx[1]], query[idx[2]:idx[3]]
+		switch {
+		case ref == bindingClient:
+		case strings.HasPrefix(ref, bindingPath) && len(ref) > len(bindingPath):
+		case strings.HasPrefix(ref, bindingHeader) && len(ref) > len(bindingHeader):
+		case strings.HasPrefix(ref, bindingIdentity) && len(ref) > len(bindingIdentity):
+		default:
+			return nil, fmt.Errorf(validationErrUndefinedBinding, ref)
+		}
+		// values are only placed into quoted strings, so they can't change the query
+		if !isQuoted(query, idx[0], idx[1]) {
+			return nil, fmt.Errorf(validationErrUnquotedBinding, ref, ref)
+		}
+		bindings = append(bindings, binding{placeholder: placeholder, ref: ref})
+	}
+
+	return bindings, nil
+}
+
+func isQuoted(query string, start, end int) bool {
+	if start == 0 || end == len(query) {
+		return false
+	}
+	quote := query[start-1]
+	return (quote == '"' || quote == '\'') && query[end] == quote
+}
+
+// unsafeValueChars can end the quoted string of a value or the query
+const unsafeValueChars = "\"'\\\r\n"
+
+// bindValues returns values of the parser bindings for the request, false means
+// a value is absent or can't be safely placed into a query.
+func bindValues(parser preparedParser, rq *checkRequest, cn *preparedCn) ([]string, bool) {
+	values := make([]string, 0, len(parser.Bindings))
+	for _, b := range parser.Bindings {
+		var (
+			val string
+			ok  bool
+		)
+
+		switch {
+		case b.ref == bindingClient:
+			val, ok = cn.Name, true
+		case strings.HasPrefix(b.ref, bindingPath):
+			val, ok = rq.pathParams()[strings.TrimPrefix(b.ref, bindingPath)]
+		case strings.HasPrefix(b.ref, bindingHeader):
+			val, ok = rq.in.Headers[strings.TrimPrefix(b.ref, bindingHeader)]
+		case strings.HasPrefix(b.ref, bindingIdentity):
+			val, ok = rq.ids.asMap()[strings.TrimPrefix(b.ref, bindingIdentity)]
+		}
+
+		if !ok || strings.ContainsAny(val, unsafeValueChars) {
+			return nil, false
+		}
+		values = append(values, val)
+	}
+
+	return values, true
+}
+
+func bindQuery(parser preparedParser, values []string) string {
+	query := parser.Jsonpath
+	for i, b := range parser.Bindings {
+		query = strings.Replace(query, b.placeholder, values[i], 1)
+	}
+
+	return query
+}
+
+// boundQueryCache keeps clients found by bound queries, it's keyed by query and bound values.
+type boundQueryCache struct {
+	mux     sync.RWMutex
+	clients map[string][]string
+}
+
+func newBoundQueryCache() *boundQueryCache {
+	return &boundQueryCache{
+		clients: map[string][]string{},
+	}
+}
+
+func (bc *boundQueryCache) key(parser preparedParser, values []string) string {
+	return parser.Jsonpath + "\x00" + strings.Join(values, "\x00")
+}
+
+func (bc *boundQueryCache) get(key string) ([]string, bool) {
+	bc.mux.RLock()
+	defer bc.mux.RUnlock()
+
+	clients, ok := bc.clients[key]
+	return clients, ok
+}
+
+func (bc *boundQueryCache) set(key string, clients []string) {
+	bc.mux.Lock()
+	defer bc.mux.Unlock()
+
+	if len(bc.clients) >= maxBoundQueries {
+		bc.clients = map[string][]string{}
+	}
+	bc.clients[key] = clients
+}
+
+func (s *snapshot) findBoundClients(parser preparedParser, rq *checkRequest, cn *preparedCn) ([]string, error) {
+	values, ok := bindValues(parser, rq, cn)
+	if !ok {
+		return nil, nil
+	}
+
+	cache := s.bound
+	key := cache.key(parser, values)
+	if clients, ok := cache.get(key); ok {
+		return clients, nil
+	}
+
+	// keys of the request values missing in data mean no clients, the empty result is cached as well
+	jp := jsonpath.New("").AllowMissingKeys(true)
+	if err := jp.Parse(bindQuery(parser, values)); err != nil {
+		return nil, fmt.Errorf("jsonpath parsing bound query failure: %s", err.Error())
+	}
+
+	clients, err := findClients(jp, s.data)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.set(key, clients)
+
+	return clients, nil
+}
@@ -5,30 +5,60 @@
 package policy
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"reflect"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 const (
-	errPayloadFieldDoesntExist    = "payload field `%s` doesn't exists in token `%s`"
-	errPayloadFieldIsntStringType = "payload field `%s` isn't string type in token `%s`"
+	// messages of invalid tokens don't contain them, they are copied to decision logs and spans
+	errPayloadFieldDoesntExist    = "payload field `%s` doesn't exist in token"
+	errPayloadFieldIsntStringType = "payload field `%s` isn't string type in token"
+)
+
+// InvalidClientNameReason is the cause of ErrInvalidClientName
+type InvalidClientNameReason string
+
+const (
+	// ReasonUndefinedClientName means no source of the client name is present in the request
+	ReasonUndefinedClientName InvalidClientNameReason = "undefined"
+	// ReasonInvalidCookie means the cookie header of the jwt source can't be parsed
+	ReasonInvalidCookie InvalidClientNameReason = "invalid_cookie"
+	// ReasonInvalidJWT means the token can't be parsed or its signature is invalid
+	ReasonInvalidJWT InvalidClientNameReason = "invalid_jwt"
+	// ReasonMissingClaim means the payload field of the client name is absent in the token
+	ReasonMissingClaim InvalidClientNameReason = "missing_claim"
+	// ReasonInvalidClaim means the payload field of the client name isn't a string
+	ReasonInvalidClaim InvalidClientNameReason = "invalid_claim"
 )
 
 type ErrInvalidClientName struct {
 	errMessage string
+	reason     InvalidClientNameReason
+	source     string
 }
 
 func (e ErrInvalidClientName) Error() string {
 	return e.errMessage
 }
 
+func (e ErrInvalidClientName) Reason() InvalidClientNameReason {
+	return e.reason
+}
+
+// Source is the name of the client name source which failed, empty for undefined client name
+func (e ErrInvalidClientName) Source() string {
+	return e.source
+}
+
 type CheckInput struct {
 	Uri     string
 	Method  string
@@ -36,23 +66,78 @@ type CheckInput struct {
 }
 
 type preparedCn struct {
+	Source string
 	Prefix string
 	Name   string
+	Claims jwt.MapClaims
+}
+
+// identities holds client names resolved from the configured sources.
+// The primary one is the first matched source and is used for plain allow entries,
+// all of them are used for allow expressions.
+type identities struct {
+	primary *preparedCn
+	all     []preparedCn
+}
+
+// asMap returns client names of the identities by source name, sources without a name
+// and a prefix have no name and are omitted
+func (ids *identities) asMap() map[string]string {
+	if ids == nil {
+		return nil
+	}
+
+	var m map[string]string
+	for _, id := range ids.all {
+		if len(id.Source) == 0 {
+			continue
+		}
+		if m == nil {
+			m = make(map[string]string, len(ids.all))
+		}
+		if _, ok := m[id.Source]; !ok {
+			m[id.Source] = id.Name
+		}
+	}
+
+	return m
+}
+
+// claimsMap returns jwt claims of the identities by source name, sources without claims
+// or without a name are omitted
+func (ids *identities) claimsMap() map[string]map[string]interface{} {
+	if ids == nil {
+		return nil
+	}
+
+	var m map[string]map[string]interface{}
+	for _, id := range ids.all {
+		if id.Claims == nil || len(id.Source) == 0 {
+			continue
+		}
+		if m == nil {
+			m = map[string]map[string]interface{}{}
+		}
+		if _, ok := m[id.Source]; !ok {
+			m[id.Source] = id.Claims
+		}
+	}
+
+	return m
 }
 
 type Checker struct {
-	prepCfg   *preparedConfig
-	rawPolicy []byte
-	data      interface{}
-	dataMux   sync.RWMutex
-	dataCache map[string][]string
+	snapshot atomic.Pointer[snapshot]
+	// updateMux serializes updates, checks don't take it
+	updateMux sync.Mutex
 }
 
 func NewChecker() *Checker {
 	// todo: default policy
-	return &Checker{
-		dataMux: sync.RWMutex{},
-	}
+	c := &Checker{}
+	c.snapshot.Store(newSnapshot(nil, nil, nil, nil))
+
+	return c
 }
 
 func (c *Checker) SetPolicy(policy []byte) error {
@@ -61,10 +146,11 @@ func (c *Checker) SetPolicy(policy []byte) error {
 		return fmt.Errorf("parse policy: %s", err)
 	}
 
-	c.dataMux.Lock()
-	c.prepCfg = prepConfig
-	c.rawPolicy = policy
-	c.dataMux.Unlock()
+	c.updateMux.Lock()
+	defer c.updateMux.Unlock()
+
+	current := c.snapshot.Load()
+	c.snapshot.Store(newSnapshot(prepConfig, policy, current.data, newRuleSet(prepConfig, current.rules)))
 
 	return nil
 }
@@ -76,50 +162,170 @@ func (c *Checker) SetData(data []byte) error {
 		return fmt.Errorf("invalid json format: %w", err)
 	}
 
-	c.dataMux.Lock()
-	c.data = newData
-	c.dataCache = map[string][]string{}
-	// todo: async warmup
-	c.dataMux.Unlock()
+	c.updateMux.Lock()
+	defer c.updateMux.Unlock()
+
+	current := c.snapshot.Load()
+	c.snapshot.Store(newSnapshot(current.cfg, current.rawPolicy, newData, current.rules))
+
+	return nil
+}
+
+// SetPolicyWithData prepares the policy and the data together and activates them at once.
+// If any of them is invalid, the active policy and data stay unchanged.
+// Nil data means the policy is used without data.
+func (c *Checker) SetPolicyWithData(policy, data []byte) error {
+	prepConfig, err := PrepareConfig(policy)
+	if err != nil {
+		return fmt.Errorf("parse policy: %s", err)
+	}
+
+	var newData interface{}
+	if data != nil {
+		if err := json.Unmarshal(data, &newData); err != nil {
+			return fmt.Errorf("invalid json format: %w", err)
+		}
+	}
+
+	c.updateMux.Lock()
+	defer c.updateMux.Unlock()
+
+	c.snapshot.Store(newSnapshot(prepConfig, policy, newData, newRuleSet(prepConfig, c.snapshot.Load().rules)))
+
+	return nil
+}
+
+// ValidatePolicyWithData checks the policy and the data the same way as SetPolicyWithData
+// without activating them. JWT key files are not read, they may exist only on the hosts
+// where the policy is enforced.
+func ValidatePolicyWithData(policy, data []byte) error {
+	if _, err := prepareConfig(policy, false); err != nil {
+		return fmt.Errorf("parse policy: %s", err)
+	}
+
+	if data != nil {
+		var newData interface{}
+		if err := json.Unmarshal(data, &newData); err != nil {
+			return fmt.Errorf("invalid json format: %w", err)
+		}
+	}
 
 	return nil
 }
 
 func (c *Checker) Data() interface{} {
-	return c.data
+	return c.snapshot.Load().data
 }
 
 func (c *Checker) Policy() []byte {
-	return c.rawPolicy
+	return c.snapshot.Load().rawPolicy
 }
 
 type CheckResult struct {
 	Allow      bool
 	ClientName string
-	Endpoint   string
-	Err        error
+	// ClientNameSource is the name of the source of the client name, empty for unnamed sources
+	ClientNameSource string
+	// Identities are client names resolved from named sources (name -> client name without prefix)
+	Identities map[string]string
+	// Claims are jwt claims of the identities resolved from jwt sources (name -> claims)
+	Claims   map[string]map[string]interface{}
+	Endpoint string
+	Err      error
+	// DataUsed reports that jsonpath queries of allow entries were evaluated with the data
+	DataUsed bool
+	// Audit of the matched policy or the default, the zero value if it isn't set
+	Audit Audit
 }
 
-func newCheckResult(allow bool, cn *preparedCn, endpoint string, err error) *CheckResult {
-	var clientName string
-	if cn != nil {
-		clientName = cn.Prefix + cn.Name
+func newCheckResult(allow bool, ids *identities, endpoint string, err error) *CheckResult {
+	var clientName, clientNameSource string
+	if ids != nil && ids.primary != nil {
+		clientName = ids.primary.Prefix + ids.primary.Name
+		clientNameSource = ids.primary.Source
 	}
 
 	return &CheckResult{
-		Allow:      allow,
-		ClientName: clientName,
-		Endpoint:   endpoint,
-		Err:        err,
+		Allow:            allow,
+		ClientName:       clientName,
+		ClientNameSource: clientNameSource,
+		Identities:       ids.asMap(),
+		Claims:           ids.claimsMap(),
+		Endpoint:         endpoint,
+		Err:              err,
+	}
+}
+
+// checkRequest holds attributes of the checked request available to allow rules and conditions
+type checkRequest struct {
+	ctx    context.Context
+	in     CheckInput
+	ids    *identities
+	policy *preparedPolicy
+	params map[string]string
+	// withoutData disables jsonpath allow entries and conditions referencing data
+	withoutData bool
+	dataUsed    bool
+}
+
+type CheckOpt func(*checkRequest)
+
+// WithoutData makes jsonpath allow entries never match and conditions referencing data
+// false, so decisions depending on the data fail closed, e.g. when the data is stale
+func WithoutData() CheckOpt {
+	return func(rq *checkRequest) {
+		rq.withoutData = true
+	}
+}
+
+// pathParams returns named groups of the matched policy uri regexp
+func (rq *checkRequest) pathParams() map[string]string {
+	if rq.params != nil {
+		return rq.params
+	}
+
+	rq.params = map[string]string{}
+	if rq.policy == nil || rq.policy.RegexUri == nil {
+		return rq.params
+	}
+
+	match := rq.policy.RegexUri.FindStringSubmatch(rq.in.Uri)
+	if match == nil {
+		return rq.params
+	}
+
+	for i, name := range rq.policy.RegexUri.SubexpNames() {
+		if i > 0 && len(name) > 0 {
+			rq.params[name] = match[i]
+		}
 	}
+
+	return rq.params
+}
+
+func (c *Checker) Check(in CheckInput, opts ...CheckOpt) (*CheckResult, error) {
+	rq := &checkRequest{ctx: context.Background(), in: in}
+	for _, o := range opts {
+		o(rq)
+	}
+
+	result, err := c.snapshot.Load().check(rq)
+	if result != nil {
+		result.DataUsed = rq.dataUsed
+	}
+
+	return result, err
 }
 
-func (c *Checker) Check(in CheckInput) (*CheckResult, error) {
-	c.dataMux.RLock()
-	defer c.dataMux.RUnlock()
+func (s *snapshot) check(rq *checkRequest) (*CheckResult, error) {
+	in := rq.in
+
+	if s.cfg == nil {
+		return nil, errors.New("policy isn't loaded")
+	}
 
 	// define client prefix and name
-	cn, err := c.defineCn(in)
+	ids, err := s.resolveIdentities(rq.ctx, in)
 	if err != nil {
 		if invalidCnErr, ok := err.(ErrInvalidClientName); ok {
 			return newCheckResult(false, nil, "", invalidCnErr), nil
@@ -127,34 +333,117 @@ func (c *Checker) Check(in CheckInput) (*CheckResult, error) {
 		return nil, fmt.Errorf("defining client name: %w", err)
 	}
 
+	rq.ids = ids
+
 	// check routes
-	for _, policy := range c.prepCfg.Policies {
+	for pi, policy := range s.cfg.Policies {
 		if policy.RegexUri != nil {
 			if policy.RegexUri.MatchString(in.Uri) {
 				if policy.Method[0] == "*" || slices.Contains(policy.Method, in.Method) {
-					isAllowed, err := c.isAllowed(policy.Allow, cn)
-					return newCheckResult(isAllowed, cn, policy.RegexUri.String(), err), nil
+					rq.policy = &s.cfg.Policies[pi]
+					isAllowed, err := s.isPolicyAllowed(rq)
+					s.rules.policies[pi].hit(isAllowed)
+					result := newCheckResult(isAllowed, ids, policy.endpoint(), err)
+					result.Audit = policy.Audit
+					return result, nil
 				}
 			}
 		}
 
 		if policy.Uri == in.Uri && (policy.Method[0] == "*" || slices.Contains(policy.Method, in.Method)) {
-			isAllowed, err := c.isAllowed(policy.Allow, cn)
-			return newCheckResult(isAllowed, cn, policy.Uri, err), nil
+			rq.policy = &s.cfg.Policies[pi]
+			isAllowed, err := s.isPolicyAllowed(rq)
+			s.rules.policies[pi].hit(isAllowed)
+			result := newCheckResult(isAllowed, ids, policy.endpoint(), err)
+			result.Audit = policy.Audit
+			return result, nil
 		}
 	}
 
 	// apply default
-	isAllowed, err := c.isAllowed(c.prepCfg.Default, cn)
+	isAllowed, err := s.isAllowed(s.cfg.Default, rq)
+	s.rules.defaultHit.hit(isAllowed)
+	result := newCheckResult(isAllowed, ids, DefaultRule, err)
+	result.Audit = s.cfg.DefaultAudit
+
+	return result, nil
+}
+
+// isPolicyAllowed checks allow list and condition of the matched policy.
+// A policy with a condition and without allow list is decided by the condition only.
+func (s *snapshot) isPolicyAllowed(rq *checkRequest) (bool, error) {
+	policy := rq.policy
+	if policy.Condition == nil {
+		return s.isAllowed(policy.Allow, rq)
+	}
+
+	if !policy.Allow.empty() {
+		isAllowed, err := s.isAllowed(policy.Allow, rq)
+		if !isAllowed || err != nil {
+			return isAllowed, err
+		}
+	}
+
+	return policy.Condition.eval(rq, s.data)
+}
+
+func (s *snapshot) isAllowed(allow preparedAllow, rq *checkRequest) (bool, error) {
+	if rq.ids == nil {
+		return false, nil
+	}
+
+	if rq.ids.primary != nil {
+		allowed, err := s.isClientAllowed(allow, rq, rq.ids.primary)
+		if allowed || err != nil {
+			return allowed, err
+		}
+	}
+
+	for _, expr := range allow.exprs {
+		allowed, err := s.evalAllowExpr(expr, rq)
+		if allowed || err != nil {
+			return allowed, err
+		}
+	}
 
-	return newCheckResult(isAllowed, cn, "default", err), nil
+	return false, nil
 }
 
-func (c *Checker) isAllowed(allow preparedAllow, cn *preparedCn) (bool, error) {
-	if cn == nil {
+func (s *snapshot) evalAllowExpr(expr *allowExpr, rq *checkRequest) (bool, error) {
+	if expr.term != nil {
+		for i := range rq.ids.all {
+			allowed, err := s.isClientAllowed(*expr.term, rq, &rq.ids.all[i])
+			if allowed || err != nil {
+				return allowed, err
+			}
+		}
+		// variables may hold nested expressions
+		for _, nested := range expr.term.exprs {
+			allowed, err := s.evalAllowExpr(nested, rq)
+			if allowed || err != nil {
+				return allowed, err
+			}
+		}
 		return false, nil
 	}
 
+	for _, child := range expr.children {
+		allowed, err := s.evalAllowExpr(child, rq)
+		if err != nil {
+			return false, err
+		}
+		if expr.op == exprOpAnd && !allowed {
+			return false, nil
+		}
+		if expr.op == exprOpOr && allowed {
+			return true, nil
+		}
+	}
+
+	return expr.op == exprOpAnd, nil
+}
+
+func (s *snapshot) isClientAllowed(allow preparedAllow, rq *checkRequest, cn *preparedCn) (bool, error) {
 	for _, allowCn := range allow.clients {
 		if cn.Prefix+cn.Name == allowCn || cn.Prefix+"*" == allowCn {
 			return true, nil
@@ -162,118 +451,196 @@ func (c *Checker) isAllowed(allow preparedAllow, cn *preparedCn) (bool, error) {
 	}
 
 	for _, allowJsonPath := range allow.parsers {
-		clients, ok := c.dataCache[allowJsonPath.Jsonpath]
-		if !ok {
-			values, err := allowJsonPath.JsonParser.FindResults(c.data)
-			if err != nil {
-				return false, fmt.Errorf("jsonpath finding results failure: %s", err.Error())
-			}
+		rq.dataUsed = true
+		if rq.withoutData {
+			continue
+		}
 
-			if len(values) == 0 {
-				c.dataCache[allowJsonPath.Jsonpath] = []string{}
-				break
-			}
+		allowed, err := s.evalJsonPath(allowJsonPath, rq, cn)
+		if allowed || err != nil {
+			return allowed, err
+		}
+	}
 
-			for i := 0; i < len(values[0]); i++ {
-				if values[0][i].Kind() != reflect.Interface {
-					continue
-				}
-				if val, ok := values[0][i].Interface().(string); ok {
-					clients = append(clients, val)
-				}
-			}
+	return false, nil
+}
 
-			c.dataCache[allowJsonPath.Jsonpath] = clients
+// evalJsonPath checks whether the client name is among the results of the jsonpath allow entry
+func (s *snapshot) evalJsonPath(allowJsonPath preparedParser, rq *checkRequest, cn *preparedCn) (allowed bool, err error) {
+	_, span := startSpan(rq.ctx, SpanEvaluateJSONPath, AttrJSONPath.String(allowJsonPath.Prefix+allowJsonPath.Jsonpath))
+	defer func() {
+		span.SetAttributes(AttrAllow.Bool(allowed))
+		endSpan(span, err)
+	}()
+
+	if len(allowJsonPath.Bindings) > 0 {
+		clients, err := s.findBoundClients(allowJsonPath, rq, cn)
+		if err != nil {
+			return false, err
 		}
-
 		for _, allowCn := range clients {
 			if cn.Prefix+cn.Name == allowJsonPath.Prefix+allowCn {
 				return true, nil
 			}
 		}
+		return false, nil
 	}
 
-	return false, nil
+	idx, ok := s.index[allowJsonPath.Jsonpath]
+	if !ok {
+		return false, fmt.Errorf("jsonpath %s isn't indexed", allowJsonPath.Jsonpath)
+	}
+	if idx.err != nil {
+		return false, idx.err
+	}
+
+	fullName := cn.Prefix + cn.Name
+	return strings.HasPrefix(fullName, allowJsonPath.Prefix) && idx.contains(fullName[len(allowJsonPath.Prefix):]), nil
 }
 
-func (c *Checker) defineCn(in CheckInput) (*preparedCn, error) {
-	for _, cn := range c.prepCfg.Cn {
-		if cn.Header != nil {
-			if val, ok := in.Headers[*cn.Header]; ok {
-				return &preparedCn{
-					Prefix: cn.Prefix,
-					Name:   val,
-				}, nil
+func (s *snapshot) resolveIdentities(ctx context.Context, in CheckInput) (ids *identities, err error) {
+	ctx, span := startSpan(ctx, SpanResolveClientName)
+	defer func() {
+		if ids != nil {
+			span.SetAttributes(
+				AttrClientName.String(ids.primary.Prefix+ids.primary.Name),
+				AttrCnSource.String(ids.primary.Source),
+			)
+		}
+		endSpan(span, err)
+	}()
+
+	ids = &identities{}
+
+	for _, cn := range s.cfg.Cn {
+		id, err := s.defineCn(ctx, cn, in)
+		if err != nil {
+			// sources after the primary one don't break the check, the identity is just unresolved
+			if ids.primary == nil {
+				return nil, err
 			}
+			continue
+		}
+		if id == nil {
+			continue
 		}
 
-		if cn.JWT != nil {
-			var token string
-			if cn.JWT.Header != nil {
-				if t, ok := in.Headers[*cn.JWT.Header]; ok {
-					token = t
-				}
+		ids.all = append(ids.all, *id)
+		if ids.primary == nil {
+			ids.primary = &ids.all[0]
+			if !s.cfg.ResolveAllCn {
+				break
 			}
+		}
+	}
 
-			if cn.JWT.Cookie != nil {
-				cookies, err := http.ParseCookie(in.Headers["Cookie"])
-				if err != nil {
-					return nil, ErrInvalidClientName{
-						errMessage: fmt.Sprintf("parse cookie: %s", err),
-					}
-				}
+	if ids.primary == nil {
+		return nil, ErrInvalidClientName{
+			errMessage: "undefined client name",
+			reason:     ReasonUndefinedClientName,
+		}
+	}
 
-				for _, ck := range cookies {
-					if ck.Name == *cn.JWT.Cookie {
-						token = ck.Value
-						break
-					}
-				}
-			}
+	return ids, nil
+}
 
-			if len(token) == 0 {
-				continue
-			}
+// defineCn resolves client name from the source, nil means the source is absent in the request.
+func (s *snapshot) defineCn(ctx context.Context, cn Cn, in CheckInput) (*preparedCn, error) {
+	if cn.Header != nil {
+		if val, ok := in.Headers[*cn.Header]; ok {
+			return &preparedCn{
+				Source: cn.Name,
+				Prefix: cn.Prefix,
+				Name:   val,
+			}, nil
+		}
+	}
 
-			var keyFunc jwt.Keyfunc
-			if cn.JWT.KeyFile != nil {
-				keyFunc = func(t *jwt.Token) (interface{}, error) {
-					return cn.JWT.KeyFileData, nil
-				}
+	if cn.JWT != nil {
+		var token string
+		if cn.JWT.Header != nil {
+			if t, ok := in.Headers[*cn.JWT.Header]; ok {
+				token = t
 			}
+		}
 
-			claims := jwt.MapClaims{}
-			_, err := jwt.ParseWithClaims(token, claims, keyFunc)
+		if cn.JWT.Cookie != nil {
+			cookies, err := http.ParseCookie(in.Headers["Cookie"])
 			if err != nil {
-				if keyFunc == nil && strings.Contains(err.Error(), "no keyfunc was provided") {
-				} else {
-					return nil, ErrInvalidClientName{
-						errMessage: fmt.Sprintf("parse jwt token: %s", err.Error()),
-					}
-				}
-			}
-
-			cnClaim, ok := claims[cn.JWT.Payload]
-			if !ok {
 				return nil, ErrInvalidClientName{
-					errMessage: fmt.Sprintf(errPayloadFieldDoesntExist, cn.JWT.Payload, token),
+					errMessage: fmt.Sprintf("parse cookie: %s", err),
+					reason:     ReasonInvalidCookie,
+					source:     cn.Name,
 				}
 			}
-			cnValue, ok := cnClaim.(string)
-			if !ok {
-				return nil, ErrInvalidClientName{
-					errMessage: fmt.Sprintf(errPayloadFieldIsntStringType, cn.JWT.Payload, token),
+
+			for _, ck := range cookies {
+				if ck.Name == *cn.JWT.Cookie {
+					token = ck.Value
+					break
 				}
 			}
+		}
 
-			return &preparedCn{
-				Prefix: cn.Prefix,
-				Name:   cnValue,
-			}, nil
+		if len(token) == 0 {
+			return nil, nil
+		}
+
+		return s.verifyJWT(ctx, cn, token)
+	}
+
+	return nil, nil
+}
+
+// verifyJWT parses the token of the jwt source and resolves client name from its payload field
+func (s *snapshot) verifyJWT(ctx context.Context, cn Cn, token string) (_ *preparedCn, err error) {
+	_, span := startSpan(ctx, SpanVerifyJWT, AttrCnSource.String(cn.Name))
+	defer func() { endSpan(span, err) }()
+
+	var keyFunc jwt.Keyfunc
+	if cn.JWT.KeyFile != nil {
+		keyFunc = func(t *jwt.Token) (interface{}, error) {
+			return cn.JWT.KeyFileData, nil
 		}
 	}
 
-	return nil, ErrInvalidClientName{
-		errMessage: "undefined client name",
+	// the signature is verified only with the key file
+	span.SetAttributes(AttrJWTVerified.Bool(keyFunc != nil))
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(token, claims, keyFunc)
+	if err != nil {
+		if keyFunc == nil && strings.Contains(err.Error(), "no keyfunc was provided") {
+		} else {
+			return nil, ErrInvalidClientName{
+				errMessage: fmt.Sprintf("parse jwt token: %s", err.Error()),
+				reason:     ReasonInvalidJWT,
+				source:     cn.Name,
+			}
+		}
 	}
+
+	cnClaim, ok := claims[cn.JWT.Payload]
+	if !ok {
+		return nil, ErrInvalidClientName{
+			errMessage: fmt.Sprintf(errPayloadFieldDoesntExist, cn.JWT.Payload),
+			reason:     ReasonMissingClaim,
+			source:     cn.Name,
+		}
+	}
+	cnValue, ok := cnClaim.(string)
+	if !ok {
+		return nil, ErrInvalidClientName{
+			errMessage: fmt.Sprintf(errPayloadFieldIsntStringType, cn.JWT.Payload),
+			reason:     ReasonInvalidClaim,
+			source:     cn.Name,
+		}
+	}
+
+	return &preparedCn{
+		Source: cn.Name,
+		Prefix: cn.Prefix,
+		Name:   cnValue,
+		Claims: claims,
+	}, nil
 }
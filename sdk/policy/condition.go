@@ -0,0 +1,171 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package policy
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+)
+
+// conditionCostLimit bounds the runtime cost of a single condition evaluation
+const conditionCostLimit = 100000
+
+const (
+	validationErrCompileCondition        = "compile condition `%s`: %s"
+	validationErrConditionIsntBoolean    = "condition `%s` must return bool, got %s"
+	validationErrConditionProgramFailure = "prepare condition `%s`: %s"
+	errConditionEvaluation               = "condition evaluation failure: %s"
+)
+
+type preparedCondition struct {
+	Expr    string
+	Program cel.Program
+	// UsesData is set when the expression references the data variable
+	UsesData bool
+}
+
+var conditionEnv = func() *cel.Env {
+	env, err := cel.NewEnv(
+		cel.Variable("request.method", cel.StringType),
+		cel.Variable("request.path", cel.StringType),
+		cel.Variable("request.params", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("request.headers", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("request.query", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("client", cel.StringType),
+		cel.Variable("identities", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("claims", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("identityClaims", cel.MapType(cel.StringType, cel.MapType(cel.StringType, cel.DynType))),
+		cel.Variable("data", cel.DynType),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("condition cel environment: %s", err))
+	}
+
+	return env
+}()
+
+func prepareCondition(expr string) (*preparedCondition, error) {
+	ast, iss := conditionEnv.Compile(expr)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf(validationErrCompileCondition, expr, iss.Err().Error())
+	}
+
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf(validationErrConditionIsntBoolean, expr, ast.OutputType().String())
+	}
+
+	prg, err := conditionEnv.Program(ast,
+		cel.CostLimit(conditionCostLimit),
+		cel.EvalOptions(cel.OptOptimize),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(validationErrConditionProgramFailure, expr, err.Error())
+	}
+
+	usesData := false
+	for _, ref := range ast.NativeRep().ReferenceMap() {
+		if ref.Name == "data" {
+			usesData = true
+			break
+		}
+	}
+
+	return &preparedCondition{
+		Expr:     expr,
+		Program:  prg,
+		UsesData: usesData,
+	}, nil
+}
+
+// eval evaluates the condition, a condition referencing data is false without data
+// the same way as jsonpath allow entries never match then
+func (cond *preparedCondition) eval(rq *checkRequest, data interface{}) (bool, error) {
+	if cond.UsesData {
+		rq.dataUsed = true
+		if rq.withoutData {
+			return false, nil
+		}
+	}
+
+	path, rawQuery, _ := strings.Cut(rq.in.Uri, "?")
+	query := map[string]string{}
+	if values, err := url.ParseQuery(rawQuery); err == nil {
+		for k, v := range values {
+			query[k] = strings.Join(v, ",")
+		}
+	}
+
+	headers := rq.in.Headers
+	if headers == nil {
+		headers = map[string]string{}
+	}
+
+	var client string
+	ids := map[string]string{}
+	claims := map[string]interface{}{}
+	identityClaims := map[string]map[string]interface{}{}
+	if rq.ids != nil {
+		if rq.ids.primary != nil {
+			client = rq.ids.primary.Prefix + rq.ids.primary.Name
+		}
+		if m := rq.ids.asMap(); m != nil {
+			ids = m
+		}
+		if m := rq.ids.claimsMap(); m != nil {
+			identityClaims = m
+		}
+		claims = mergeClaims(rq.ids.all)
+	}
+
+	out, _, err := cond.Program.Eval(map[string]interface{}{
+		"request.method":  rq.in.Method,
+		"request.path":    path,
+		"request.params":  rq.pathParams(),
+		"request.headers": headers,
+		"request.query":   query,
+		"client":          client,
+		"identities":      ids,
+		"claims":          claims,
+		"identityClaims":  identityClaims,
+		"data":            data,
+	})
+	if err != nil {
+		return false, fmt.Errorf(errConditionEvaluation, err.Error())
+	}
+
+	allowed, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf(errConditionEvaluation, "result isn't boolean")
+	}
+
+	return allowed, nil
+}
+
+// mergeClaims merges claims of all identities, unnamed sources included. A claim with
+// different values in several sources is omitted, so it's read from identityClaims
+// of the intended source
+func mergeClaims(ids []preparedCn) map[string]interface{} {
+	claims := map[string]interface{}{}
+	conflicts := map[string]struct{}{}
+	for _, id := range ids {
+		for k, v := range id.Claims {
+			if current, ok := claims[k]; ok && !reflect.DeepEqual(current, v) {
+				conflicts[k] = struct{}{}
+				continue
+			}
+			claims[k] = v
+		}
+	}
+
+	for k := range conflicts {
+		delete(claims, k)
+	}
+
+	return claims
+}
@@ -0,0 +1,176 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// allowExpr is a boolean combination of allow terms, e.g. `service:web-bff && role:support`.
+// Each term is an ordinary allow entry (client name, wildcard, jsonpath query or variable)
+// and is satisfied when any of the resolved identities matches it.
+type allowExpr struct {
+	op       string // "&&", "||" or empty for a term
+	term     *preparedAllow
+	children []*allowExpr
+}
+
+const (
+	exprOpAnd = "&&"
+	exprOpOr  = "||"
+)
+
+func isAllowExpr(a string) bool {
+	depth := 0
+	for i := 0; i < len(a); i++ {
+		switch a[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '&', '|':
+			if depth == 0 && i+1 < len(a) && a[i+1] == a[i] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func tokenizeAllowExpr(a string) ([]string, error) {
+	tokens := []string{}
+	term := strings.Builder{}
+	depth := 0
+
+	flush := func() {
+		if t := strings.TrimSpace(term.String()); len(t) > 0 {
+			tokens = append(tokens, t)
+		}
+		term.Reset()
+	}
+
+	for i := 0; i < len(a); i++ {
+		ch := a[i]
+		if depth > 0 {
+			if ch == '{' {
+				depth++
+			} else if ch == '}' {
+				depth--
+			}
+			term.WriteByte(ch)
+			continue
+		}
+
+		switch {
+		case ch == '{':
+			depth++
+			term.WriteByte(ch)
+		case ch == '(' || ch == ')':
+			flush()
+			tokens = append(tokens, string(ch))
+		case (ch == '&' || ch == '|') && i+1 < len(a) && a[i+1] == ch:
+			flush()
+			tokens = append(tokens, a[i:i+2])
+			i++
+		default:
+			term.WriteByte(ch)
+		}
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced braces")
+	}
+	flush()
+
+	return tokens, nil
+}
+
+type allowExprParser struct {
+	tokens []string
+	pos    int
+	vars   Variables
+}
+
+func prepareAllowExpr(a string, vars Variables) (*allowExpr, error) {
+	tokens, err := tokenizeAllowExpr(a)
+	if err != nil {
+		return nil, fmt.Errorf(validationErrInvalidAllowExpression, a, err.Error())
+	}
+
+	p := &allowExprParser{tokens: tokens, vars: vars}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf(validationErrInvalidAllowExpression, a, err.Error())
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf(validationErrInvalidAllowExpression, a, "unexpected "+p.tokens[p.pos])
+	}
+
+	return expr, nil
+}
+
+func (p *allowExprParser) parseOr() (*allowExpr, error) {
+	return p.parseBinary(exprOpOr, p.parseAnd)
+}
+
+func (p *allowExprParser) parseAnd() (*allowExpr, error) {
+	return p.parseBinary(exprOpAnd, p.parsePrimary)
+}
+
+func (p *allowExprParser) parseBinary(op string, next func() (*allowExpr, error)) (*allowExpr, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	expr := &allowExpr{op: op, children: []*allowExpr{left}}
+	for p.pos < len(p.tokens) && p.tokens[p.pos] == op {
+		p.pos++
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		expr.children = append(expr.children, right)
+	}
+
+	if len(expr.children) == 1 {
+		return left, nil
+	}
+
+	return expr, nil
+}
+
+func (p *allowExprParser) parsePrimary() (*allowExpr, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch tok {
+	case "(":
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos] != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return expr, nil
+	case ")", exprOpAnd, exprOpOr:
+		return nil, fmt.Errorf("unexpected %s", tok)
+	}
+
+	term, err := prepareAllow([]string{tok}, p.vars)
+	if err != nil {
+		return nil, err
+	}
+
+	return &allowExpr{term: term}, nil
+}
@@ -28,6 +28,9 @@ type CnJWT struct {
 }
 
 type Cn struct {
+	// Name of the identity in the resolved identities map,
+	// defaults to the prefix without trailing colon.
+	Name   string  `yaml:"name,omitempty"`
 	Prefix string  `yaml:"prefix"`
 	Header *string `yaml:"header,omitempty"`
 	JWT    *CnJWT  `yaml:"jwt,omitempty"`
@@ -37,40 +40,66 @@ type Policy struct {
 	Uri     []string `yaml:"uri"`
 	Methods []string `yaml:"method"`
 	Allow   []string `yaml:"allow"`
+	// Condition is an optional CEL expression which must be true to allow the request
+	Condition string `yaml:"condition,omitempty"`
+	// Audit defines which decisions of the policy are logged: always, deny-only, sample:N or never
+	Audit string `yaml:"audit,omitempty"`
 }
 
 type Variables map[string][]string
 
 type Config struct {
-	Cn       []Cn      `yaml:"cn"`
-	Vars     Variables `yaml:"vars"`
-	Default  []string  `yaml:"default"`
-	Policies []Policy  `yaml:"policies"`
+	Cn       []Cn          `yaml:"cn"`
+	Vars     Variables     `yaml:"vars"`
+	Default  DefaultPolicy `yaml:"default"`
+	Policies []Policy      `yaml:"policies"`
 }
 
 type preparedParser struct {
 	Prefix     string
 	JsonParser *jsonpath.JSONPath
 	Jsonpath   string
+	// Bindings are placeholders of request attributes, the query with bindings
+	// is parsed for every set of bound values, so JsonParser is only used for validation
+	Bindings []binding
+	// DataPaths are static field prefixes of the query templates, they define
+	// which data patches affect results of the query
+	DataPaths [][]string
 }
 
 type preparedAllow struct {
 	clients []string
 	parsers []preparedParser
+	exprs   []*allowExpr
 }
 
 type preparedPolicy struct {
-	RegexUri *regexp.Regexp
-	Uri      string
-	Method   []string
-	Allow    preparedAllow
-	Priority int
+	RegexUri  *regexp.Regexp
+	Uri       string
+	Method    []string
+	Allow     preparedAllow
+	Condition *preparedCondition
+	Audit     Audit
+	Priority  int
+}
+
+// endpoint is the uri of the policy reported in check results
+func (p preparedPolicy) endpoint() string {
+	if p.RegexUri != nil {
+		return p.RegexUri.String()
+	}
+
+	return p.Uri
 }
 
 type preparedConfig struct {
-	Cn       []Cn
-	Default  preparedAllow
-	Policies []preparedPolicy
+	Cn           []Cn
+	Default      preparedAllow
+	DefaultAudit Audit
+	Policies     []preparedPolicy
+	// ResolveAllCn is set when some rule needs every client name source
+	// to be resolved, not only the first matched one.
+	ResolveAllCn bool
 }
 
 const (
@@ -82,10 +111,16 @@ const (
 	validationErrVarIsNotAllowedInThisSection = "variables is not allowed in this section"
 	validationErrHeaderOrCookieAsJWTSource    = "header or cookie may be used at the same time as a jwt source"
 	validationErrAtLeastOneCNSourceMustExist  = "at least one client name source must exist"
+	validationErrInvalidAllowExpression       = "invalid allow expression `%s`: %s"
 	errLoadJWTKeyFile                         = "loading JWT key file: %s"
 )
 
 func PrepareConfig(config []byte) (*preparedConfig, error) {
+	return prepareConfig(config, true)
+}
+
+// prepareConfig parses and validates the config, jwt key files are read only with loadKeyFiles
+func prepareConfig(config []byte, loadKeyFiles bool) (*preparedConfig, error) {
 	c := Config{}
 
 	err := yaml.Unmarshal(config, &c)
@@ -94,12 +129,16 @@ func PrepareConfig(config []byte) (*preparedConfig, error) {
 	}
 
 	// prepare client names
-	for _, cn := range c.Cn {
+	for ci, cn := range c.Cn {
+		if len(cn.Name) == 0 {
+			c.Cn[ci].Name = strings.TrimSuffix(cn.Prefix, ":")
+		}
+
 		if cn.JWT != nil {
 			if cn.JWT.Cookie != nil && cn.JWT.Header != nil {
 				return nil, errors.New(validationErrHeaderOrCookieAsJWTSource)
 			}
-			if cn.JWT.KeyFile != nil {
+			if cn.JWT.KeyFile != nil && loadKeyFiles {
 				d, err := os.ReadFile(*cn.JWT.KeyFile)
 				if err != nil {
 					return nil, fmt.Errorf(errLoadJWTKeyFile, *cn.JWT.KeyFile)
@@ -164,38 +203,66 @@ func PrepareConfig(config []byte) (*preparedConfig, error) {
 		}
 	}
 
-	prepDefault, err := prepareAllow(c.Default, c.Vars)
+	prepDefault, err := prepareAllow(c.Default.Allow, c.Vars)
 	if err != nil {
 		return nil, fmt.Errorf("fail to parse client: %s", err.Error())
 	}
+	defaultAudit, err := ParseAudit(c.Default.Audit)
+	if err != nil {
+		return nil, err
+	}
 	preparedConfig := preparedConfig{
-		Cn:      c.Cn,
-		Default: *prepDefault,
+		Cn:           c.Cn,
+		Default:      *prepDefault,
+		DefaultAudit: defaultAudit,
+		ResolveAllCn: len(prepDefault.exprs) > 0,
 	}
 
 	prepPolicies := []preparedPolicy{}
 
 	for _, policy := range c.Policies {
+		audit, err := ParseAudit(policy.Audit)
+		if err != nil {
+			return nil, err
+		}
+
+		var prepCondition *preparedCondition
+		if len(policy.Condition) > 0 {
+			prepCondition, err = prepareCondition(policy.Condition)
+			if err != nil {
+				return nil, err
+			}
+			// claims of every jwt source are available in conditions
+			preparedConfig.ResolveAllCn = true
+		}
+
 		for _, uri := range policy.Uri {
 			prepAllow, err := prepareAllow(policy.Allow, c.Vars)
 			if err != nil {
 				return nil, fmt.Errorf("fail to parse client: %s", err.Error())
 			}
+			if len(prepAllow.exprs) > 0 {
+				preparedConfig.ResolveAllCn = true
+			}
 			if uri[0] == '~' {
 				uri = "^" + strings.TrimLeft(uri, "~") + "$"
 				preparedPolicy := preparedPolicy{
-					RegexUri: regexp.MustCompile(uri),
-					Method:   policy.Methods,
-					Allow:    *prepAllow,
-					Priority: len(uri),
+					RegexUri:  regexp.MustCompile(uri),
+					Method:    policy.Methods,
+					Allow:     *prepAllow,
+					Condition: prepCondition,
+					Audit:     audit,
+					Priority:  len(uri),
 				}
 				prepPolicies = append(prepPolicies, preparedPolicy)
 			} else {
 				preparedPolicy := preparedPolicy{
-					Uri:      uri,
-					Method:   policy.Methods,
-					Allow:    *prepAllow,
-					Priority: 9999999,
+					Uri:       uri,
+					Method:    policy.Methods,
+					Allow:     *prepAllow,
+					Condition: prepCondition,
+					Audit:     audit,
+					Priority:  9999999,
 				}
 				prepPolicies = append(prepPolicies, preparedPolicy)
 			}
@@ -221,6 +288,16 @@ func prepareAllow(allow []string, vars Variables) (*preparedAllow, error) {
 			return nil, fmt.Errorf("empty client name")
 		}
 
+		if isAllowExpr(a) {
+			expr, err := prepareAllowExpr(a, vars)
+			if err != nil {
+				return nil, err
+			}
+			prepAllow.exprs = append(prepAllow.exprs, expr)
+
+			continue
+		}
+
 		if idx := strings.Index(a, "{"); idx >= 0 {
 			prepParser := preparedParser{}
 			if idx > 0 {
@@ -228,10 +305,17 @@ func prepareAllow(allow []string, vars Variables) (*preparedAllow, error) {
 			}
 			prepParser.Jsonpath = a[idx:]
 
+			bindings, err := prepareBindings(prepParser.Jsonpath)
+			if err != nil {
+				return nil, fmt.Errorf("fail to parse jsonpath: %s: %s", a, err.Error())
+			}
+			prepParser.Bindings = bindings
+
 			prepParser.JsonParser = jsonpath.New("")
-			if err := prepParser.JsonParser.Parse(prepParser.Jsonpath); err != nil {
+			if err := prepParser.JsonParser.Parse(bindingRegexp.ReplaceAllString(prepParser.Jsonpath, "")); err != nil {
 				return nil, fmt.Errorf("fail to parse jsonpath: %s: %s", a, err.Error())
 			}
+			prepParser.DataPaths = dataPaths(prepParser.Jsonpath)
 			prepAllow.parsers = append(prepAllow.parsers, prepParser)
 
 			continue
@@ -253,6 +337,7 @@ func prepareAllow(allow []string, vars Variables) (*preparedAllow, error) {
 
 			prepAllow.clients = append(prepAllow.clients, vClients.clients...)
 			prepAllow.parsers = append(prepAllow.parsers, vClients.parsers...)
+			prepAllow.exprs = append(prepAllow.exprs, vClients.exprs...)
 
 			continue
 		}
@@ -262,3 +347,7 @@ func prepareAllow(allow []string, vars Variables) (*preparedAllow, error) {
 
 	return prepAllow, nil
 }
+
+func (a preparedAllow) empty() bool {
+	return len(a.clients) == 0 && len(a.parsers) == 0 && len(a.exprs) == 0
+}
@@ -98,6 +98,82 @@ default:
   - "*"`,
 			want: validationErrAtLeastOneCNSourceMustExist,
 		},
+		{
+			config: `
+cn:
+  - header: "x-source"
+default:
+  - "service:web-bff && (role:support"`,
+			want: fmt.Sprintf(validationErrInvalidAllowExpression, "service:web-bff && (role:support", "missing closing parenthesis"),
+		},
+		{
+			config: `
+cn:
+  - header: "x-source"
+default:
+  - "service:web-bff && || role:support"`,
+			want: fmt.Sprintf(validationErrInvalidAllowExpression, "service:web-bff && || role:support", "unexpected ||"),
+		},
+		{
+			config: `
+cn:
+  - header: "x-source"
+policies:
+  - uri: ["/ep1"]
+    condition: request.unknown == "value"`,
+			want: "compile condition `request.unknown == \"value\"`",
+		},
+		{
+			config: `
+cn:
+  - header: "x-source"
+policies:
+  - uri: ["/ep1"]
+    condition: request.method`,
+			want: fmt.Sprintf(validationErrConditionIsntBoolean, "request.method", "string"),
+		},
+		{
+			config: `
+cn:
+  - header: "x-source"
+default:
+  - '{.orders[?(@.id=="${query.id}")].owner}'`,
+			want: fmt.Sprintf(validationErrUndefinedBinding, "query.id"),
+		},
+		{
+			config: `
+cn:
+  - header: "x-source"
+default:
+  - '{.teams.${header.x-team}.members[*]}'`,
+			want: fmt.Sprintf(validationErrUnquotedBinding, "header.x-team", "header.x-team"),
+		},
+		{
+			config: `
+cn:
+  - header: "x-source"
+default:
+  - '{.orders[?(@.id=="${path.id}'')].owner}'`,
+			want: fmt.Sprintf(validationErrUnquotedBinding, "path.id", "path.id"),
+		},
+		{
+			config: `
+cn:
+  - header: "x-source"
+policies:
+  - uri: ["/ep1"]
+    audit: sometimes`,
+			want: fmt.Sprintf(validationErrInvalidAudit, "sometimes"),
+		},
+		{
+			config: `
+cn:
+  - header: "x-source"
+default:
+  allow: ["client"]
+  audit: sample:2`,
+			want: fmt.Sprintf(validationErrInvalidAudit, "sample:2"),
+		},
 	}
 
 	for _, tcase := range tcases {
@@ -0,0 +1,304 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+type PatchOp string
+
+const (
+	PatchAdd     PatchOp = "add"
+	PatchReplace PatchOp = "replace"
+	PatchRemove  PatchOp = "remove"
+)
+
+// DataPatch changes the data at the JSON Pointer (RFC 6901) path,
+// operations follow JSON Patch (RFC 6902) semantics.
+type DataPatch struct {
+	Op    PatchOp
+	Path  string
+	Value interface{}
+}
+
+const (
+	errPatchPathNotFound     = "patch path %s not found"
+	errPatchInvalidPath      = "invalid patch path %s"
+	errPatchUnsupportedOp    = "unsupported patch operation %s"
+	errPatchRootRemove       = "data root can't be removed"
+	errPatchInvalidArrayItem = "invalid array index %s of patch path %s"
+)
+
+// bindingSentinel replaces placeholders to find where the static part of a bound query ends
+const bindingSentinel = "__authlink_binding__"
+
+// PatchData applies the patches to the active data at once. Unlike SetData it doesn't parse
+// the whole document: only containers on the patched paths are copied and only jsonpath
+// indexes which may read the patched paths are recomputed, each of them over the whole data.
+func (c *Checker) PatchData(patches ...DataPatch) error {
+	type preparedPatch struct {
+		DataPatch
+		tokens []string
+	}
+
+	prepared := make([]preparedPatch, 0, len(patches))
+	for _, p := range patches {
+		tokens, err := parsePointer(p.Path)
+		if err != nil {
+			return err
+		}
+
+		value, err := normalizeValue(p.Value)
+		if err != nil {
+			return fmt.Errorf("patch value of %s: %w", p.Path, err)
+		}
+		p.Value = value
+
+		prepared = append(prepared, preparedPatch{DataPatch: p, tokens: tokens})
+	}
+
+	c.updateMux.Lock()
+	defer c.updateMux.Unlock()
+
+	current := c.snapshot.Load()
+
+	data := current.data
+	for _, p := range prepared {
+		var err error
+		data, err = patchValue(data, p.tokens, p.Op, p.Value, p.Path)
+		if err != nil {
+			return err
+		}
+	}
+
+	paths := make([][]string, 0, len(prepared))
+	for _, p := range prepared {
+		paths = append(paths, p.tokens)
+	}
+
+	c.snapshot.Store(current.withPatchedData(data, paths))
+
+	return nil
+}
+
+// withPatchedData returns a new snapshot reusing indexes which aren't affected by the patched paths
+func (s *snapshot) withPatchedData(data interface{}, paths [][]string) *snapshot {
+	next := &snapshot{
+		cfg:       s.cfg,
+		rawPolicy: s.rawPolicy,
+		data:      data,
+		index:     make(map[string]*dataIndex, len(s.index)),
+		bound:     s.bound,
+		rules:     s.rules,
+	}
+
+	if s.cfg == nil {
+		next.bound = newBoundQueryCache()
+		return next
+	}
+
+	for _, parser := range s.cfg.parsers() {
+		if _, ok := next.index[parser.Jsonpath]; ok {
+			continue
+		}
+		if idx, ok := s.index[parser.Jsonpath]; ok && !parser.affectedBy(paths) {
+			next.index[parser.Jsonpath] = idx
+			continue
+		}
+		next.index[parser.Jsonpath] = newDataIndex(parser.JsonParser, data)
+	}
+
+	// cached results of bound queries stay valid if none of them reads the patched paths
+	for _, parser := range s.cfg.boundParsers() {
+		if parser.affectedBy(paths) {
+			next.bound = newBoundQueryCache()
+			break
+		}
+	}
+
+	return next
+}
+
+// affectedBy reports whether the query may read data changed at the paths
+func (p preparedParser) affectedBy(paths [][]string) bool {
+	for _, dataPath := range p.DataPaths {
+		for _, path := range paths {
+			n := min(len(dataPath), len(path))
+			if slices.Equal(dataPath[:n], path[:n]) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// dataPaths returns static field prefixes of every template of the query,
+// e.g. [teams team1] for `{.teams.team1[*].name}`. The prefix ends at the first
+// array, filter, wildcard or recursive descent, empty prefix means the whole data.
+func dataPaths(query string) [][]string {
+	parser, err := jsonpath.Parse("", bindingRegexp.ReplaceAllString(query, bindingSentinel))
+	if err != nil {
+		return [][]string{{}}
+	}
+
+	paths := [][]string{}
+	for _, node := range parser.Root.Nodes {
+		template, ok := node.(*jsonpath.ListNode)
+		if !ok {
+			continue
+		}
+
+		path := []string{}
+		for _, n := range template.Nodes {
+			field, ok := n.(*jsonpath.FieldNode)
+			if !ok || strings.Contains(field.Value, bindingSentinel) {
+				break
+			}
+			if len(field.Value) > 0 {
+				path = append(path, field.Value)
+			}
+		}
+		paths = append(paths, path)
+	}
+
+	return paths
+}
+
+func parsePointer(path string) ([]string, error) {
+	if len(path) == 0 {
+		return []string{}, nil
+	}
+	if path[0] != '/' {
+		return nil, fmt.Errorf(errPatchInvalidPath, path)
+	}
+
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// normalizeValue converts the value to the types produced by json.Unmarshal, so
+// jsonpath queries work with patched values the same way as with parsed data
+func normalizeValue(value interface{}) (interface{}, error) {
+	switch value.(type) {
+	case nil, string, float64, bool:
+		return value, nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return nil, err
+	}
+
+	return normalized, nil
+}
+
+// patchValue applies the operation copying containers on the path, so the previous
+// data which may be used by concurrent checks isn't modified
+func patchValue(doc interface{}, tokens []string, op PatchOp, value interface{}, path string) (interface{}, error) {
+	if op != PatchAdd && op != PatchReplace && op != PatchRemove {
+		return nil, fmt.Errorf(errPatchUnsupportedOp, op)
+	}
+
+	if len(tokens) == 0 {
+		if op == PatchRemove {
+			return nil, errors.New(errPatchRootRemove)
+		}
+		return value, nil
+	}
+
+	token := tokens[0]
+	last := len(tokens) == 1
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, exists := node[token]
+		if !exists && (!last || op != PatchAdd) {
+			return nil, fmt.Errorf(errPatchPathNotFound, path)
+		}
+
+		patched := maps.Clone(node)
+		if !last {
+			newChild, err := patchValue(child, tokens[1:], op, value, path)
+			if err != nil {
+				return nil, err
+			}
+			patched[token] = newChild
+			return patched, nil
+		}
+
+		if op == PatchRemove {
+			delete(patched, token)
+		} else {
+			patched[token] = value
+		}
+		return patched, nil
+	case []interface{}:
+		if last && op == PatchAdd && token == "-" {
+			return append(slices.Clip(node), value), nil
+		}
+
+		idx, err := arrayIndex(token)
+		if err != nil || idx > len(node) || (idx == len(node) && (!last || op != PatchAdd)) {
+			return nil, fmt.Errorf(errPatchInvalidArrayItem, token, path)
+		}
+
+		if !last {
+			newChild, err := patchValue(node[idx], tokens[1:], op, value, path)
+			if err != nil {
+				return nil, err
+			}
+			patched := slices.Clone(node)
+			patched[idx] = newChild
+			return patched, nil
+		}
+
+		switch op {
+		case PatchAdd:
+			return slices.Insert(slices.Clone(node), idx, value), nil
+		case PatchRemove:
+			return slices.Delete(slices.Clone(node), idx, idx+1), nil
+		default:
+			patched := slices.Clone(node)
+			patched[idx] = value
+			return patched, nil
+		}
+	default:
+		return nil, fmt.Errorf(errPatchPathNotFound, path)
+	}
+}
+
+// arrayIndex parses the array index token of a json pointer, rfc 6901 allows only
+// "0" or digits without leading zeros, signs are not allowed
+func arrayIndex(token string) (int, error) {
+	if len(token) == 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, strconv.ErrSyntax
+	}
+	for _, c := range token {
+		if c < '0' || c > '9' {
+			return 0, strconv.ErrSyntax
+		}
+	}
+
+	return strconv.Atoi(token)
+}
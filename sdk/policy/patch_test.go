@@ -0,0 +1,128 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PatchData(t *testing.T) {
+	config := `
+cn:
+  - header: "x-source"
+policies:
+  - uri: ["/users"]
+    allow: ["{.users[*]}"]
+  - uri: ["/admins"]
+    allow: ["{.teams.admins[*]}"]
+  - uri: ["/devs"]
+    allow: ["{.teams.devs[*].name}"]
+  - uri: ["~/orders/(?P<id>[0-9]+)"]
+    allow: ['{.orders[?(@.id=="${path.id}")].owner}']`
+
+	data := []byte(`{
+  "users": ["user1"],
+  "teams": {"admins": ["admin1"], "devs": [{"name": "dev1"}]},
+  "orders": [{"id": "1", "owner": "user1"}]
+}`)
+
+	checker := NewChecker()
+	require.NoError(t, checker.SetPolicyWithData([]byte(config), data))
+
+	check := func(uri, client string) bool {
+		result, err := checker.Check(CheckInput{Uri: uri, Headers: map[string]string{"x-source": client}})
+		require.NoError(t, err)
+		require.NoError(t, result.Err)
+		return result.Allow
+	}
+
+	assert.True(t, check("/orders/1", "user1"))
+
+	before := checker.snapshot.Load()
+
+	require.NoError(t, checker.PatchData(
+		DataPatch{Op: PatchAdd, Path: "/users/-", Value: "user2"},
+		DataPatch{Op: PatchReplace, Path: "/teams/devs/0", Value: map[string]string{"name": "dev2"}},
+	))
+
+	after := checker.snapshot.Load()
+
+	assert.True(t, check("/users", "user2"))
+	assert.True(t, check("/devs", "dev2"))
+	assert.False(t, check("/devs", "dev1"))
+	assert.True(t, check("/admins", "admin1"))
+
+	// only affected indexes are recomputed
+	assert.NotSame(t, before.index["{.users[*]}"], after.index["{.users[*]}"])
+	assert.NotSame(t, before.index["{.teams.devs[*].name}"], after.index["{.teams.devs[*].name}"])
+	assert.Same(t, before.index["{.teams.admins[*]}"], after.index["{.teams.admins[*]}"])
+	assert.Same(t, before.bound, after.bound)
+
+	// the previous snapshot isn't modified
+	assert.Equal(t, []interface{}{"user1"}, before.data.(map[string]interface{})["users"])
+	assert.False(t, before.index["{.users[*]}"].contains("user2"))
+
+	// patches of the parent path affect the query
+	require.NoError(t, checker.PatchData(DataPatch{Op: PatchReplace, Path: "/teams", Value: map[string]interface{}{
+		"admins": []string{},
+		"devs":   []interface{}{},
+	}}))
+	assert.False(t, check("/admins", "admin1"))
+	assert.False(t, check("/devs", "dev2"))
+
+	// bound results are invalidated by patches of the bound query data
+	require.NoError(t, checker.PatchData(DataPatch{Op: PatchReplace, Path: "/orders/0/owner", Value: "user2"}))
+	assert.NotSame(t, after.bound, checker.snapshot.Load().bound)
+	assert.True(t, check("/orders/1", "user2"))
+	assert.False(t, check("/orders/1", "user1"))
+}
+
+func Test_PatchDataErrors(t *testing.T) {
+	checker := NewChecker()
+	require.NoError(t, checker.SetData([]byte(`{"users": ["user1"], "name": "value"}`)))
+
+	cases := []struct {
+		patch DataPatch
+		err   string
+	}{
+		{patch: DataPatch{Op: PatchReplace, Path: "/unknown", Value: 1}, err: "patch path /unknown not found"},
+		{patch: DataPatch{Op: PatchAdd, Path: "/unknown/key", Value: 1}, err: "patch path /unknown/key not found"},
+		{patch: DataPatch{Op: PatchRemove, Path: "/name/key"}, err: "patch path /name/key not found"},
+		{patch: DataPatch{Op: PatchRemove, Path: "/users/2"}, err: "invalid array index 2 of patch path /users/2"},
+		{patch: DataPatch{Op: PatchReplace, Path: "/users/x", Value: 1}, err: "invalid array index x of patch path /users/x"},
+		{patch: DataPatch{Op: PatchReplace, Path: "/users/+1", Value: 1}, err: "invalid array index +1 of patch path /users/+1"},
+		{patch: DataPatch{Op: PatchReplace, Path: "/users/01", Value: 1}, err: "invalid array index 01 of patch path /users/01"},
+		{patch: DataPatch{Op: PatchReplace, Path: "/users/-0", Value: 1}, err: "invalid array index -0 of patch path /users/-0"},
+		{patch: DataPatch{Op: PatchRemove, Path: "/users/"}, err: "invalid array index  of patch path /users/"},
+		{patch: DataPatch{Op: PatchRemove, Path: ""}, err: errPatchRootRemove},
+		{patch: DataPatch{Op: "move", Path: "/users"}, err: "unsupported patch operation move"},
+		{patch: DataPatch{Op: PatchAdd, Path: "users", Value: 1}, err: "invalid patch path users"},
+	}
+
+	for _, c := range cases {
+		// the valid patch isn't applied either
+		err := checker.PatchData(DataPatch{Op: PatchAdd, Path: "/users/-", Value: "user2"}, c.patch)
+		require.EqualError(t, err, c.err)
+		assert.Equal(t, map[string]interface{}{"users": []interface{}{"user1"}, "name": "value"}, checker.Data())
+	}
+
+	require.NoError(t, checker.PatchData(
+		DataPatch{Op: PatchAdd, Path: "/users/0", Value: "user0"},
+		DataPatch{Op: PatchAdd, Path: "/a~1b", Value: 1},
+		DataPatch{Op: PatchRemove, Path: "/name"},
+	))
+	assert.Equal(t, map[string]interface{}{"users": []interface{}{"user0", "user1"}, "a/b": float64(1)}, checker.Data())
+}
+
+func Test_DataPaths(t *testing.T) {
+	assert.Equal(t, [][]string{{"teams", "admins"}}, dataPaths("{.teams.admins[*]}"))
+	assert.Equal(t, [][]string{{"team1"}, {"team2"}}, dataPaths("{.team1[*].name}{.team2[*].name}"))
+	assert.Equal(t, [][]string{{"orders"}}, dataPaths(`{.orders[?(@.id=="${path.id}")].owner}`))
+	assert.Equal(t, [][]string{{"teams"}}, dataPaths(`{.teams.${header.x-team}[*]}`))
+	assert.Equal(t, [][]string{{}}, dataPaths("{..name}"))
+}
@@ -0,0 +1,141 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package policy
+
+import (
+	"fmt"
+	"reflect"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// snapshot is an immutable set of prepared policy, data and data indexes.
+// Checks work with a single snapshot, updates build a new one and swap it atomically.
+type snapshot struct {
+	cfg       *preparedConfig
+	rawPolicy []byte
+	data      interface{}
+	// index contains clients found by every jsonpath query of the policy (jsonpath -> clients)
+	index map[string]*dataIndex
+	bound *boundQueryCache
+	// rules are hit counters of the policies of cfg
+	rules *ruleSet
+}
+
+type dataIndex struct {
+	clients map[string]struct{}
+	err     error
+}
+
+func (idx *dataIndex) contains(client string) bool {
+	_, ok := idx.clients[client]
+	return ok
+}
+
+func newSnapshot(cfg *preparedConfig, rawPolicy []byte, data interface{}, rules *ruleSet) *snapshot {
+	return &snapshot{
+		cfg:       cfg,
+		rawPolicy: rawPolicy,
+		data:      data,
+		index:     buildDataIndex(cfg, data),
+		bound:     newBoundQueryCache(),
+		rules:     rules,
+	}
+}
+
+// buildDataIndex evaluates every jsonpath query without bindings referenced by the policy
+func buildDataIndex(cfg *preparedConfig, data interface{}) map[string]*dataIndex {
+	index := map[string]*dataIndex{}
+	if cfg == nil {
+		return index
+	}
+
+	for _, parser := range cfg.parsers() {
+		if _, ok := index[parser.Jsonpath]; ok {
+			continue
+		}
+		index[parser.Jsonpath] = newDataIndex(parser.JsonParser, data)
+	}
+
+	return index
+}
+
+func newDataIndex(parser *jsonpath.JSONPath, data interface{}) *dataIndex {
+	clients, err := findClients(parser, data)
+	if err != nil {
+		return &dataIndex{err: err}
+	}
+
+	idx := &dataIndex{clients: make(map[string]struct{}, len(clients))}
+	for _, cl := range clients {
+		idx.clients[cl] = struct{}{}
+	}
+
+	return idx
+}
+
+// parsers returns all jsonpath parsers without bindings used by the config
+func (cfg *preparedConfig) parsers() []preparedParser {
+	return cfg.collectParsers(func(p preparedParser) bool { return len(p.Bindings) == 0 })
+}
+
+// boundParsers returns all jsonpath parsers with bindings used by the config
+func (cfg *preparedConfig) boundParsers() []preparedParser {
+	return cfg.collectParsers(func(p preparedParser) bool { return len(p.Bindings) > 0 })
+}
+
+func (cfg *preparedConfig) collectParsers(filter func(p preparedParser) bool) []preparedParser {
+	parsers := []preparedParser{}
+	var collect func(allow preparedAllow)
+	var collectExpr func(expr *allowExpr)
+
+	collect = func(allow preparedAllow) {
+		for _, p := range allow.parsers {
+			if filter(p) {
+				parsers = append(parsers, p)
+			}
+		}
+		for _, expr := range allow.exprs {
+			collectExpr(expr)
+		}
+	}
+	collectExpr = func(expr *allowExpr) {
+		if expr.term != nil {
+			collect(*expr.term)
+		}
+		for _, child := range expr.children {
+			collectExpr(child)
+		}
+	}
+
+	collect(cfg.Default)
+	for _, policy := range cfg.Policies {
+		collect(policy.Allow)
+	}
+
+	return parsers
+}
+
+// findClients evaluates jsonpath query and returns found string values
+func findClients(parser *jsonpath.JSONPath, data interface{}) ([]string, error) {
+	results, err := parser.FindResults(data)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpath finding results failure: %s", err.Error())
+	}
+
+	clients := []string{}
+	for _, values := range results {
+		for _, val := range values {
+			if val.Kind() == reflect.Interface {
+				val = val.Elem()
+			}
+			if val.Kind() == reflect.String {
+				clients = append(clients, val.String())
+			}
+		}
+	}
+
+	return clients, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package policy
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultRule is the endpoint of decisions made by the default policy
+const DefaultRule = "default"
+
+// RuleStats are hit counters of a rule since it was loaded
+type RuleStats struct {
+	// Endpoint is the uri of the rule as reported in CheckResult.Endpoint, DefaultRule for the default policy
+	Endpoint string   `json:"endpoint"`
+	Method   []string `json:"method,omitempty"`
+	Allow    uint64   `json:"allow"`
+	Deny     uint64   `json:"deny"`
+	// LastHit is the time of the last decision of the rule, nil if it wasn't hit
+	LastHit *time.Time `json:"lastHit,omitempty"`
+	// LoadedAt is the time the rule appeared in the policy, counting started then
+	LoadedAt time.Time `json:"loadedAt"`
+}
+
+// Hits returns the number of decisions of the rule
+func (s RuleStats) Hits() uint64 {
+	return s.Allow + s.Deny
+}
+
+// ruleCounters are updated by checks without locking, they are kept across updates
+// of the policy while the rule stays in it
+type ruleCounters struct {
+	allow    atomic.Uint64
+	deny     atomic.Uint64
+	lastHit  atomic.Int64
+	loadedAt time.Time
+}
+
+func (r *ruleCounters) hit(allow bool) {
+	if allow {
+		r.allow.Add(1)
+	} else {
+		r.deny.Add(1)
+	}
+	r.lastHit.Store(time.Now().UnixNano())
+}
+
+func (r *ruleCounters) stats(endpoint string, method []string) RuleStats {
+	stats := RuleStats{
+		Endpoint: endpoint,
+		Method:   method,
+		Allow:    r.allow.Load(),
+		Deny:     r.deny.Load(),
+		LoadedAt: r.loadedAt,
+	}
+	if lastHit := r.lastHit.Load(); lastHit > 0 {
+		t := time.Unix(0, lastHit).UTC()
+		stats.LastHit = &t
+	}
+
+	return stats
+}
+
+// ruleSet holds counters of policies of the config by their index and of the default policy
+type ruleSet struct {
+	policies   []*ruleCounters
+	defaultHit *ruleCounters
+	// byKey finds counters of the rules for the next config
+	byKey map[string]*ruleCounters
+}
+
+func ruleKey(policy preparedPolicy) string {
+	return policy.endpoint() + " " + strings.Join(policy.Method, ",")
+}
+
+// newRuleSet creates counters of rules of the config, counters of rules present in the previous set are kept
+func newRuleSet(cfg *preparedConfig, prev *ruleSet) *ruleSet {
+	if cfg == nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	counters := func(key string) *ruleCounters {
+		if prev != nil {
+			if c, ok := prev.byKey[key]; ok {
+				return c
+			}
+		}
+		return &ruleCounters{loadedAt: now}
+	}
+
+	rules := &ruleSet{
+		policies:   make([]*ruleCounters, len(cfg.Policies)),
+		defaultHit: counters(DefaultRule),
+		byKey:      make(map[string]*ruleCounters, len(cfg.Policies)+1),
+	}
+	rules.byKey[DefaultRule] = rules.defaultHit
+	for i, policy := range cfg.Policies {
+		key := ruleKey(policy)
+		rules.policies[i] = counters(key)
+		rules.byKey[key] = rules.policies[i]
+	}
+
+	return rules
+}
+
+// RuleStats returns hit counters of rules of the active policy in the order of matching,
+// the default policy is the last one
+func (c *Checker) RuleStats() []RuleStats {
+	s := c.snapshot.Load()
+	if s.cfg == nil || s.rules == nil {
+		return nil
+	}
+
+	stats := make([]RuleStats, 0, len(s.cfg.Policies)+1)
+	for i, policy := range s.cfg.Policies {
+		stats = append(stats, s.rules.policies[i].stats(policy.endpoint(), policy.Method))
+	}
+	stats = append(stats, s.rules.defaultHit.stats(DefaultRule, nil))
+
+	return stats
+}
@@ -0,0 +1,94 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func statsByEndpoint(stats []RuleStats) map[string]RuleStats {
+	m := make(map[string]RuleStats, len(stats))
+	for _, rule := range stats {
+		m[rule.Endpoint] = rule
+	}
+
+	return m
+}
+
+func Test_RuleStats(t *testing.T) {
+	config := `
+cn:
+  - header: "x-source"
+policies:
+  - uri: ["/ep1"]
+    method: ["GET"]
+    allow: ["client1"]
+  - uri: ["~/ep2/.*"]
+    allow: ["client1"]`
+
+	checker := NewChecker()
+	assert.Nil(t, checker.RuleStats())
+	require.NoError(t, checker.SetPolicy([]byte(config)))
+
+	checks := []CheckInput{
+		{Uri: "/ep1", Method: "GET", Headers: map[string]string{"x-source": "client1"}},
+		{Uri: "/ep1", Method: "GET", Headers: map[string]string{"x-source": "client2"}},
+		{Uri: "/ep1", Method: "GET", Headers: map[string]string{"x-source": "client1"}},
+		{Uri: "/ep3", Method: "GET", Headers: map[string]string{"x-source": "client1"}},
+	}
+	for _, in := range checks {
+		_, err := checker.Check(in)
+		require.NoError(t, err)
+	}
+
+	stats := checker.RuleStats()
+	require.Len(t, stats, 3)
+
+	assert.Equal(t, "/ep1", stats[0].Endpoint)
+	assert.Equal(t, []string{"GET"}, stats[0].Method)
+	assert.Equal(t, uint64(2), stats[0].Allow)
+	assert.Equal(t, uint64(1), stats[0].Deny)
+	assert.Equal(t, uint64(3), stats[0].Hits())
+	require.NotNil(t, stats[0].LastHit)
+	assert.False(t, stats[0].LastHit.Before(stats[0].LoadedAt))
+
+	assert.Equal(t, "^/ep2/.*$", stats[1].Endpoint)
+	assert.Equal(t, uint64(0), stats[1].Hits())
+	assert.Nil(t, stats[1].LastHit)
+
+	assert.Equal(t, DefaultRule, stats[2].Endpoint)
+	assert.Equal(t, uint64(1), stats[2].Deny)
+
+	// counters of rules staying in the policy are kept on updates
+	require.NoError(t, checker.SetData([]byte(`{"users":["user1"]}`)))
+	require.NoError(t, checker.SetPolicy([]byte(config+`
+  - uri: ["/ep4"]
+    allow: ["client1"]`)))
+
+	require.Len(t, checker.RuleStats(), 4)
+	updated := statsByEndpoint(checker.RuleStats())
+	assert.Equal(t, uint64(3), updated["/ep1"].Hits())
+	assert.Equal(t, stats[0].LoadedAt, updated["/ep1"].LoadedAt)
+	assert.Equal(t, uint64(1), updated[DefaultRule].Hits())
+	assert.Equal(t, uint64(0), updated["/ep4"].Hits())
+	assert.False(t, updated["/ep4"].LoadedAt.Before(stats[0].LoadedAt))
+
+	// a rule with changed methods is counted from the update
+	require.NoError(t, checker.SetPolicyWithData([]byte(`
+cn:
+  - header: "x-source"
+policies:
+  - uri: ["/ep1"]
+    method: ["GET", "POST"]
+    allow: ["client1"]`), nil))
+
+	require.Len(t, checker.RuleStats(), 2)
+	updated = statsByEndpoint(checker.RuleStats())
+	assert.Equal(t, uint64(0), updated["/ep1"].Hits())
+	assert.Equal(t, uint64(1), updated[DefaultRule].Hits())
+}
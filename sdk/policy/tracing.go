@@ -0,0 +1,51 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package policy
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/goauthlink/authlink/sdk/policy"
+
+const (
+	SpanResolveClientName = "authlink.resolve_client_name"
+	SpanVerifyJWT         = "authlink.verify_jwt"
+	SpanEvaluateJSONPath  = "authlink.evaluate_jsonpath"
+
+	AttrClientName  = attribute.Key("authlink.client_name")
+	AttrCnSource    = attribute.Key("authlink.cn_source")
+	AttrJWTVerified = attribute.Key("authlink.jwt.signature_verified")
+	AttrJSONPath    = attribute.Key("authlink.jsonpath")
+	AttrAllow       = attribute.Key("authlink.allow")
+)
+
+// WithContext passes the context of the check. Spans of client name resolution, jwt verification
+// and jsonpath evaluation are created with the tracer provider of the context span,
+// so nothing is traced if the context doesn't contain a span.
+func WithContext(ctx context.Context) CheckOpt {
+	return func(rq *checkRequest) {
+		rq.ctx = ctx
+	}
+}
+
+// startSpan starts the child span of the context span
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return trace.SpanFromContext(ctx).TracerProvider().Tracer(tracerName).
+		Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records the error of the span and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,32 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/goauthlink/authlink/pkg"
+	"github.com/spf13/cobra"
+)
+
+func NewRootCommand() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   path.Base(os.Args[0]),
+		Short: "Auth policy control plane",
+	}
+
+	rootCmd.AddCommand(newRunCmd())
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "version",
+		Short: "Show control plane version",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Print(pkg.Version)
+		},
+	})
+
+	return rootCmd
+}
@@ -0,0 +1,219 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/goauthlink/authlink/agent/monitoring"
+	"github.com/goauthlink/authlink/pkg/cmd"
+	"github.com/goauthlink/authlink/pkg/logging"
+	"github.com/goauthlink/authlink/server"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/credentials"
+)
+
+type runCmdParams struct {
+	logLevel           string
+	grpcAddr           string
+	monitoringAddr     string
+	policiesDir        string
+	updateFilesSeconds int
+	tlsPrivateKeyPath  string
+	tlsCertPath        string
+}
+
+type runServer interface {
+	Start(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+}
+
+type runConfig struct {
+	logLevel       slog.Level
+	grpcAddr       string
+	monitoringAddr string
+	policiesDir    string
+	updateInterval time.Duration
+	tlsCert        *tls.Certificate
+}
+
+const (
+	errPoliciesDirIsRequired = "--policies-dir is required"
+	errUpdateFilesSeconds    = "update files period must not be less than 0 seconds"
+	errTLSKeyAndCert         = "TLS certificate and private key must be set together"
+)
+
+func exitErr(msg string) {
+	fmt.Println(msg)
+	os.Exit(1)
+}
+
+func newRunCmd() *cobra.Command {
+	cmdParams := runCmdParams{}
+	runCmd := &cobra.Command{
+		Use:   "run",
+		Short: "Start policy control plane",
+		Run: func(command *cobra.Command, args []string) {
+			config, err := prepareConfig(cmdParams)
+			if err != nil {
+				exitErr(err.Error())
+			}
+
+			if err := run(config); err != nil {
+				exitErr(err.Error())
+			}
+		},
+	}
+
+	runCmd.Flags().StringVar(&cmdParams.logLevel, "log-level", "info", "set log level")
+	runCmd.Flags().StringVar(&cmdParams.grpcAddr, "grpc-addr", ":8383", "set listening address of the grpc server streaming snapshots to agents (e.g., [ip]:<port>)")
+	runCmd.Flags().StringVar(&cmdParams.monitoringAddr, "monitoring-addr", ":9292", "set listening address for the /health, /metrics and /v1/agents (e.g., [ip]:<port>)")
+	runCmd.Flags().StringVar(&cmdParams.policiesDir, "policies-dir", "", "set directory with a subdirectory per service containing policy.yaml and optional data.json")
+	runCmd.Flags().IntVar(&cmdParams.updateFilesSeconds, "update-files-seconds", 0, "set policies directory reloading period (seconds) (default 0 - do not reload)")
+	runCmd.Flags().StringVar(&cmdParams.tlsPrivateKeyPath, "tls-private-key", "", "set path of TLS private key file (default empty - plaintext)")
+	runCmd.Flags().StringVar(&cmdParams.tlsCertPath, "tls-cert", "", "set path of TLS certificate file (default empty - plaintext)")
+
+	return runCmd
+}
+
+func prepareConfig(params runCmdParams) (*runConfig, error) {
+	if len(params.policiesDir) == 0 {
+		return nil, errors.New(errPoliciesDirIsRequired)
+	}
+	if params.updateFilesSeconds < 0 {
+		return nil, errors.New(errUpdateFilesSeconds)
+	}
+
+	logLevel, err := logging.ParseLevel(params.logLevel)
+	if err != nil {
+		return nil, fmt.Errorf("init logger: %w", err)
+	}
+
+	config := &runConfig{
+		logLevel:       logLevel,
+		grpcAddr:       params.grpcAddr,
+		monitoringAddr: params.monitoringAddr,
+		policiesDir:    params.policiesDir,
+		updateInterval: time.Second * time.Duration(params.updateFilesSeconds),
+	}
+
+	if len(params.tlsCertPath) > 0 != (len(params.tlsPrivateKeyPath) > 0) {
+		return nil, errors.New(errTLSKeyAndCert)
+	}
+	if len(params.tlsCertPath) > 0 {
+		cert, err := tls.LoadX509KeyPair(params.tlsCertPath, params.tlsPrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		config.tlsCert = &cert
+	}
+
+	return config, nil
+}
+
+func run(config *runConfig) error {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: config.logLevel,
+	}))
+
+	controlPlane := server.NewControlPlane(server.WithControlPlaneLogger(logger))
+
+	loadMux := sync.Mutex{}
+	var loadErr error
+	load := func() {
+		err := controlPlane.LoadDir(config.policiesDir)
+		if err != nil {
+			logger.Error(fmt.Sprintf("loading policies failed, the last valid snapshots are served: %s", err.Error()))
+		}
+
+		loadMux.Lock()
+		loadErr = err
+		loadMux.Unlock()
+	}
+	load()
+
+	serverOpts := []server.ServerOpt{server.WithLogger(logger)}
+	if config.tlsCert != nil {
+		serverOpts = append(serverOpts, server.WithCredentials(credentials.NewServerTLSFromCert(config.tlsCert)))
+	}
+
+	monitoringServer, err := monitoring.NewServer(config.monitoringAddr,
+		monitoring.WithLogger(logger),
+		monitoring.WithHealthCheck(func() error {
+			loadMux.Lock()
+			defer loadMux.Unlock()
+			return loadErr
+		}),
+		monitoring.WithHandler(server.AgentsPattern, controlPlane.AgentsHandler()),
+	)
+	if err != nil {
+		return err
+	}
+
+	servers := []runServer{
+		server.New(config.grpcAddr, controlPlane, serverOpts...),
+		monitoringServer,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+	errchan := make(chan error, len(servers))
+
+	for _, srv := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.Start(ctx); err != nil {
+				errchan <- err
+			}
+		}()
+	}
+
+	if config.updateInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ticker := time.NewTicker(config.updateInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					load()
+				}
+			}
+		}()
+	}
+
+	stop := make(chan struct{}, 1)
+	go cmd.WaitSignal(stop)
+
+	var reserr error
+	select {
+	case <-stop:
+		logger.Info("received exit signal")
+	case reserr = <-errchan:
+		logger.Error(reserr.Error())
+	}
+
+	cancel()
+	for _, srv := range servers {
+		srv.Shutdown(ctx) //nolint: errcheck
+	}
+	wg.Wait()
+	logger.Info("control plane shutdown")
+
+	return reserr
+}
@@ -0,0 +1,56 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/goauthlink/authlink/test/testdata"
+	"github.com/goauthlink/authlink/test/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ServerParams(t *testing.T) {
+	rootDir, cleanFs, err := util.MakeTmpFs("", t.Name(), map[string][]byte{
+		"key.pem":  testdata.TLSServerKey,
+		"cert.pem": testdata.TLSServerCert,
+	})
+	require.NoError(t, err)
+	defer cleanFs()
+
+	params := runCmdParams{
+		logLevel:           "debug",
+		grpcAddr:           ":8383",
+		monitoringAddr:     ":9292",
+		updateFilesSeconds: 10,
+	}
+
+	_, err = prepareConfig(params)
+	require.EqualError(t, err, errPoliciesDirIsRequired)
+
+	params.policiesDir = rootDir
+	config, err := prepareConfig(params)
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, config.logLevel)
+	assert.Equal(t, rootDir, config.policiesDir)
+	assert.Equal(t, 10*time.Second, config.updateInterval)
+	assert.Nil(t, config.tlsCert)
+
+	params.tlsCertPath = rootDir + "/cert.pem"
+	_, err = prepareConfig(params)
+	require.EqualError(t, err, errTLSKeyAndCert)
+
+	params.tlsPrivateKeyPath = rootDir + "/key.pem"
+	config, err = prepareConfig(params)
+	require.NoError(t, err)
+	assert.NotNil(t, config.tlsCert)
+
+	params.updateFilesSeconds = -1
+	_, err = prepareConfig(params)
+	require.EqualError(t, err, errUpdateFilesSeconds)
+}
@@ -0,0 +1,401 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/goauthlink/authlink/pkg/logging"
+	"github.com/goauthlink/authlink/pkg/snapshot"
+	"github.com/goauthlink/authlink/sdk/policy"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	errServiceIsRequired = "service is required"
+	errNodeIsRequired    = "node with cluster set to the service name is required"
+	errUnknownTypeURL    = "unknown type url %s, %s is expected"
+)
+
+// AgentStatus is the state of an agent connected to the control plane
+type AgentStatus struct {
+	// ID is the node id reported by the agent
+	ID        string    `json:"id"`
+	Service   string    `json:"service"`
+	Address   string    `json:"address"`
+	Connected time.Time `json:"connected"`
+	// Version is the last version acknowledged by the agent, empty if none was
+	Version  string `json:"version"`
+	Revision string `json:"revision"`
+	// Error is the validation error of the last rejected version, empty if the last one was accepted
+	Error        string `json:"error,omitempty"`
+	ErrorVersion string `json:"errorVersion,omitempty"`
+}
+
+type service struct {
+	snapshot snapshot.Snapshot
+	version  uint64
+	// watchers are notified when the snapshot of the service is changed
+	watchers map[chan struct{}]struct{}
+}
+
+// ControlPlane holds policies per service and streams their snapshots to connected agents
+// over the aggregated discovery service. An agent identifies its service by the cluster
+// of the node, acknowledges applied versions and rejects invalid ones with the error detail
+// as xDS clients do.
+type ControlPlane struct {
+	discoveryv3.UnimplementedAggregatedDiscoveryServiceServer
+
+	mux      sync.RWMutex
+	services map[string]*service
+	agents   map[uint64]*AgentStatus
+	streamID atomic.Uint64
+	logger   *slog.Logger
+}
+
+type ControlPlaneOpt func(*ControlPlane)
+
+func WithControlPlaneLogger(logger *slog.Logger) ControlPlaneOpt {
+	return func(c *ControlPlane) {
+		c.logger = logger
+	}
+}
+
+func NewControlPlane(opts ...ControlPlaneOpt) *ControlPlane {
+	c := &ControlPlane{
+		services: map[string]*service{},
+		agents:   map[uint64]*AgentStatus{},
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	if c.logger == nil {
+		c.logger = logging.NewNullLogger()
+	}
+
+	return c
+}
+
+// SetPolicy validates the policy with data and makes it the next snapshot of the service,
+// connected agents of the service receive it. Unchanged content keeps the current version.
+func (c *ControlPlane) SetPolicy(name string, policyData, data []byte) (snapshot.Snapshot, error) {
+	if len(name) == 0 {
+		return snapshot.Snapshot{}, errors.New(errServiceIsRequired)
+	}
+
+	// key files are read by the agents, the control plane may not have them
+	if err := policy.ValidatePolicyWithData(policyData, data); err != nil {
+		return snapshot.Snapshot{}, fmt.Errorf("service %s: %w", name, err)
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	svc, ok := c.services[name]
+	if !ok {
+		svc = &service{watchers: map[chan struct{}]struct{}{}}
+		c.services[name] = svc
+	}
+
+	if len(svc.snapshot.Version) > 0 && bytes.Equal(svc.snapshot.Policy, policyData) && bytes.Equal(svc.snapshot.Data, data) {
+		return svc.snapshot, nil
+	}
+
+	svc.version++
+	svc.snapshot = snapshot.Snapshot{
+		Service:  name,
+		Version:  strconv.FormatUint(svc.version, 10),
+		Revision: snapshot.Revision(policyData, data),
+		Policy:   slices.Clone(policyData),
+		Data:     slices.Clone(data),
+	}
+
+	for watcher := range svc.watchers {
+		select {
+		case watcher <- struct{}{}:
+		default:
+		}
+	}
+
+	c.logger.Info(fmt.Sprintf("service %s snapshot version %s (revision %s) is set", name, svc.snapshot.Version, svc.snapshot.Revision))
+
+	return svc.snapshot, nil
+}
+
+// Snapshot returns the current snapshot of the service
+func (c *ControlPlane) Snapshot(name string) (snapshot.Snapshot, bool) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	svc, ok := c.services[name]
+	if !ok || len(svc.snapshot.Version) == 0 {
+		return snapshot.Snapshot{}, false
+	}
+
+	return svc.snapshot, true
+}
+
+// RemovePolicy drops the snapshot of the service. Connected agents of the service keep
+// their active policy and data, a policy set again is sent to them with the next version.
+func (c *ControlPlane) RemovePolicy(name string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	svc, ok := c.services[name]
+	if !ok || len(svc.snapshot.Version) == 0 {
+		return
+	}
+
+	if len(svc.watchers) == 0 {
+		delete(c.services, name)
+	} else {
+		svc.snapshot = snapshot.Snapshot{}
+	}
+
+	c.logger.Info(fmt.Sprintf("service %s is removed", name))
+}
+
+// serviceNames returns names of services with snapshots
+func (c *ControlPlane) serviceNames() []string {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	names := []string{}
+	for name, svc := range c.services {
+		if len(svc.snapshot.Version) > 0 {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Agents returns the state of connected agents sorted by service and id
+func (c *ControlPlane) Agents() []AgentStatus {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	agents := make([]AgentStatus, 0, len(c.agents))
+	for _, agent := range c.agents {
+		agents = append(agents, *agent)
+	}
+
+	slices.SortFunc(agents, func(a, b AgentStatus) int {
+		if n := strings.Compare(a.Service, b.Service); n != 0 {
+			return n
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return agents
+}
+
+// StreamAggregatedResources sends the snapshot of the agent service on connect and on every change.
+// A new snapshot isn't sent until the previous one is acknowledged or rejected.
+func (c *ControlPlane) StreamAggregatedResources(stream discoveryv3.AggregatedDiscoveryService_StreamAggregatedResourcesServer) error {
+	ctx := stream.Context()
+
+	rq, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if err := validateRequest(rq); err != nil {
+		return err
+	}
+
+	name, err := nodeService(rq.GetNode())
+	if err != nil {
+		return err
+	}
+
+	streamID := c.streamID.Add(1)
+	watcher := make(chan struct{}, 1)
+	c.register(ctx, streamID, name, watcher, rq.GetNode())
+	defer c.unregister(streamID, name, watcher)
+
+	requests := make(chan *discoveryv3.DiscoveryRequest)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			rq, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case requests <- rq:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// versions aren't kept across restarts of the control plane, so the snapshot is always sent
+	// on connect, the agent applies it again even if the content is unchanged
+	sent := snapshot.Snapshot{}
+	nonce := ""
+	pending := false
+	send := func() error {
+		s, ok := c.Snapshot(name)
+		if !ok || s.Version == sent.Version {
+			return nil
+		}
+
+		resource, err := snapshot.Encode(s)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+
+		nonce = strconv.FormatUint(streamID, 10) + "-" + s.Version
+		if err := stream.Send(&discoveryv3.DiscoveryResponse{
+			VersionInfo: s.Version,
+			Resources:   []*anypb.Any{resource},
+			TypeUrl:     snapshot.TypeURL,
+			Nonce:       nonce,
+		}); err != nil {
+			return err
+		}
+
+		sent = s
+		pending = true
+
+		return nil
+	}
+
+	if err := send(); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-recvErr:
+			if errors.Is(err, io.EOF) || status.Code(err) == codes.Canceled {
+				return nil
+			}
+			return err
+		case <-watcher:
+			if pending {
+				continue
+			}
+		case rq := <-requests:
+			if err := validateRequest(rq); err != nil {
+				return err
+			}
+			// responses to previous nonces are stale
+			if rq.GetResponseNonce() != nonce {
+				continue
+			}
+			pending = false
+			c.updateAgent(streamID, sent, rq)
+		}
+
+		if err := send(); err != nil {
+			return err
+		}
+	}
+}
+
+// register adds the agent of the stream and subscribes it to changes of the service
+func (c *ControlPlane) register(ctx context.Context, streamID uint64, name string, watcher chan struct{}, node *corev3.Node) {
+	agent := &AgentStatus{
+		ID:        node.GetId(),
+		Service:   name,
+		Connected: time.Now(),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		agent.Address = p.Addr.String()
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	svc, ok := c.services[name]
+	if !ok {
+		svc = &service{watchers: map[chan struct{}]struct{}{}}
+		c.services[name] = svc
+	}
+	svc.watchers[watcher] = struct{}{}
+	c.agents[streamID] = agent
+
+	c.logger.Info(fmt.Sprintf("agent %s of service %s connected from %s", agent.ID, name, agent.Address))
+}
+
+func (c *ControlPlane) unregister(streamID uint64, name string, watcher chan struct{}) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if agent, ok := c.agents[streamID]; ok {
+		c.logger.Info(fmt.Sprintf("agent %s of service %s disconnected", agent.ID, name))
+	}
+
+	delete(c.agents, streamID)
+
+	svc := c.services[name]
+	delete(svc.watchers, watcher)
+	// services requested by agents but never set or removed don't accumulate
+	if len(svc.snapshot.Version) == 0 && len(svc.watchers) == 0 {
+		delete(c.services, name)
+	}
+}
+
+// updateAgent records acknowledgement or rejection of the sent snapshot
+func (c *ControlPlane) updateAgent(streamID uint64, sent snapshot.Snapshot, rq *discoveryv3.DiscoveryRequest) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	agent, ok := c.agents[streamID]
+	if !ok {
+		return
+	}
+
+	if rq.GetErrorDetail() != nil {
+		agent.Error = rq.GetErrorDetail().GetMessage()
+		agent.ErrorVersion = sent.Version
+		c.logger.Warn(fmt.Sprintf("agent %s of service %s rejected version %s: %s", agent.ID, agent.Service, sent.Version, agent.Error))
+		return
+	}
+
+	agent.Version = rq.GetVersionInfo()
+	agent.Revision = sent.Revision
+	agent.Error = ""
+	agent.ErrorVersion = ""
+	c.logger.Debug(fmt.Sprintf("agent %s of service %s applied version %s", agent.ID, agent.Service, agent.Version))
+}
+
+func validateRequest(rq *discoveryv3.DiscoveryRequest) error {
+	if rq.GetTypeUrl() != snapshot.TypeURL {
+		return status.Errorf(codes.InvalidArgument, errUnknownTypeURL, rq.GetTypeUrl(), snapshot.TypeURL)
+	}
+
+	return nil
+}
+
+func nodeService(node *corev3.Node) (string, error) {
+	if node == nil || len(node.GetCluster()) == 0 {
+		return "", status.Error(codes.InvalidArgument, errNodeIsRequired)
+	}
+
+	return node.GetCluster(), nil
+}
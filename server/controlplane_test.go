@@ -0,0 +1,285 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/goauthlink/authlink/pkg/snapshot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	rpc_status "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testPolicy = `cn:
+  - header: "x-source"
+policies:
+  - uri: ["/endpoint"]
+    allow: ["client"]`
+
+func startControlPlane(t *testing.T, cp *ControlPlane) discoveryv3.AggregatedDiscoveryServiceClient {
+	listener := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	discoveryv3.RegisterAggregatedDiscoveryServiceServer(srv, cp)
+	go srv.Serve(listener) //nolint: errcheck
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return discoveryv3.NewAggregatedDiscoveryServiceClient(conn)
+}
+
+func Test_SetPolicy(t *testing.T) {
+	cp := NewControlPlane()
+
+	s, err := cp.SetPolicy("orders", []byte(testPolicy), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "1", s.Version)
+	assert.Equal(t, snapshot.Revision([]byte(testPolicy), nil), s.Revision)
+
+	// unchanged content keeps the version
+	s, err = cp.SetPolicy("orders", []byte(testPolicy), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "1", s.Version)
+
+	s, err = cp.SetPolicy("orders", []byte(testPolicy), []byte(`{"users":["user1"]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "2", s.Version)
+
+	// invalid policy keeps the current snapshot
+	_, err = cp.SetPolicy("orders", []byte("cn: {"), nil)
+	require.Error(t, err)
+
+	s, ok := cp.Snapshot("orders")
+	require.True(t, ok)
+	assert.Equal(t, "2", s.Version)
+
+	_, ok = cp.Snapshot("payments")
+	assert.False(t, ok)
+
+	_, err = cp.SetPolicy("", []byte(testPolicy), nil)
+	require.EqualError(t, err, errServiceIsRequired)
+}
+
+func Test_SetPolicyWithAgentKeyFile(t *testing.T) {
+	cp := NewControlPlane()
+
+	// the key file exists only on the agents
+	keyFilePolicy := `cn:
+  - jwt:
+      header: "authorization"
+      keyFile: "/etc/authlink/agent-only.key"
+      payload: "sub"
+policies:
+  - uri: ["/endpoint"]
+    allow: ["client"]
+    condition: "claims.role == 'admin'"`
+
+	s, err := cp.SetPolicy("orders", []byte(keyFilePolicy), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "1", s.Version)
+
+	// the rest of the policy is still validated
+	_, err = cp.SetPolicy("orders", []byte(strings.Replace(keyFilePolicy, "== 'admin'", "==", 1)), nil)
+	require.Error(t, err)
+}
+
+func Test_LoadDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"orders/policy.yaml":   testPolicy,
+		"orders/data.json":     `{"users":["user1"]}`,
+		"payments/policy.yaml": testPolicy,
+		"broken/policy.yaml":   "cn: {",
+		"empty/README.md":      "no policy",
+	}
+	for path, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, path), []byte(content), 0o600))
+	}
+
+	cp := NewControlPlane()
+	err := cp.LoadDir(dir)
+	require.ErrorContains(t, err, "service broken")
+
+	s, ok := cp.Snapshot("orders")
+	require.True(t, ok)
+	assert.Equal(t, []byte(`{"users":["user1"]}`), s.Data)
+
+	s, ok = cp.Snapshot("payments")
+	require.True(t, ok)
+	assert.Nil(t, s.Data)
+
+	_, ok = cp.Snapshot("empty")
+	assert.False(t, ok)
+
+	// removed services are dropped
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "payments")))
+	require.NoError(t, os.Remove(filepath.Join(dir, "orders", "policy.yaml")))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "users"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "users", "policy.yaml"), []byte(testPolicy), 0o600))
+	err = cp.LoadDir(dir)
+	require.ErrorContains(t, err, "service broken")
+
+	_, ok = cp.Snapshot("payments")
+	assert.False(t, ok)
+	_, ok = cp.Snapshot("orders")
+	assert.False(t, ok)
+	_, ok = cp.Snapshot("users")
+	assert.True(t, ok)
+}
+
+func Test_RemovePolicy(t *testing.T) {
+	cp := NewControlPlane()
+
+	_, err := cp.SetPolicy("orders", []byte(testPolicy), nil)
+	require.NoError(t, err)
+
+	watcher := make(chan struct{}, 1)
+	cp.register(context.Background(), 1, "orders", watcher, &corev3.Node{Id: "agent", Cluster: "orders"})
+
+	cp.RemovePolicy("orders")
+	_, ok := cp.Snapshot("orders")
+	assert.False(t, ok)
+
+	// connected agents get the policy set again with the next version
+	s, err := cp.SetPolicy("orders", []byte(testPolicy), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "2", s.Version)
+
+	cp.unregister(1, "orders", watcher)
+	cp.RemovePolicy("orders")
+	cp.mux.RLock()
+	assert.Empty(t, cp.services)
+	cp.mux.RUnlock()
+}
+
+func Test_StreamAggregatedResources(t *testing.T) {
+	cp := NewControlPlane()
+	_, err := cp.SetPolicy("orders", []byte(testPolicy), nil)
+	require.NoError(t, err)
+
+	client := startControlPlane(t, cp)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.StreamAggregatedResources(ctx)
+	require.NoError(t, err)
+
+	node := &corev3.Node{Id: "agent-1", Cluster: "orders"}
+	require.NoError(t, stream.Send(&discoveryv3.DiscoveryRequest{Node: node, TypeUrl: snapshot.TypeURL}))
+
+	// the current snapshot is sent on connect
+	rsp, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "1", rsp.GetVersionInfo())
+	s, err := snapshot.Decode(rsp.GetResources()[0])
+	require.NoError(t, err)
+	assert.Equal(t, []byte(testPolicy), s.Policy)
+
+	require.NoError(t, stream.Send(&discoveryv3.DiscoveryRequest{
+		Node:          node,
+		TypeUrl:       snapshot.TypeURL,
+		VersionInfo:   "1",
+		ResponseNonce: rsp.GetNonce(),
+	}))
+
+	assert.Eventually(t, func() bool {
+		agents := cp.Agents()
+		return len(agents) == 1 && agents[0].Version == "1"
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "agent-1", cp.Agents()[0].ID)
+	assert.Equal(t, s.Revision, cp.Agents()[0].Revision)
+
+	// a change is pushed and rejected by the agent
+	_, err = cp.SetPolicy("orders", []byte(testPolicy), []byte(`{"users":["user1"]}`))
+	require.NoError(t, err)
+
+	rsp, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "2", rsp.GetVersionInfo())
+
+	require.NoError(t, stream.Send(&discoveryv3.DiscoveryRequest{
+		Node:          node,
+		TypeUrl:       snapshot.TypeURL,
+		VersionInfo:   "1",
+		ResponseNonce: rsp.GetNonce(),
+		ErrorDetail:   &rpc_status.Status{Code: int32(codes.InvalidArgument), Message: "invalid data"},
+	}))
+
+	assert.Eventually(t, func() bool {
+		agents := cp.Agents()
+		return len(agents) == 1 && agents[0].Error == "invalid data"
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "1", cp.Agents()[0].Version)
+	assert.Equal(t, "2", cp.Agents()[0].ErrorVersion)
+
+	// the agent is removed on disconnect
+	cancel()
+	assert.Eventually(t, func() bool { return len(cp.Agents()) == 0 }, time.Second, 5*time.Millisecond)
+}
+
+func Test_StreamAggregatedResourcesInvalid(t *testing.T) {
+	client := startControlPlane(t, NewControlPlane())
+
+	cases := []*discoveryv3.DiscoveryRequest{
+		{Node: &corev3.Node{Cluster: "orders"}, TypeUrl: "type.googleapis.com/envoy.config.cluster.v3.Cluster"},
+		{TypeUrl: snapshot.TypeURL},
+	}
+
+	for _, rq := range cases {
+		stream, err := client.StreamAggregatedResources(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(rq))
+
+		_, err = stream.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+}
+
+func Test_StreamAggregatedResourcesUnknownService(t *testing.T) {
+	cp := NewControlPlane()
+	client := startControlPlane(t, cp)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.StreamAggregatedResources(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&discoveryv3.DiscoveryRequest{
+		Node:    &corev3.Node{Id: "agent-1", Cluster: "unknown"},
+		TypeUrl: snapshot.TypeURL,
+	}))
+
+	assert.Eventually(t, func() bool { return len(cp.Agents()) == 1 }, time.Second, 5*time.Millisecond)
+
+	// the service without a policy is dropped when its last agent disconnects
+	cancel()
+	assert.Eventually(t, func() bool {
+		cp.mux.RLock()
+		defer cp.mux.RUnlock()
+		return len(cp.services) == 0
+	}, time.Second, 5*time.Millisecond)
+}
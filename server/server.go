@@ -0,0 +1,152 @@
+// Copyright 2025 The AuthLink Authors. All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/goauthlink/authlink/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	PolicyFileName = "policy.yaml"
+	DataFileName   = "data.json"
+
+	// AgentsPattern is the route of states of connected agents on the monitoring server
+	AgentsPattern = "GET /v1/agents"
+)
+
+// Server serves the control plane over grpc
+type Server struct {
+	server *grpc.Server
+	logger *slog.Logger
+	addr   string
+	creds  credentials.TransportCredentials
+}
+
+type ServerOpt func(*Server)
+
+func WithLogger(logger *slog.Logger) ServerOpt {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithCredentials sets transport credentials of the server, e.g. TLS, plaintext is used by default
+func WithCredentials(creds credentials.TransportCredentials) ServerOpt {
+	return func(s *Server) {
+		s.creds = creds
+	}
+}
+
+func New(addr string, controlPlane *ControlPlane, opts ...ServerOpt) *Server {
+	srv := &Server{
+		addr: addr,
+	}
+
+	for _, o := range opts {
+		o(srv)
+	}
+
+	if srv.logger == nil {
+		srv.logger = logging.NewNullLogger()
+	}
+
+	var grpcOpts []grpc.ServerOption
+	if srv.creds != nil {
+		grpcOpts = append(grpcOpts, grpc.Creds(srv.creds))
+	}
+	srv.server = grpc.NewServer(grpcOpts...)
+	discoveryv3.RegisterAggregatedDiscoveryServiceServer(srv.server, controlPlane)
+
+	return srv
+}
+
+func (s *Server) Start(_ context.Context) error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("listen tcp for grpc server on %s: %w", s.addr, err)
+	}
+
+	s.logger.Info(fmt.Sprintf("control plane grpc server is starting on %s", s.addr))
+
+	if err := s.server.Serve(listener); err != nil {
+		return fmt.Errorf("serve grpc server: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Server) Shutdown(_ context.Context) error {
+	s.server.Stop()
+	s.logger.Info("control plane grpc server stopped")
+	return nil
+}
+
+// LoadDir sets policies of services from subdirectories of the directory, a subdirectory
+// is named after the service and contains policy.yaml with optional data.json.
+// Invalid services keep their current snapshots and are reported in the joined error,
+// services whose subdirectory or policy.yaml is removed are dropped.
+func (c *ControlPlane) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("read policies directory: %w", err)
+	}
+
+	var errs []error
+	loaded := map[string]struct{}{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		policyData, err := os.ReadFile(filepath.Join(dir, entry.Name(), PolicyFileName))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		loaded[entry.Name()] = struct{}{}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name(), DataFileName))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+
+		if _, err := c.SetPolicy(entry.Name(), policyData, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, name := range c.serviceNames() {
+		if _, ok := loaded[name]; !ok {
+			c.RemovePolicy(name)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// AgentsHandler responds with states of connected agents
+func (c *ControlPlane) AgentsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c.Agents()) //nolint: errcheck
+	})
+}
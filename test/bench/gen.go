@@ -70,7 +70,7 @@ func genPolicy() {
 		})
 	}
 
-	testPolicy.Default = []string{"client"}
+	testPolicy.Default = policy.DefaultPolicy{Allow: []string{"client"}}
 
 	config, err := yaml.Marshal(testPolicy)
 	if err != nil {